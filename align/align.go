@@ -0,0 +1,407 @@
+// Package align reads sequence alignments from SAM and BAM files.
+//
+// Only the fields needed to relate alignments to gene models are decoded:
+// read name, reference, position, flags, mapping quality, CIGAR and the NH
+// tag giving the number of reported alignments of the read. BAM files are
+// read with compress/gzip, which handles the concatenated gzip members of
+// BGZF; random access through an index is not supported.
+package align
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Flags are SAM alignment flags.
+type Flags uint16
+
+// SAM flags.
+const (
+	Paired Flags = 1 << iota
+	ProperPair
+	Unmapped
+	MateUnmapped
+	Reverse
+	MateReverse
+	Read1
+	Read2
+	Secondary
+	QCFail
+	Duplicate
+	Supplementary
+)
+
+// CigarOp is a CIGAR operation.
+type CigarOp struct {
+	Op  byte
+	Len int
+}
+
+// Record is an alignment.
+type Record struct {
+	Name string
+	// Ref is the name of the reference sequence, empty if unmapped.
+	Ref string
+	// Pos is the zero based position of the first aligned base.
+	Pos   int
+	Flags Flags
+	MapQ  byte
+	Cigar []CigarOp
+	// NH is the number of reported alignments of the read, or zero if the
+	// record has no NH tag.
+	NH int
+}
+
+// Blocks returns the reference intervals covered by r as zero based, half
+// open intervals. Skipped regions (N) separate blocks; deletions (D) are
+// part of the block they occur in.
+func (r *Record) Blocks() [][2]int {
+	var blocks [][2]int
+	pos := r.Pos
+	start := pos
+	for _, c := range r.Cigar {
+		switch c.Op {
+		case 'M', '=', 'X', 'D':
+			pos += c.Len
+		case 'N':
+			if pos > start {
+				blocks = append(blocks, [2]int{start, pos})
+			}
+			pos += c.Len
+			start = pos
+		}
+	}
+	if pos > start {
+		blocks = append(blocks, [2]int{start, pos})
+	}
+	return blocks
+}
+
+// End returns the reference position after the last aligned base of r.
+func (r *Record) End() int {
+	end := r.Pos
+	for _, c := range r.Cigar {
+		switch c.Op {
+		case 'M', '=', 'X', 'D', 'N':
+			end += c.Len
+		}
+	}
+	return end
+}
+
+// Reader is the common interface for alignment readers.
+type Reader interface {
+	// Read returns the next alignment. At EOF, it returns nil and io.EOF.
+	Read() (*Record, error)
+}
+
+// NewReader returns a Reader of the SAM or BAM data in r. BAM is recognised
+// by its gzip magic number.
+func NewReader(r io.Reader) (Reader, error) {
+	br := bufio.NewReader(r)
+	magic, _ := br.Peek(2)
+	if bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		return NewBAMReader(br)
+	}
+	return NewSAMReader(br), nil
+}
+
+// SAMReader reads alignments from SAM text.
+type SAMReader struct {
+	sc   *bufio.Scanner
+	line int
+}
+
+// NewSAMReader returns a new SAMReader reading from r.
+func NewSAMReader(r io.Reader) *SAMReader {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, 1<<26)
+	return &SAMReader{sc: sc}
+}
+
+// Read implements Reader. Header lines are skipped.
+func (r *SAMReader) Read() (*Record, error) {
+	for r.sc.Scan() {
+		r.line++
+		text := r.sc.Text()
+		if text == "" || text[0] == '@' {
+			continue
+		}
+		rec, err := parseSAM(text)
+		if err != nil {
+			return nil, fmt.Errorf("align: line %d: %v", r.line, err)
+		}
+		return rec, nil
+	}
+	if err := r.sc.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+// parseSAM parses a SAM alignment line.
+func parseSAM(text string) (*Record, error) {
+	fields := strings.Split(text, "\t")
+	if len(fields) < 11 {
+		return nil, errors.New("too few fields")
+	}
+	flags, err := strconv.ParseUint(fields[1], 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("bad flags %q", fields[1])
+	}
+	pos, err := strconv.Atoi(fields[3])
+	if err != nil {
+		return nil, fmt.Errorf("bad position %q", fields[3])
+	}
+	mapq, err := strconv.ParseUint(fields[4], 10, 8)
+	if err != nil {
+		return nil, fmt.Errorf("bad mapping quality %q", fields[4])
+	}
+	rec := &Record{Name: fields[0], Pos: pos - 1, Flags: Flags(flags), MapQ: byte(mapq)}
+	if fields[2] != "*" {
+		rec.Ref = fields[2]
+	}
+	if fields[5] != "*" {
+		if rec.Cigar, err = parseCigar(fields[5]); err != nil {
+			return nil, err
+		}
+	}
+	for _, tag := range fields[11:] {
+		if strings.HasPrefix(tag, "NH:i:") {
+			if rec.NH, err = strconv.Atoi(tag[5:]); err != nil {
+				return nil, fmt.Errorf("bad NH tag %q", tag)
+			}
+		}
+	}
+	return rec, nil
+}
+
+// parseCigar parses a CIGAR string.
+func parseCigar(s string) ([]CigarOp, error) {
+	var ops []CigarOp
+	n := 0
+	digits := false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if '0' <= c && c <= '9' {
+			n = n*10 + int(c-'0')
+			digits = true
+			continue
+		}
+		if !digits || !strings.ContainsRune("MIDNSHP=X", rune(c)) {
+			return nil, fmt.Errorf("bad CIGAR %q", s)
+		}
+		ops = append(ops, CigarOp{Op: c, Len: n})
+		n, digits = 0, false
+	}
+	if digits {
+		return nil, fmt.Errorf("bad CIGAR %q", s)
+	}
+	return ops, nil
+}
+
+// BAMReader reads alignments from BAM data.
+type BAMReader struct {
+	r    *bufio.Reader
+	refs []string
+	buf  []byte
+}
+
+// NewBAMReader returns a new BAMReader reading from r. It reads the BAM
+// header.
+func NewBAMReader(r io.Reader) (*BAMReader, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	br := &BAMReader{r: bufio.NewReader(gz)}
+	var magic [4]byte
+	if _, err := io.ReadFull(br.r, magic[:]); err != nil || string(magic[:]) != "BAM\x01" {
+		return nil, errors.New("align: not a BAM file")
+	}
+	textLen, err := br.int32()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := br.r.Discard(int(textLen)); err != nil {
+		return nil, errTruncated(err)
+	}
+	n, err := br.int32()
+	if err != nil {
+		return nil, err
+	}
+	for i := int32(0); i < n; i++ {
+		l, err := br.int32()
+		if err != nil {
+			return nil, err
+		}
+		name, err := br.bytes(int(l))
+		if err != nil {
+			return nil, err
+		}
+		if _, err := br.int32(); err != nil {
+			return nil, err
+		}
+		br.refs = append(br.refs, string(bytes.TrimRight(name, "\x00")))
+	}
+	return br, nil
+}
+
+// Refs returns the names of the reference sequences of the BAM header.
+func (r *BAMReader) Refs() []string {
+	return append([]string(nil), r.refs...)
+}
+
+func errTruncated(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return errors.New("align: truncated BAM file")
+	}
+	return err
+}
+
+func (r *BAMReader) int32() (int32, error) {
+	var b [4]byte
+	if _, err := io.ReadFull(r.r, b[:]); err != nil {
+		return 0, errTruncated(err)
+	}
+	return int32(binary.LittleEndian.Uint32(b[:])), nil
+}
+
+func (r *BAMReader) bytes(n int) ([]byte, error) {
+	if n < 0 {
+		return nil, errors.New("align: corrupt BAM file")
+	}
+	if cap(r.buf) < n {
+		r.buf = make([]byte, n)
+	}
+	b := r.buf[:n]
+	if _, err := io.ReadFull(r.r, b); err != nil {
+		return nil, errTruncated(err)
+	}
+	return b, nil
+}
+
+// Read implements Reader.
+func (r *BAMReader) Read() (*Record, error) {
+	var size [4]byte
+	if _, err := io.ReadFull(r.r, size[:]); err != nil {
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		return nil, errTruncated(err)
+	}
+	b, err := r.bytes(int(int32(binary.LittleEndian.Uint32(size[:]))))
+	if err != nil {
+		return nil, err
+	}
+	if len(b) < 32 {
+		return nil, errors.New("align: corrupt BAM record")
+	}
+	le := binary.LittleEndian
+	refID := int32(le.Uint32(b[0:]))
+	rec := &Record{
+		Pos:   int(int32(le.Uint32(b[4:]))),
+		MapQ:  b[9],
+		Flags: Flags(le.Uint16(b[14:])),
+	}
+	nameLen := int(b[8])
+	nCigar := int(le.Uint16(b[12:]))
+	seqLen := int(int32(le.Uint32(b[16:])))
+	if refID >= 0 {
+		if int(refID) >= len(r.refs) {
+			return nil, errors.New("align: corrupt BAM record")
+		}
+		rec.Ref = r.refs[refID]
+	}
+	p := 32
+	if p+nameLen+4*nCigar+(seqLen+1)/2+seqLen > len(b) || seqLen < 0 {
+		return nil, errors.New("align: corrupt BAM record")
+	}
+	rec.Name = string(bytes.TrimRight(b[p:p+nameLen], "\x00"))
+	p += nameLen
+	for i := 0; i < nCigar; i++ {
+		v := le.Uint32(b[p:])
+		if v&0xf > 8 {
+			return nil, errors.New("align: corrupt BAM record")
+		}
+		rec.Cigar = append(rec.Cigar, CigarOp{Op: "MIDNSHP=X"[v&0xf], Len: int(v >> 4)})
+		p += 4
+	}
+	p += (seqLen+1)/2 + seqLen
+	rec.NH, err = auxNH(b[p:])
+	if err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
+
+// auxNH returns the value of the NH tag in the BAM auxiliary data b, or zero
+// if it is absent.
+func auxNH(b []byte) (int, error) {
+	le := binary.LittleEndian
+	for len(b) >= 3 {
+		tag, typ := string(b[:2]), b[2]
+		b = b[3:]
+		var size int
+		switch typ {
+		case 'A', 'c', 'C':
+			size = 1
+		case 's', 'S':
+			size = 2
+		case 'i', 'I', 'f':
+			size = 4
+		case 'Z', 'H':
+			i := bytes.IndexByte(b, 0)
+			if i < 0 {
+				return 0, errors.New("align: corrupt BAM auxiliary data")
+			}
+			size = i + 1
+		case 'B':
+			if len(b) < 5 {
+				return 0, errors.New("align: corrupt BAM auxiliary data")
+			}
+			var elem int
+			switch b[0] {
+			case 'c', 'C':
+				elem = 1
+			case 's', 'S':
+				elem = 2
+			case 'i', 'I', 'f':
+				elem = 4
+			default:
+				return 0, errors.New("align: corrupt BAM auxiliary data")
+			}
+			size = 5 + elem*int(le.Uint32(b[1:]))
+		default:
+			return 0, errors.New("align: corrupt BAM auxiliary data")
+		}
+		if size > len(b) {
+			return 0, errors.New("align: corrupt BAM auxiliary data")
+		}
+		if tag == "NH" {
+			switch typ {
+			case 'c':
+				return int(int8(b[0])), nil
+			case 'C':
+				return int(b[0]), nil
+			case 's':
+				return int(int16(le.Uint16(b))), nil
+			case 'S':
+				return int(le.Uint16(b)), nil
+			case 'i':
+				return int(int32(le.Uint32(b))), nil
+			case 'I':
+				return int(le.Uint32(b)), nil
+			}
+		}
+		b = b[size:]
+	}
+	return 0, nil
+}
@@ -0,0 +1,154 @@
+package align
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var samInput = "" +
+	"@HD\tVN:1.6\n" +
+	"@SQ\tSN:X\tLN:1000\n" +
+	"r1\t0\tX\t11\t60\t5M10N5M\t*\t0\t0\tACGTAACGTA\t*\tNH:i:1\n" +
+	"r2\t16\tX\t21\t3\t2S4M2D3M1I2M\t*\t0\t0\t*\t*\n" +
+	"r3\t4\t*\t0\t0\t*\t*\t0\t0\tACGT\t*\n"
+
+var samWant = []*Record{
+	{Name: "r1", Ref: "X", Pos: 10, MapQ: 60, Cigar: []CigarOp{{'M', 5}, {'N', 10}, {'M', 5}}, NH: 1},
+	{Name: "r2", Ref: "X", Pos: 20, Flags: Reverse, MapQ: 3, Cigar: []CigarOp{{'S', 2}, {'M', 4}, {'D', 2}, {'M', 3}, {'I', 1}, {'M', 2}}},
+	{Name: "r3", Pos: -1, Flags: Unmapped},
+}
+
+func readAll(t *testing.T, r Reader) []*Record {
+	var recs []*Record
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			return recs
+		}
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		recs = append(recs, rec)
+	}
+}
+
+// Test SAMReader
+func TestSAMReader(t *testing.T) {
+	r, err := NewReader(strings.NewReader(samInput))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if _, ok := r.(*SAMReader); !ok {
+		t.Fatalf("out reader=%T want *SAMReader", r)
+	}
+	if got := readAll(t, r); !reflect.DeepEqual(got, samWant) {
+		t.Errorf("out records=%+v\nwant %+v", got, samWant)
+	}
+
+	for _, test := range []struct {
+		in, err string
+	}{
+		{"r\t0\tX\t1\t60\t5M\n", "align: line 1: too few fields"},
+		{"r\t0\tX\t1\t60\t5Q\t*\t0\t0\t*\t*\n", "align: line 1: bad CIGAR \"5Q\""},
+		{"r\t0\tX\t1\t60\tM\t*\t0\t0\t*\t*\n", "align: line 1: bad CIGAR \"M\""},
+		{"r\tx\tX\t1\t60\t5M\t*\t0\t0\t*\t*\n", "align: line 1: bad flags \"x\""},
+	} {
+		_, err := NewSAMReader(strings.NewReader(test.in)).Read()
+		if err == nil || err.Error() != test.err {
+			t.Errorf("%q: error %q, want error %q", test.in, err, test.err)
+		}
+	}
+}
+
+// bamRecord returns the BAM encoding of rec with the refID and auxiliary
+// data given.
+func bamRecord(refID int32, rec *Record, aux []byte) []byte {
+	le := binary.LittleEndian
+	name := append([]byte(rec.Name), 0)
+	seqLen := 3
+	b := make([]byte, 32)
+	le.PutUint32(b[0:], uint32(refID))
+	le.PutUint32(b[4:], uint32(int32(rec.Pos)))
+	b[8] = byte(len(name))
+	b[9] = rec.MapQ
+	le.PutUint16(b[12:], uint16(len(rec.Cigar)))
+	le.PutUint16(b[14:], uint16(rec.Flags))
+	le.PutUint32(b[16:], uint32(seqLen))
+	b = append(b, name...)
+	for _, c := range rec.Cigar {
+		var v [4]byte
+		le.PutUint32(v[:], uint32(c.Len)<<4|uint32(strings.IndexByte("MIDNSHP=X", c.Op)))
+		b = append(b, v[:]...)
+	}
+	b = append(b, make([]byte, (seqLen+1)/2+seqLen)...)
+	b = append(b, aux...)
+	var size [4]byte
+	le.PutUint32(size[:], uint32(len(b)))
+	return append(size[:], b...)
+}
+
+// Test BAMReader
+func TestBAMReader(t *testing.T) {
+	le := binary.LittleEndian
+	var raw bytes.Buffer
+	raw.WriteString("BAM\x01")
+	text := "@SQ\tSN:X\tLN:1000\n"
+	binary.Write(&raw, le, int32(len(text)))
+	raw.WriteString(text)
+	binary.Write(&raw, le, int32(2))
+	for _, ref := range []string{"Y", "X"} {
+		binary.Write(&raw, le, int32(len(ref)+1))
+		raw.WriteString(ref + "\x00")
+		binary.Write(&raw, le, int32(1000))
+	}
+	raw.Write(bamRecord(1, samWant[0], []byte("XAZabc\x00NHC\x01")))
+	raw.Write(bamRecord(1, samWant[1], []byte("BCBs\x02\x00\x00\x00\x01\x00\x02\x00")))
+	raw.Write(bamRecord(-1, samWant[2], nil))
+
+	// Split the data into two gzip members as in BGZF.
+	var gz bytes.Buffer
+	for _, part := range [][]byte{raw.Bytes()[:50], raw.Bytes()[50:]} {
+		w := gzip.NewWriter(&gz)
+		w.Write(part)
+		w.Close()
+	}
+	r, err := NewReader(&gz)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	br, ok := r.(*BAMReader)
+	if !ok {
+		t.Fatalf("out reader=%T want *BAMReader", r)
+	}
+	if got, want := br.Refs(), []string{"Y", "X"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("out refs=%v want %v", got, want)
+	}
+	if got := readAll(t, r); !reflect.DeepEqual(got, samWant) {
+		t.Errorf("out records=%+v\nwant %+v", got, samWant)
+	}
+}
+
+// Test Blocks and End
+func TestBlocks(t *testing.T) {
+	for _, test := range []struct {
+		rec    *Record
+		blocks [][2]int
+		end    int
+	}{
+		{samWant[0], [][2]int{{10, 15}, {25, 30}}, 30},
+		{samWant[1], [][2]int{{20, 31}}, 31},
+		{samWant[2], nil, -1},
+	} {
+		if got := test.rec.Blocks(); !reflect.DeepEqual(got, test.blocks) {
+			t.Errorf("%s: out blocks=%v want %v", test.rec.Name, got, test.blocks)
+		}
+		if got := test.rec.End(); got != test.end {
+			t.Errorf("%s: out end=%d want %d", test.rec.Name, got, test.end)
+		}
+	}
+}
@@ -0,0 +1,426 @@
+// Package annotate predicts the consequences of variants on gene models.
+//
+// An Annotator finds the genes near a variant with a region index and
+// classifies the effect of the variant on each of their transcripts using the
+// exon and coding sequence structure built by geneio.GeneReader.
+// Consequences are named by their Sequence Ontology terms. Amino acid changes
+// of substitutions are only predicted when a reference sequence is given.
+package annotate
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/biogo/biogo/feat/gene"
+	"github.com/go-bio/geneio"
+	"github.com/go-bio/geneio/coordmap"
+	"github.com/go-bio/geneio/index"
+)
+
+// Consequence is the consequence of a variant on a transcript. Consequences
+// are ordered from most to least severe.
+type Consequence int
+
+// Consequences.
+const (
+	Frameshift Consequence = iota
+	StopGained
+	StopLost
+	StartLost
+	SpliceAcceptor
+	SpliceDonor
+	InframeInsertion
+	InframeDeletion
+	Missense
+	SpliceRegion
+	Synonymous
+	CodingSequence
+	UTR5
+	UTR3
+	NonCodingExon
+	Intronic
+	Upstream
+	Downstream
+)
+
+var consequenceTerms = [...]string{
+	Frameshift:       "frameshift_variant",
+	StopGained:       "stop_gained",
+	StopLost:         "stop_lost",
+	StartLost:        "start_lost",
+	SpliceAcceptor:   "splice_acceptor_variant",
+	SpliceDonor:      "splice_donor_variant",
+	InframeInsertion: "inframe_insertion",
+	InframeDeletion:  "inframe_deletion",
+	Missense:         "missense_variant",
+	SpliceRegion:     "splice_region_variant",
+	Synonymous:       "synonymous_variant",
+	CodingSequence:   "coding_sequence_variant",
+	UTR5:             "5_prime_UTR_variant",
+	UTR3:             "3_prime_UTR_variant",
+	NonCodingExon:    "non_coding_transcript_exon_variant",
+	Intronic:         "intron_variant",
+	Upstream:         "upstream_gene_variant",
+	Downstream:       "downstream_gene_variant",
+}
+
+// String returns the Sequence Ontology term of c.
+func (c Consequence) String() string {
+	if c < 0 || int(c) >= len(consequenceTerms) {
+		return "unknown"
+	}
+	return consequenceTerms[c]
+}
+
+// Variant is a variant on the forward strand of a reference sequence. Pos
+// is the zero based position of the first base of Ref.
+type Variant struct {
+	Chrom    string
+	Pos      int
+	Ref, Alt string
+}
+
+// String returns v as chrom:pos:ref:alt with a one based position.
+func (v Variant) String() string {
+	return fmt.Sprintf("%s:%d:%s:%s", v.Chrom, v.Pos+1, v.Ref, v.Alt)
+}
+
+// Symbolic returns whether the alternate allele of v is symbolic, e.g. <DEL>
+// or the * allele of VCF, or a breakend.
+func (v Variant) Symbolic() bool {
+	return v.Alt == "*" || strings.ContainsAny(v.Alt, "<>[].")
+}
+
+// Normalize returns v without the bases that Ref and Alt share at their ends,
+// such as the padding base of VCF indels.
+func (v Variant) Normalize() Variant {
+	for len(v.Ref) > 0 && len(v.Alt) > 0 && v.Ref[len(v.Ref)-1] == v.Alt[len(v.Alt)-1] {
+		v.Ref, v.Alt = v.Ref[:len(v.Ref)-1], v.Alt[:len(v.Alt)-1]
+	}
+	for len(v.Ref) > 0 && len(v.Alt) > 0 && v.Ref[0] == v.Alt[0] {
+		v.Ref, v.Alt = v.Ref[1:], v.Alt[1:]
+		v.Pos++
+	}
+	return v
+}
+
+// ParseVCF returns the variants of the VCF data line, one for each
+// alternate allele in order.
+func ParseVCF(line string) ([]Variant, error) {
+	fields := strings.SplitN(line, "\t", 6)
+	if len(fields) < 5 {
+		return nil, errors.New("annotate: too few VCF fields")
+	}
+	pos, err := strconv.Atoi(fields[1])
+	if err != nil || pos < 1 {
+		return nil, fmt.Errorf("annotate: bad VCF position %q", fields[1])
+	}
+	alts := strings.Split(fields[4], ",")
+	vs := make([]Variant, len(alts))
+	for i, alt := range alts {
+		vs[i] = Variant{Chrom: fields[0], Pos: pos - 1, Ref: fields[3], Alt: alt}
+	}
+	return vs, nil
+}
+
+// Effect is the effect of a variant on a transcript.
+type Effect struct {
+	Gene, Transcript string
+	// Consequences are the consequences ordered by severity.
+	Consequences []Consequence
+	// Codon is the first affected codon of coding variants and RefAA and
+	// AltAA its reference and alternate amino acids, if known.
+	Codon        int
+	RefAA, AltAA byte
+}
+
+// Annotator predicts the effects of variants on indexed genes. An Annotator
+// caches the coordinate mappers of transcripts and is not safe for
+// concurrent use.
+type Annotator struct {
+	idx *index.Index
+	seq geneio.Sequencer
+
+	// mappers holds the coordinate mappers of the transcripts seen.
+	mappers map[gene.Transcript]*coordmap.Mapper
+
+	// Distance is the largest distance from a transcript at which variants
+	// are reported as upstream or downstream variants.
+	Distance int
+}
+
+// NewAnnotator returns a new Annotator of variants on the genes of idx with
+// a Distance of 5000. seq provides the reference sequence for amino acid
+// prediction and may be nil.
+func NewAnnotator(idx *index.Index, seq geneio.Sequencer) *Annotator {
+	return &Annotator{idx: idx, seq: seq, Distance: 5000, mappers: make(map[gene.Transcript]*coordmap.Mapper)}
+}
+
+// mapper returns the coordinate mapper of t, creating it on first use.
+func (a *Annotator) mapper(t gene.Transcript) (*coordmap.Mapper, error) {
+	if m, ok := a.mappers[t]; ok {
+		return m, nil
+	}
+	m, err := coordmap.New(t)
+	if err != nil {
+		return nil, err
+	}
+	if a.mappers == nil {
+		a.mappers = make(map[gene.Transcript]*coordmap.Mapper)
+	}
+	a.mappers[t] = m
+	return m, nil
+}
+
+// Annotate returns the effects of v on the transcripts near it, ordered by
+// gene start and transcript. Symbolic variants have no effects.
+func (a *Annotator) Annotate(v Variant) ([]Effect, error) {
+	if v.Symbolic() {
+		return nil, nil
+	}
+	v = v.Normalize()
+	if v.Ref == "" && v.Alt == "" {
+		return nil, nil
+	}
+	var effects []Effect
+	end := v.Pos + len(v.Ref)
+	for _, g := range a.idx.Overlapping(v.Chrom, v.Pos-a.Distance-1, end+a.Distance+1) {
+		for _, t := range geneio.Transcripts(g) {
+			e, ok, err := a.effect(g, t, v)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				effects = append(effects, e)
+			}
+		}
+	}
+	return effects, nil
+}
+
+// effect returns the effect of the normalized variant v on t and whether
+// there is one.
+func (a *Annotator) effect(g gene.Interface, t gene.Transcript, v Variant) (Effect, bool, error) {
+	eff := Effect{Gene: g.Name(), Transcript: t.Name()}
+	m, err := a.mapper(t)
+	if err != nil {
+		return eff, false, err
+	}
+	ts, _ := geneio.RefPosition(t, 0)
+	te := ts + t.Len()
+	s, e := v.Pos, v.Pos+len(v.Ref)
+	insertion := s == e
+	reverse := geneio.RefOrientation(t) < 0
+
+	inside := s < te && e > ts
+	if insertion {
+		inside = s > ts && s < te
+	}
+	if !inside {
+		before := e <= ts
+		dist := ts - e
+		if !before {
+			dist = s - te
+		}
+		if dist >= a.Distance {
+			return eff, false, nil
+		}
+		if before != reverse {
+			eff.Consequences = []Consequence{Upstream}
+		} else {
+			eff.Consequences = []Consequence{Downstream}
+		}
+		return eff, true, nil
+	}
+
+	first, last := s, e-1
+	if first < ts {
+		first = ts
+	}
+	if last >= te {
+		last = te - 1
+	}
+	if insertion {
+		first, last = s-1, s
+	}
+	cons := make(map[Consequence]bool)
+	coding := false
+	for pos := first; pos <= last; pos++ {
+		p, err := m.GenomicToTranscript(pos)
+		if err != nil {
+			return eff, false, err
+		}
+		switch off := p.Offset; {
+		case off == 1 || off == 2:
+			cons[SpliceDonor] = true
+		case off == -1 || off == -2:
+			cons[SpliceAcceptor] = true
+		case off != 0:
+			cons[Intronic] = true
+			if off >= -8 && off <= 8 {
+				cons[SpliceRegion] = true
+			}
+		default:
+			if a.nearIntron(m, pos) {
+				cons[SpliceRegion] = true
+			}
+			if !m.Coding() {
+				cons[NonCodingExon] = true
+				break
+			}
+			c, err := m.TranscriptToCDS(p)
+			if err != nil {
+				return eff, false, err
+			}
+			switch {
+			case c.UTR3:
+				cons[UTR3] = true
+			case c.Pos < 0:
+				cons[UTR5] = true
+			default:
+				coding = true
+				if c.Pos <= m.Phase() {
+					cons[CodingSequence] = true
+					break
+				}
+				codon, _, err := m.CDSToProtein(c)
+				if err != nil {
+					return eff, false, err
+				}
+				if eff.Codon == 0 || codon < eff.Codon {
+					eff.Codon = codon
+				}
+			}
+		}
+	}
+	if coding {
+		switch diff := len(v.Alt) - len(v.Ref); {
+		case diff%3 != 0:
+			cons[Frameshift] = true
+		case diff > 0:
+			cons[InframeInsertion] = true
+		case diff < 0:
+			cons[InframeDeletion] = true
+		default:
+			if err := a.substitution(m, t, v, reverse, &eff, cons); err != nil {
+				return eff, false, err
+			}
+		}
+	}
+	for c := range cons {
+		eff.Consequences = append(eff.Consequences, c)
+	}
+	sort.Slice(eff.Consequences, func(i, j int) bool { return eff.Consequences[i] < eff.Consequences[j] })
+	return eff, true, nil
+}
+
+// nearIntron returns whether the exonic position pos is within three bases
+// of an intron of the transcript of m.
+func (a *Annotator) nearIntron(m *coordmap.Mapper, pos int) bool {
+	for d := 1; d <= 3; d++ {
+		for _, n := range []int{pos - d, pos + d} {
+			if p, err := m.GenomicToTranscript(n); err == nil && p.Offset != 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// substitution adds the consequences of the coding substitution v to cons
+// and records the amino acids of the first affected codon in eff. Codons are
+// numbered by m from the first complete codon of the coding sequence.
+// Codons with a translational exception of t translate as the exception
+// unless the substitution changes their amino acid, and only a first codon
+// translated as methionine in a coding sequence whose start was found can
+// lose the start.
+func (a *Annotator) substitution(m *coordmap.Mapper, t gene.Transcript, v Variant, reverse bool, eff *Effect, cons map[Consequence]bool) error {
+	if a.seq == nil {
+		cons[CodingSequence] = true
+		return nil
+	}
+	ref, err := a.seq.Seq(v.Chrom, v.Pos, v.Pos+len(v.Ref))
+	if err != nil {
+		return err
+	}
+	if !bytes.EqualFold(ref, []byte(v.Ref)) {
+		return fmt.Errorf("annotate: reference allele %s does not match sequence %s at %s:%d", v.Ref, ref, v.Chrom, v.Pos+1)
+	}
+	exceptions := geneio.ExceptionCodons(t)
+	startNF := geneio.StartNotFound(t)
+	seen := make(map[int]bool)
+	for pos := v.Pos; pos < v.Pos+len(v.Ref); pos++ {
+		c, err := m.GenomicToCDS(pos)
+		if err != nil {
+			return err
+		}
+		if c.UTR3 || c.Pos <= m.Phase() || c.Offset != 0 {
+			continue
+		}
+		codon, frame, err := m.CDSToProtein(c)
+		if err != nil {
+			return err
+		}
+		// start is the zero based position of the first base of the codon
+		// in the coding sequence, as keyed by geneio.ExceptionCodons.
+		start := c.Pos - 1 - frame
+		if seen[codon] {
+			continue
+		}
+		seen[codon] = true
+		refCodon, altCodon := make([]byte, 3), make([]byte, 3)
+		for j := range refCodon {
+			g, err := m.CDSToGenomic(coordmap.CDSPos{Pos: start + 1 + j})
+			if err != nil {
+				return err
+			}
+			b, err := a.seq.Seq(v.Chrom, g, g+1)
+			if err != nil {
+				return err
+			}
+			refCodon[j], altCodon[j] = b[0], b[0]
+			if g >= v.Pos && g < v.Pos+len(v.Alt) {
+				altCodon[j] = v.Alt[g-v.Pos]
+			}
+		}
+		if reverse {
+			refCodon, altCodon = complement(refCodon), complement(altCodon)
+		}
+		refAA, altAA := geneio.Translate(refCodon)[0], geneio.Translate(altCodon)[0]
+		if aa, ok := exceptions[start]; ok {
+			if altAA == refAA {
+				altAA = aa
+			}
+			refAA = aa
+		}
+		if codon == eff.Codon {
+			eff.RefAA, eff.AltAA = refAA, altAA
+		}
+		switch {
+		case codon == 1 && !startNF && refAA == 'M' && altAA != 'M':
+			cons[StartLost] = true
+		case refAA == '*' && altAA != '*':
+			cons[StopLost] = true
+		case altAA == '*' && refAA != '*':
+			cons[StopGained] = true
+		case refAA != altAA:
+			cons[Missense] = true
+		default:
+			cons[Synonymous] = true
+		}
+	}
+	return nil
+}
+
+// complement returns the complement of the bases of s in place order.
+func complement(s []byte) []byte {
+	rc := geneio.ReverseComplement(s)
+	for i, j := 0, len(rc)-1; i < j; i, j = i+1, j-1 {
+		rc[i], rc[j] = rc[j], rc[i]
+	}
+	return rc
+}
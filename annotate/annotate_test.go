@@ -0,0 +1,150 @@
+package annotate
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/biogo/biogo/io/featio/gff"
+	"github.com/go-bio/geneio"
+	ggff "github.com/go-bio/geneio/gff"
+	"github.com/go-bio/geneio/index"
+)
+
+var annotateInput = "" +
+	"X\t.\texon\t11\t20\t0\t+\t.\tgene_id F; transcript_id F1;\n" +
+	"X\t.\texon\t31\t40\t0\t+\t.\tgene_id F; transcript_id F1;\n" +
+	"X\t.\tstart_codon\t13\t15\t0\t+\t.\tgene_id F; transcript_id F1;\n" +
+	"X\t.\tstop_codon\t35\t37\t0\t+\t.\tgene_id F; transcript_id F1;\n" +
+	"Y\t.\texon\t11\t20\t0\t-\t.\tgene_id R; transcript_id R1;\n" +
+	"Y\t.\texon\t31\t40\t0\t-\t.\tgene_id R; transcript_id R1;\n" +
+	"Y\t.\tstart_codon\t35\t37\t0\t-\t.\tgene_id R; transcript_id R1;\n" +
+	"Y\t.\tstop_codon\t13\t15\t0\t-\t.\tgene_id R; transcript_id R1;\n" +
+	"Z\t.\texon\t51\t60\t0\t+\t.\tgene_id N; transcript_id N1;\n" +
+	"W\t.\texon\t1\t13\t.\t+\t.\tgene_id P; transcript_id P1; tag cds_start_NF;\n" +
+	"W\t.\tCDS\t1\t10\t.\t+\t1\tgene_id P; transcript_id P1; tag cds_start_NF;\n" +
+	"W\t.\tstop_codon\t11\t13\t.\t+\t0\tgene_id P; transcript_id P1; tag cds_start_NF;\n" +
+	"W\t.\tSelenocysteine\t2\t4\t.\t+\t.\tgene_id P; transcript_id P1; tag cds_start_NF;\n" +
+	"V\t.\texon\t1\t9\t.\t+\t.\tgene_id L; transcript_id L1;\n" +
+	"V\t.\tstart_codon\t1\t3\t.\t+\t.\tgene_id L; transcript_id L1;\n" +
+	"V\t.\tstop_codon\t7\t9\t.\t+\t.\tgene_id L; transcript_id L1;\n"
+
+// sequence returns the sequence of X holding the coding sequence of F1,
+// ATG GCC TG|G AAA TAA, in an otherwise C sequence, and of W holding the
+// coding sequence of P1 with a phase of one and a selenocysteine codon,
+// C TGA AAA GGC TAA, and of V holding the coding sequence of L1 with a non
+// AUG start codon, CTG AAA TAA.
+func sequence() geneio.SeqMap {
+	x := []byte(strings.Repeat("C", 100))
+	copy(x[12:], "ATGGCCTG")
+	copy(x[30:], "GAAATAA")
+	return geneio.SeqMap{"X": x, "W": []byte("CTGAAAAGGCTAA"), "V": []byte("CTGAAATAA")}
+}
+
+func annotator(t *testing.T, seq geneio.Sequencer) *Annotator {
+	idx, err := index.Read(ggff.NewReader(gff.NewReader(strings.NewReader(annotateInput))))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	return NewAnnotator(idx, seq)
+}
+
+// Test Annotate
+var annotateTests = []struct {
+	Variant      Variant
+	Consequences []Consequence
+	Codon        int
+	AA           string
+	Error        string
+}{
+	{Variant: Variant{"X", 12, "A", "G"}, Consequences: []Consequence{StartLost}, Codon: 1, AA: "MV"},
+	{Variant: Variant{"X", 16, "C", "T"}, Consequences: []Consequence{Missense}, Codon: 2, AA: "AV"},
+	{Variant: Variant{"X", 17, "C", "T"}, Consequences: []Consequence{SpliceRegion, Synonymous}, Codon: 2, AA: "AA"},
+	{Variant: Variant{"X", 31, "A", "T"}, Consequences: []Consequence{StopGained, SpliceRegion}, Codon: 4, AA: "K*"},
+	{Variant: Variant{"X", 34, "T", "C"}, Consequences: []Consequence{StopLost}, Codon: 5, AA: "*Q"},
+	{Variant: Variant{"X", 20, "C", "A"}, Consequences: []Consequence{SpliceDonor}},
+	{Variant: Variant{"X", 29, "C", "A"}, Consequences: []Consequence{SpliceAcceptor}},
+	{Variant: Variant{"X", 25, "C", "A"}, Consequences: []Consequence{SpliceRegion, Intronic}},
+	{Variant: Variant{"X", 10, "C", "A"}, Consequences: []Consequence{UTR5}},
+	{Variant: Variant{"X", 38, "C", "A"}, Consequences: []Consequence{UTR3}},
+	{Variant: Variant{"X", 15, "AGC", "A"}, Consequences: []Consequence{Frameshift, SpliceRegion}, Codon: 2},
+	{Variant: Variant{"X", 15, "GCCT", "G"}, Consequences: []Consequence{InframeDeletion, SpliceRegion}, Codon: 2},
+	{Variant: Variant{"X", 16, "C", "CAAA"}, Consequences: []Consequence{InframeInsertion, SpliceRegion}, Codon: 2},
+	{Variant: Variant{"X", 5, "C", "A"}, Consequences: []Consequence{Upstream}},
+	{Variant: Variant{"X", 100, "C", "A"}, Consequences: []Consequence{Downstream}},
+	{Variant: Variant{"X", 6000, "C", "A"}},
+	{Variant: Variant{"X", 16, "C", "<DEL>"}},
+	{Variant: Variant{"X", 12, "G", "A"}, Error: "annotate: reference allele G does not match sequence A at X:13"},
+	{Variant: Variant{"Y", 36, "A", "G"}, Consequences: []Consequence{CodingSequence}, Codon: 1},
+	{Variant: Variant{"Y", 29, "C", "A"}, Consequences: []Consequence{SpliceDonor}},
+	{Variant: Variant{"Y", 45, "C", "A"}, Consequences: []Consequence{Upstream}},
+	{Variant: Variant{"Y", 5, "C", "A"}, Consequences: []Consequence{Downstream}},
+	{Variant: Variant{"Z", 55, "C", "A"}, Consequences: []Consequence{NonCodingExon}},
+	{Variant: Variant{"W", 0, "C", "A"}, Consequences: []Consequence{CodingSequence}},
+	{Variant: Variant{"W", 3, "A", "G"}, Consequences: []Consequence{Missense}, Codon: 1, AA: "UW"},
+	{Variant: Variant{"W", 2, "G", "A"}, Consequences: []Consequence{Synonymous}, Codon: 1, AA: "UU"},
+	{Variant: Variant{"W", 4, "A", "T"}, Consequences: []Consequence{StopGained}, Codon: 2, AA: "K*"},
+	{Variant: Variant{"V", 2, "G", "C"}, Consequences: []Consequence{Synonymous}, Codon: 1, AA: "LL"},
+}
+
+func TestAnnotate(t *testing.T) {
+	withSeq, noSeq := annotator(t, sequence()), annotator(t, nil)
+	for _, tt := range annotateTests {
+		a := withSeq
+		if tt.Variant.Chrom != "X" && tt.Variant.Chrom != "W" && tt.Variant.Chrom != "V" {
+			a = noSeq
+		}
+		effects, err := a.Annotate(tt.Variant)
+		if tt.Error != "" {
+			if err == nil || err.Error() != tt.Error {
+				t.Errorf("%s: error %q, want error %q", tt.Variant, err, tt.Error)
+			}
+			continue
+		} else if err != nil {
+			t.Errorf("%s: unexpected error %v", tt.Variant, err)
+			continue
+		}
+		if tt.Consequences == nil {
+			if len(effects) != 0 {
+				t.Errorf("%s: out effects=%+v want none", tt.Variant, effects)
+			}
+			continue
+		}
+		if len(effects) != 1 {
+			t.Errorf("%s: out effects=%+v want one", tt.Variant, effects)
+			continue
+		}
+		e := effects[0]
+		if !reflect.DeepEqual(e.Consequences, tt.Consequences) {
+			t.Errorf("%s: out consequences=%v want %v", tt.Variant, e.Consequences, tt.Consequences)
+		}
+		if e.Codon != tt.Codon {
+			t.Errorf("%s: out codon=%d want %d", tt.Variant, e.Codon, tt.Codon)
+		}
+		if tt.AA != "" && string([]byte{e.RefAA, e.AltAA}) != tt.AA {
+			t.Errorf("%s: out amino acids=%c%c want %s", tt.Variant, e.RefAA, e.AltAA, tt.AA)
+		}
+	}
+}
+
+func TestParseVCF(t *testing.T) {
+	vs, err := ParseVCF("X\t17\trs1\tAC\tA,ACC,<DEL>\t50\tPASS\t.")
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	want := []Variant{{"X", 16, "AC", "A"}, {"X", 16, "AC", "ACC"}, {"X", 16, "AC", "<DEL>"}}
+	if !reflect.DeepEqual(vs, want) {
+		t.Errorf("out variants=%v want %v", vs, want)
+	}
+	if got, want := vs[1].Normalize(), (Variant{"X", 17, "", "C"}); got != want {
+		t.Errorf("out normalized=%v want %v", got, want)
+	}
+	if !vs[2].Symbolic() || vs[0].Symbolic() {
+		t.Errorf("unexpected symbolic alleles %v", vs)
+	}
+	for _, line := range []string{"X\t17\t.\tA", "X\tx\t.\tA\tC"} {
+		if _, err := ParseVCF(line); err == nil {
+			t.Errorf("%q: expected error", line)
+		}
+	}
+}
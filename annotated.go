@@ -0,0 +1,224 @@
+package geneio
+
+import (
+	"sort"
+
+	"github.com/biogo/biogo/feat"
+	"github.com/biogo/biogo/feat/gene"
+)
+
+// Transcript tags that mark coding sequences whose ends were not found, as
+// used by GENCODE and Ensembl.
+const (
+	TagCDSStartNF   = "cds_start_NF"
+	TagCDSEndNF     = "cds_end_NF"
+	TagStartCodonNF = "start_codon_NF"
+	TagStopCodonNF  = "stop_codon_NF"
+)
+
+// Tagger is implemented by Features that carry tags, such as the tag
+// attributes of GENCODE GTF records. GeneReader attaches the tags of the
+// features of a coding transcript to the transcript.
+type Tagger interface {
+	Tags() []string
+}
+
+// Framer is implemented by Features that have a reading frame. Frame returns
+// the number of bases to remove from the 5' end of the feature to reach the
+// first base of a codon, or -1 if the feature has no frame.
+type Framer interface {
+	Frame() int
+}
+
+// Exception is a translational exception, a codon that is translated as
+// AminoAcid instead of by the genetic code, such as a UGA codon that encodes
+// selenocysteine ('U').
+type Exception struct {
+	// Offset is the position of the first base of the codon in the
+	// direction of transcription, relative to the transcript.
+	Offset    int
+	AminoAcid byte
+}
+
+// Annotated is implemented by coding transcripts that carry tags and
+// translational exceptions. Code that needs the coding sequence of a
+// transcript should use Coding rather than a type assertion to
+// *gene.CodingTranscript so that Annotated transcripts are recognised.
+type Annotated interface {
+	gene.Transcript
+	// Coding returns the underlying coding transcript.
+	Coding() *gene.CodingTranscript
+	// TranscriptTags returns the tags of the transcript.
+	TranscriptTags() []string
+	// TranslationExceptions returns the translational exceptions of the
+	// transcript.
+	TranslationExceptions() []Exception
+	// CodingPhase returns the number of bases at the 5' end of the coding
+	// sequence before the first complete codon.
+	CodingPhase() int
+}
+
+// AnnotatedTranscript is a coding transcript with tags, translational
+// exceptions and attributes. It implements Annotated and Attributer.
+type AnnotatedTranscript struct {
+	*gene.CodingTranscript
+	Tags       []string
+	Exceptions []Exception
+	// Phase is the number of bases at the 5' end of the coding sequence
+	// before the first complete codon. It is only non zero for coding
+	// sequences whose start was not found.
+	Phase int
+	Attrs map[string]string
+}
+
+// Coding returns the underlying coding transcript of t.
+func (t *AnnotatedTranscript) Coding() *gene.CodingTranscript { return t.CodingTranscript }
+
+// TranscriptTags returns the tags of t.
+func (t *AnnotatedTranscript) TranscriptTags() []string { return t.Tags }
+
+// TranslationExceptions returns the translational exceptions of t.
+func (t *AnnotatedTranscript) TranslationExceptions() []Exception { return t.Exceptions }
+
+// CodingPhase returns the phase of the 5' end of the coding sequence of t.
+func (t *AnnotatedTranscript) CodingPhase() int { return t.Phase }
+
+// Attributes returns the attributes of t.
+func (t *AnnotatedTranscript) Attributes() map[string]string { return t.Attrs }
+
+// Coding returns the coding transcript of t, which is t itself or the
+// underlying coding transcript of an Annotated transcript. It returns false
+// if t is not coding.
+func Coding(t gene.Transcript) (*gene.CodingTranscript, bool) {
+	switch t := t.(type) {
+	case *gene.CodingTranscript:
+		return t, true
+	case Annotated:
+		return t.Coding(), true
+	}
+	return nil, false
+}
+
+// CopyAnnotation returns ct with the tags, translational exceptions, coding
+// phase and attributes of t as an *AnnotatedTranscript if t is Annotated, or
+// ct itself otherwise. It is used when copying t to ct, which must have the same
+// exons and coding sequence relative to the transcript as t.
+func CopyAnnotation(t gene.Transcript, ct *gene.CodingTranscript) gene.Transcript {
+	a, ok := t.(Annotated)
+	if !ok {
+		return ct
+	}
+	return &AnnotatedTranscript{
+		CodingTranscript: ct,
+		Tags:             append([]string(nil), a.TranscriptTags()...),
+		Exceptions:       append([]Exception(nil), a.TranslationExceptions()...),
+		Phase:            a.CodingPhase(),
+		Attrs:            copyAttributes(Attributes(t)),
+	}
+}
+
+// copyAttributes returns a copy of attrs, or nil if attrs is empty.
+func copyAttributes(attrs map[string]string) map[string]string {
+	if len(attrs) == 0 {
+		return nil
+	}
+	c := make(map[string]string, len(attrs))
+	for k, v := range attrs {
+		c[k] = v
+	}
+	return c
+}
+
+// HasTag returns whether t is an Annotated transcript with the given tag.
+func HasTag(t gene.Transcript, tag string) bool {
+	a, ok := t.(Annotated)
+	return ok && hasTag(a.TranscriptTags(), tag)
+}
+
+// StartNotFound returns whether t is tagged as having a coding sequence
+// whose start was not found.
+func StartNotFound(t gene.Transcript) bool {
+	return HasTag(t, TagCDSStartNF) || HasTag(t, TagStartCodonNF)
+}
+
+// EndNotFound returns whether t is tagged as having a coding sequence whose
+// end was not found.
+func EndNotFound(t gene.Transcript) bool {
+	return HasTag(t, TagCDSEndNF) || HasTag(t, TagStopCodonNF)
+}
+
+// hasTag returns whether tags contains tag.
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// codingPhase returns the phase of the 5' end of the coding sequence of t.
+func codingPhase(t gene.Transcript) int {
+	if a, ok := t.(Annotated); ok {
+		return a.CodingPhase()
+	}
+	return 0
+}
+
+// codingExons returns the exonic segments of the coding sequence of ct
+// relative to ct in 5' to 3' order.
+func codingExons(ct *gene.CodingTranscript) [][2]int {
+	var ivs [][2]int
+	for _, e := range ct.Exons() {
+		start, end := e.Start(), e.End()
+		if start < ct.CDSstart {
+			start = ct.CDSstart
+		}
+		if end > ct.CDSend {
+			end = ct.CDSend
+		}
+		if start < end {
+			ivs = append(ivs, [2]int{start, end})
+		}
+	}
+	sort.Slice(ivs, func(i, j int) bool { return ivs[i][0] < ivs[j][0] })
+	if RefOrientation(ct) == feat.Reverse {
+		for i, j := 0, len(ivs)-1; i < j; i, j = i+1, j-1 {
+			ivs[i], ivs[j] = ivs[j], ivs[i]
+		}
+	}
+	return ivs
+}
+
+// ExceptionCodons returns the amino acids of the translational exceptions of
+// t keyed by the position of the first base of their codon in the coding
+// sequence, counted from its 5' end. Exceptions outside the coding sequence
+// are ignored. It returns nil if t has no exceptions.
+func ExceptionCodons(t gene.Transcript) map[int]byte {
+	a, ok := t.(Annotated)
+	if !ok || len(a.TranslationExceptions()) == 0 {
+		return nil
+	}
+	ct := a.Coding()
+	reverse := RefOrientation(ct) == feat.Reverse
+	phase := a.CodingPhase()
+	ivs := codingExons(ct)
+	codons := make(map[int]byte)
+	for _, x := range a.TranslationExceptions() {
+		n := 0
+		for _, iv := range ivs {
+			if x.Offset >= iv[0] && x.Offset < iv[1] {
+				d := x.Offset - iv[0]
+				if reverse {
+					d = iv[1] - 1 - x.Offset
+				}
+				if pos := n + d; pos >= phase {
+					codons[pos-(pos-phase)%3] = x.AminoAcid
+				}
+				break
+			}
+			n += iv[1] - iv[0]
+		}
+	}
+	return codons
+}
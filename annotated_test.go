@@ -0,0 +1,174 @@
+package geneio
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/biogo/biogo/feat/gene"
+	"github.com/biogo/biogo/io/featio/gff"
+)
+
+// Assert that interfaces are satisfied.
+var (
+	_ Annotated = (*AnnotatedTranscript)(nil)
+	_ Tagger    = (*taggedFeature)(nil)
+	_ Framer    = (*taggedFeature)(nil)
+)
+
+// taggedFeature is a FeatureImpl with the tags and frame of a GFF feature.
+type taggedFeature struct {
+	*FeatureImpl
+	tags  []string
+	frame int
+}
+
+func (f *taggedFeature) Tags() []string { return f.tags }
+func (f *taggedFeature) Frame() int     { return f.frame }
+
+// taggedReader is a FeatureReader that reads taggedFeatures.
+type taggedReader struct {
+	FeatureReaderImpl
+}
+
+func (r *taggedReader) Read() (Feature, error) {
+	f, err := r.r.Read()
+	if err != nil {
+		return nil, err
+	}
+	fi, err := r.NewFeatureImpl(f)
+	if err != nil {
+		return nil, err
+	}
+	gf := f.(*gff.Feature)
+	tf := &taggedFeature{FeatureImpl: fi, frame: int(gf.FeatFrame)}
+	for _, a := range gf.FeatAttributes {
+		if a.Tag == "tag" {
+			tf.tags = append(tf.tags, a.Value)
+		}
+	}
+	return tf, nil
+}
+
+// S1 has a CTG start codon and a selenocysteine in its second codon, N1 is
+// on the reverse strand with its start not found and a coding phase of one
+// and P1 has no tags.
+var annotatedInput = "" +
+	"X\t.\texon\t1\t9\t.\t+\t.\tgene_id S; transcript_id S1; tag basic;\n" +
+	"X\t.\texon\t13\t24\t.\t+\t.\tgene_id S; transcript_id S1; tag basic; tag seleno;\n" +
+	"X\t.\tstart_codon\t1\t3\t.\t+\t.\tgene_id S; transcript_id S1;\n" +
+	"X\t.\tstop_codon\t22\t24\t.\t+\t.\tgene_id S; transcript_id S1;\n" +
+	"X\t.\tSelenocysteine\t4\t6\t.\t+\t.\tgene_id S; transcript_id S1;\n" +
+	"Y\t.\texon\t1\t13\t.\t-\t.\tgene_id N; transcript_id N1; tag cds_start_NF;\n" +
+	"Y\t.\tCDS\t4\t13\t.\t-\t1\tgene_id N; transcript_id N1; tag cds_start_NF;\n" +
+	"Y\t.\tstop_codon\t1\t3\t.\t-\t0\tgene_id N; transcript_id N1; tag cds_start_NF;\n" +
+	"Z\t.\texon\t1\t12\t.\t+\t.\tgene_id P; transcript_id P1;\n" +
+	"Z\t.\tstart_codon\t1\t3\t.\t+\t.\tgene_id P; transcript_id P1;\n" +
+	"Z\t.\tstop_codon\t10\t12\t.\t+\t.\tgene_id P; transcript_id P1;\n"
+
+var annotatedSeqs = SeqMap{
+	"X": []byte("CTGTGAAAAgggCCCGGGTTTTAA"),
+	"Y": ReverseComplement([]byte("CGCTGCAAAATAA")),
+	"Z": []byte("CTGAAATTTTGA"),
+}
+
+func readAnnotated(t *testing.T, input string) []gene.Transcript {
+	r := NewGeneReader(&taggedReader{FeatureReaderImpl{r: gff.NewReader(strings.NewReader(input)), GID: "gene_id", TID: "transcript_id"}})
+	genes, err := r.ReadAll()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	var ts []gene.Transcript
+	for _, g := range genes {
+		ts = append(ts, Transcripts(g)...)
+	}
+	return ts
+}
+
+// Test reading annotated transcripts
+func TestGeneReaderAnnotated(t *testing.T) {
+	ts := readAnnotated(t, annotatedInput)
+	for _, test := range []struct {
+		name       string
+		annotated  bool
+		tags       []string
+		exceptions []Exception
+		phase      int
+		cds        [2]int
+	}{
+		{name: "S1", annotated: true, tags: []string{"basic", "seleno"}, exceptions: []Exception{{Offset: 3, AminoAcid: 'U'}}, cds: [2]int{0, 24}},
+		{name: "N1", annotated: true, tags: []string{"cds_start_NF"}, phase: 1, cds: [2]int{0, 13}},
+		{name: "P1", cds: [2]int{0, 12}},
+	} {
+		var tr gene.Transcript
+		for _, x := range ts {
+			if x.Name() == test.name {
+				tr = x
+			}
+		}
+		ct, ok := Coding(tr)
+		if !ok {
+			t.Errorf("%s: out coding=false want true", test.name)
+			continue
+		}
+		if got := [2]int{ct.CDSstart, ct.CDSend}; got != test.cds {
+			t.Errorf("%s: out cds=%v want %v", test.name, got, test.cds)
+		}
+		a, ok := tr.(Annotated)
+		if ok != test.annotated {
+			t.Errorf("%s: out annotated=%t want %t", test.name, ok, test.annotated)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if !reflect.DeepEqual(a.TranscriptTags(), test.tags) {
+			t.Errorf("%s: out tags=%v want %v", test.name, a.TranscriptTags(), test.tags)
+		}
+		if !reflect.DeepEqual(a.TranslationExceptions(), test.exceptions) {
+			t.Errorf("%s: out exceptions=%v want %v", test.name, a.TranslationExceptions(), test.exceptions)
+		}
+		if a.CodingPhase() != test.phase {
+			t.Errorf("%s: out phase=%d want %d", test.name, a.CodingPhase(), test.phase)
+		}
+	}
+	if !StartNotFound(ts[1]) || EndNotFound(ts[1]) || StartNotFound(ts[0]) {
+		t.Errorf("out start/end not found wrong for %s and %s", ts[0].Name(), ts[1].Name())
+	}
+	if got, want := ExceptionCodons(ts[0]), map[int]byte{3: 'U'}; !reflect.DeepEqual(got, want) {
+		t.Errorf("out exception codons=%v want %v", got, want)
+	}
+
+	// A tag without CDS features does not complete a coding sequence.
+	input := "" +
+		"Y\t.\texon\t1\t13\t.\t-\t.\tgene_id N; transcript_id N1; tag cds_start_NF;\n" +
+		"Y\t.\tstop_codon\t1\t3\t.\t-\t0\tgene_id N; transcript_id N1; tag cds_start_NF;\n"
+	r := NewGeneReader(&taggedReader{FeatureReaderImpl{r: gff.NewReader(strings.NewReader(input)), GID: "gene_id", TID: "transcript_id"}})
+	_, err := r.ReadAll()
+	want := "geneio: only one of start/stop codon found for N1 for gene N"
+	if err == nil || err.Error() != want {
+		t.Errorf("error %q, want error %q", err, want)
+	}
+}
+
+// Test TranslateTranscript
+func TestTranslateTranscript(t *testing.T) {
+	ts := readAnnotated(t, annotatedInput)
+	for i, want := range []string{"MUKPGF*", "AAK*", "MKF*"} {
+		got, err := StandardCode.TranslateTranscript(ts[i], annotatedSeqs)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", ts[i].Name(), err)
+			continue
+		}
+		if string(got) != want {
+			t.Errorf("%s: out translation=%s want %s", ts[i].Name(), got, want)
+		}
+	}
+
+	nc := &gene.NonCodingTranscript{ID: "T1", Loc: ts[0].Location()}
+	_, err := StandardCode.TranslateTranscript(nc, annotatedSeqs)
+	if want := errors.New("geneio: transcript T1 is not coding"); err == nil || err.Error() != want.Error() {
+		t.Errorf("error %q, want error %q", err, want)
+	}
+}
@@ -0,0 +1,84 @@
+package geneio
+
+import (
+	"github.com/biogo/biogo/feat/gene"
+)
+
+// Attributer is implemented by genes and transcripts that carry attributes,
+// such as the gene_name and Alias attributes of annotation records.
+type Attributer interface {
+	Attributes() map[string]string
+}
+
+// Attributes returns the attributes of f if it is an Attributer, or nil.
+func Attributes(f interface{}) map[string]string {
+	if a, ok := f.(Attributer); ok {
+		return a.Attributes()
+	}
+	return nil
+}
+
+// AttributedGene is a gene with attributes. It implements Attributer.
+type AttributedGene struct {
+	*gene.Gene
+	Attrs map[string]string
+}
+
+// Attributes returns the attributes of g.
+func (g *AttributedGene) Attributes() map[string]string { return g.Attrs }
+
+// AttributedTranscript is a non coding transcript with attributes. It
+// implements Attributer. Coding transcripts carry their attributes as
+// AnnotatedTranscripts.
+type AttributedTranscript struct {
+	*gene.NonCodingTranscript
+	Attrs map[string]string
+}
+
+// Attributes returns the attributes of t.
+func (t *AttributedTranscript) Attributes() map[string]string { return t.Attrs }
+
+// NonCoding returns the non coding transcript of t, which is t itself or the
+// underlying transcript of an AttributedTranscript. It returns false if t is
+// not a non coding transcript.
+func NonCoding(t gene.Transcript) (*gene.NonCodingTranscript, bool) {
+	switch t := t.(type) {
+	case *gene.NonCodingTranscript:
+		return t, true
+	case *AttributedTranscript:
+		return t.NonCodingTranscript, true
+	}
+	return nil, false
+}
+
+// WithAttributes returns g with the attributes attrs, or g itself if attrs
+// is empty.
+func WithAttributes(g *gene.Gene, attrs map[string]string) gene.Interface {
+	if len(attrs) == 0 {
+		return g
+	}
+	return &AttributedGene{Gene: g, Attrs: attrs}
+}
+
+// TranscriptWithAttributes returns t with the attributes attrs, or t itself
+// if attrs is empty. Coding transcripts are returned as
+// *AnnotatedTranscript and non coding transcripts as *AttributedTranscript.
+// The exons of t must already be set.
+func TranscriptWithAttributes(t gene.Transcript, attrs map[string]string) gene.Transcript {
+	if len(attrs) == 0 {
+		return t
+	}
+	switch t := t.(type) {
+	case *AnnotatedTranscript:
+		t.Attrs = attrs
+		return t
+	case *gene.CodingTranscript:
+		return &AnnotatedTranscript{CodingTranscript: t, Attrs: attrs}
+	case *AttributedTranscript:
+		t.Attrs = attrs
+		return t
+	case *gene.NonCodingTranscript:
+		return &AttributedTranscript{NonCodingTranscript: t, Attrs: attrs}
+	}
+	return t
+}
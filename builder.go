@@ -0,0 +1,300 @@
+package geneio
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/biogo/biogo/feat"
+	"github.com/biogo/biogo/feat/gene"
+)
+
+// A Builder builds genes from transcripts, exons and coding sequences given
+// in zero based, half open reference coordinates, independent of any file
+// format. Its methods return the Builder so that calls can be chained:
+//
+//	genes, err := geneio.NewBuilder().
+//		Gene("A", geneio.Chrom("X"), feat.Forward).
+//		Transcript("A1").Exon(10, 20).Exon(50, 90).CDS(14, 62).
+//		Transcript("A2").Exon(10, 100).
+//		Build()
+//
+// Genes are constructed and validated as by GeneReader, so a gene built from
+// the same exons and coding sequences as a gene read from an annotation has
+// the same transcripts. The first error from a chained call is kept and
+// returned by Build.
+type Builder struct {
+	genes []*builderGene
+	dict  *SeqDict
+	err   error
+}
+
+// builderGene is a gene under construction.
+type builderGene struct {
+	id    string
+	chrom feat.Feature
+	ori   feat.Orientation
+	ts    []*builderTranscript
+}
+
+// builderTranscript is a transcript under construction.
+type builderTranscript struct {
+	id    string
+	exons [][2]int
+	cds   *[2]int
+	tags  []string
+	sec   []int
+	phase int
+}
+
+// NewBuilder returns a new Builder with no genes.
+func NewBuilder() *Builder {
+	return &Builder{}
+}
+
+// SetSeqDict sets the sequence dictionary that built genes are validated
+// against, as for GeneReader. A nil dictionary disables validation.
+func (b *Builder) SetSeqDict(d *SeqDict) *Builder {
+	b.dict = d
+	return b
+}
+
+// Gene starts a new gene with the given ID located on chrom with
+// orientation o. Transcripts are added to the most recently started gene.
+func (b *Builder) Gene(id string, chrom feat.Feature, o feat.Orientation) *Builder {
+	if b.err != nil {
+		return b
+	}
+	if id == "" {
+		b.err = errors.New("geneio: empty gene id")
+		return b
+	}
+	if chrom == nil {
+		b.err = errors.New("geneio: no location for gene " + id)
+		return b
+	}
+	for _, g := range b.genes {
+		if g.id == id {
+			b.err = errors.New("geneio: duplicate gene " + id)
+			return b
+		}
+	}
+	b.genes = append(b.genes, &builderGene{id: id, chrom: chrom, ori: o})
+	return b
+}
+
+// gene returns the current gene, setting the error of b if there is none.
+func (b *Builder) gene() *builderGene {
+	if b.err != nil {
+		return nil
+	}
+	if len(b.genes) == 0 {
+		b.err = errors.New("geneio: transcript added before gene")
+		return nil
+	}
+	return b.genes[len(b.genes)-1]
+}
+
+// transcript returns the current transcript, setting the error of b if
+// there is none.
+func (b *Builder) transcript() *builderTranscript {
+	g := b.gene()
+	if g == nil {
+		return nil
+	}
+	if len(g.ts) == 0 {
+		b.err = errors.New("geneio: feature added before transcript for gene " + g.id)
+		return nil
+	}
+	return g.ts[len(g.ts)-1]
+}
+
+// Transcript starts a new transcript with the given ID in the current gene.
+// Exons, coding sequences and tags are added to the most recently started
+// transcript.
+func (b *Builder) Transcript(id string) *Builder {
+	g := b.gene()
+	if g == nil {
+		return b
+	}
+	if id == "" {
+		b.err = errors.New("geneio: empty transcript id for gene " + g.id)
+		return b
+	}
+	for _, t := range g.ts {
+		if t.id == id {
+			b.err = fmt.Errorf("geneio: duplicate transcript %s for gene %s", id, g.id)
+			return b
+		}
+	}
+	g.ts = append(g.ts, &builderTranscript{id: id})
+	return b
+}
+
+// Exon adds the exon from start to end to the current transcript. Touching
+// exons are merged as by GeneReader.
+func (b *Builder) Exon(start, end int) *Builder {
+	t := b.transcript()
+	if t == nil {
+		return b
+	}
+	if start < 0 || end <= start {
+		b.err = fmt.Errorf("geneio: bad exon %d-%d for transcript %s", start, end, t.id)
+		return b
+	}
+	t.exons = append(t.exons, [2]int{start, end})
+	return b
+}
+
+// CDS sets the coding sequence of the current transcript to the region from
+// start to end, which includes the start and stop codons, making it a coding
+// transcript. The gene of the transcript must be oriented.
+func (b *Builder) CDS(start, end int) *Builder {
+	t := b.transcript()
+	if t == nil {
+		return b
+	}
+	g := b.genes[len(b.genes)-1]
+	if g.ori != feat.Forward && g.ori != feat.Reverse {
+		b.err = fmt.Errorf("geneio: coding sequence for transcript %s of unoriented gene %s", t.id, g.id)
+		return b
+	}
+	if start < 0 || end-start < 3 {
+		b.err = fmt.Errorf("geneio: bad coding sequence %d-%d for transcript %s", start, end, t.id)
+		return b
+	}
+	t.cds = &[2]int{start, end}
+	return b
+}
+
+// Tag adds tags to the current transcript, which must be coding. A coding
+// transcript tagged with TagCDSStartNF or TagStartCodonNF, or with
+// TagCDSEndNF or TagStopCodonNF, has no start or stop codon at the
+// respective end of its coding sequence.
+func (b *Builder) Tag(tags ...string) *Builder {
+	t := b.transcript()
+	if t == nil {
+		return b
+	}
+	t.tags = append(t.tags, tags...)
+	return b
+}
+
+// Selenocysteine adds a translational exception for a selenocysteine codon
+// from start to start+3 to the current transcript, which must be coding.
+func (b *Builder) Selenocysteine(start int) *Builder {
+	t := b.transcript()
+	if t == nil {
+		return b
+	}
+	t.sec = append(t.sec, start)
+	return b
+}
+
+// Phase sets the coding phase of the current transcript, the number of bases
+// at the 5' end of its coding sequence before the first complete codon. The
+// transcript must be coding and tagged with TagCDSStartNF or TagStartCodonNF.
+func (b *Builder) Phase(p int) *Builder {
+	t := b.transcript()
+	if t == nil {
+		return b
+	}
+	if p < 0 || p > 2 {
+		b.err = fmt.Errorf("geneio: bad phase %d for transcript %s", p, t.id)
+		return b
+	}
+	t.phase = p
+	return b
+}
+
+// Build returns the genes of b in the order they were started. It returns
+// nil and the first error that occurred while building.
+func (b *Builder) Build() ([]*gene.Gene, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	genes := make([]*gene.Gene, len(b.genes))
+	for i, bg := range b.genes {
+		blk := &geneBlock{ID: bg.id, loc: bg.chrom, ori: bg.ori, dict: b.dict}
+		if len(bg.ts) == 0 {
+			return nil, errors.New("geneio: no transcripts for gene " + bg.id)
+		}
+		for _, t := range bg.ts {
+			if len(t.exons) == 0 {
+				return nil, fmt.Errorf("geneio: no exons for transcript %s of gene %s", t.id, bg.id)
+			}
+			if t.cds == nil && (len(t.tags) != 0 || len(t.sec) != 0 || t.phase != 0) {
+				return nil, fmt.Errorf("geneio: annotation for non coding transcript %s of gene %s", t.id, bg.id)
+			}
+			if t.phase != 0 && !hasTag(t.tags, TagCDSStartNF) && !hasTag(t.tags, TagStartCodonNF) {
+				return nil, fmt.Errorf("geneio: coding phase for transcript %s of gene %s with a start codon", t.id, bg.id)
+			}
+			blk.feats = append(blk.feats, t.features(bg)...)
+		}
+		g, err := blk.ToGene()
+		if err != nil {
+			return nil, err
+		}
+		genes[i] = g
+	}
+	return genes, nil
+}
+
+// features returns the Features of t, a transcript of g, in the form
+// GeneReader expects.
+func (t *builderTranscript) features(g *builderGene) []Feature {
+	f := func(typ string, start, end int) Feature {
+		return &builderFeature{
+			chrom: g.chrom, start: start, end: end, ori: g.ori,
+			gid: g.id, tid: t.id, typ: typ, tags: t.tags, frame: -1,
+		}
+	}
+	var fs []Feature
+	for _, e := range t.exons {
+		fs = append(fs, f("exon", e[0], e[1]))
+	}
+	if t.cds == nil {
+		return fs
+	}
+	start, end := *t.cds, *t.cds
+	start[1], end[0] = start[0]+3, end[1]-3
+	if g.ori == feat.Reverse {
+		start, end = end, start
+	}
+	cds := f("CDS", t.cds[0], t.cds[1]).(*builderFeature)
+	cds.frame = t.phase
+	fs = append(fs, cds)
+	if !hasTag(t.tags, TagCDSStartNF) && !hasTag(t.tags, TagStartCodonNF) {
+		fs = append(fs, f("start_codon", start[0], start[1]))
+	}
+	if !hasTag(t.tags, TagCDSEndNF) && !hasTag(t.tags, TagStopCodonNF) {
+		fs = append(fs, f("stop_codon", end[0], end[1]))
+	}
+	for _, s := range t.sec {
+		fs = append(fs, f("Selenocysteine", s, s+3))
+	}
+	return fs
+}
+
+// builderFeature is the Feature made by a Builder. It implements Tagger and
+// Framer.
+type builderFeature struct {
+	chrom         feat.Feature
+	start, end    int
+	ori           feat.Orientation
+	gid, tid, typ string
+	tags          []string
+	frame         int
+}
+
+func (f *builderFeature) Start() int                    { return f.start }
+func (f *builderFeature) End() int                      { return f.end }
+func (f *builderFeature) Len() int                      { return f.end - f.start }
+func (f *builderFeature) Name() string                  { return f.tid }
+func (f *builderFeature) Description() string           { return f.typ }
+func (f *builderFeature) Location() feat.Feature        { return f.chrom }
+func (f *builderFeature) Orientation() feat.Orientation { return f.ori }
+func (f *builderFeature) GID() string                   { return f.gid }
+func (f *builderFeature) TID() string                   { return f.tid }
+func (f *builderFeature) Type() string                  { return f.typ }
+func (f *builderFeature) Tags() []string                { return f.tags }
+func (f *builderFeature) Frame() int                    { return f.frame }
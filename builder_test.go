@@ -0,0 +1,215 @@
+package geneio
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/biogo/biogo/feat"
+	"github.com/biogo/biogo/feat/gene"
+	"github.com/biogo/biogo/io/featio/gff"
+)
+
+// Assert that interfaces are satisfied.
+var (
+	_ Tagger = (*builderFeature)(nil)
+	_ Framer = (*builderFeature)(nil)
+)
+
+// describe returns a description of the structure of g in reference
+// coordinates.
+func describe(g gene.Interface) string {
+	var b strings.Builder
+	start, ref := RefPosition(g, 0)
+	fmt.Fprintf(&b, "%s %s:%d-%d %v;", g.Name(), ref.Name(), start, start+g.Len(), RefOrientation(g))
+	for _, t := range Transcripts(g) {
+		s, _ := RefPosition(t, 0)
+		fmt.Fprintf(&b, " %s %T", t.Name(), t)
+		for _, e := range t.Exons() {
+			fmt.Fprintf(&b, " %d-%d", s+e.Start(), s+e.End())
+		}
+		if ct, ok := Coding(t); ok {
+			fmt.Fprintf(&b, " cds %d-%d", s+ct.CDSstart, s+ct.CDSend)
+		}
+		b.WriteString(";")
+	}
+	return b.String()
+}
+
+// Test that Builder builds the genes GeneReader reads
+func TestBuilder(t *testing.T) {
+	input := "" +
+		"X\t.\texon\t11\t20\t0\t+\t.\tgene_id A; transcript_id A1;\n" +
+		"X\t.\texon\t51\t90\t0\t+\t.\tgene_id A; transcript_id A1;\n" +
+		"X\t.\tstart_codon\t15\t17\t0\t+\t.\tgene_id A; transcript_id A1;\n" +
+		"X\t.\tstop_codon\t60\t62\t0\t+\t.\tgene_id A; transcript_id A1;\n" +
+		"X\t.\texon\t11\t100\t0\t+\t.\tgene_id A; transcript_id A2;\n" +
+		"X\t.\texon\t30\t50\t0\t-\t.\tgene_id D; transcript_id D1;\n" +
+		"X\t.\texon\t80\t99\t0\t-\t.\tgene_id D; transcript_id D1;\n" +
+		"X\t.\tstop_codon\t40\t42\t0\t-\t.\tgene_id D; transcript_id D1;\n" +
+		"X\t.\tstart_codon\t91\t93\t0\t-\t.\tgene_id D; transcript_id D1;\n"
+	r := NewGeneReader(&FeatureReaderImpl{r: gff.NewReader(strings.NewReader(input)), GID: "gene_id", TID: "transcript_id"})
+	read, err := r.ReadAll()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	built, err := NewBuilder().
+		Gene("A", Chrom("X"), feat.Forward).
+		Transcript("A1").Exon(10, 20).Exon(50, 90).CDS(14, 62).
+		Transcript("A2").Exon(10, 100).
+		Gene("D", Chrom("X"), feat.Reverse).
+		Transcript("D1").Exon(29, 50).Exon(79, 99).CDS(39, 93).
+		Build()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if len(built) != len(read) {
+		t.Fatalf("out gene count=%d want %d", len(built), len(read))
+	}
+	for i, g := range built {
+		if got, want := describe(g), describe(read[i]); got != want {
+			t.Errorf("out gene=%q want %q", got, want)
+		}
+	}
+}
+
+// Test building annotated transcripts
+func TestBuilderAnnotated(t *testing.T) {
+	genes, err := NewBuilder().
+		Gene("S", Chrom("X"), feat.Forward).
+		Transcript("S1").Exon(0, 9).Exon(12, 24).CDS(0, 24).Selenocysteine(3).Tag("basic").
+		Gene("N", Chrom("Y"), feat.Reverse).
+		Transcript("N1").Exon(0, 13).CDS(0, 12).Tag(TagCDSStartNF).
+		Build()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	want := []string{"MUKPGF*", "AAK*"}
+	for i, g := range genes {
+		tr := Transcripts(g)[0]
+		if _, ok := tr.(*AnnotatedTranscript); !ok {
+			t.Errorf("%s: out type=%T want *AnnotatedTranscript", tr.Name(), tr)
+			continue
+		}
+		p, err := StandardCode.TranslateTranscript(tr, annotatedSeqs)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tr.Name(), err)
+			continue
+		}
+		if string(p) != want[i] {
+			t.Errorf("%s: out translation=%s want %s", tr.Name(), p, want[i])
+		}
+	}
+}
+
+// Test that Builder builds the annotated transcripts GeneReader reads
+func TestBuilderPhase(t *testing.T) {
+	read := readAnnotated(t, annotatedInput)[1]
+	genes, err := NewBuilder().
+		Gene("N", Chrom("Y"), feat.Reverse).
+		Transcript("N1").Exon(0, 13).CDS(0, 13).Tag(TagCDSStartNF).Phase(1).
+		Build()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if got, want := describe(genes[0]), describe(read.Location().(gene.Interface)); got != want {
+		t.Errorf("out gene=%q want %q", got, want)
+	}
+	built := Transcripts(genes[0])[0]
+	if got, want := codingPhase(built), codingPhase(read); got != want {
+		t.Errorf("out phase=%d want %d", got, want)
+	}
+	for _, tr := range []gene.Transcript{built, read} {
+		p, err := StandardCode.TranslateTranscript(tr, annotatedSeqs)
+		if err != nil {
+			t.Errorf("unexpected error %v", err)
+			continue
+		}
+		if string(p) != "AAK*" {
+			t.Errorf("out translation=%s want AAK*", p)
+		}
+	}
+}
+
+// Test Builder errors
+func TestBuilderErrors(t *testing.T) {
+	dict := NewSeqDict()
+	dict.Add("X", 50)
+	for _, test := range []struct {
+		name string
+		b    *Builder
+		err  string
+	}{
+		{
+			name: "transcript before gene",
+			b:    NewBuilder().Transcript("A1"),
+			err:  "geneio: transcript added before gene",
+		},
+		{
+			name: "exon before transcript",
+			b:    NewBuilder().Gene("A", Chrom("X"), feat.Forward).Exon(0, 10),
+			err:  "geneio: feature added before transcript for gene A",
+		},
+		{
+			name: "bad exon",
+			b:    NewBuilder().Gene("A", Chrom("X"), feat.Forward).Transcript("A1").Exon(10, 10),
+			err:  "geneio: bad exon 10-10 for transcript A1",
+		},
+		{
+			name: "duplicate transcript",
+			b:    NewBuilder().Gene("A", Chrom("X"), feat.Forward).Transcript("A1").Exon(0, 10).Transcript("A1"),
+			err:  "geneio: duplicate transcript A1 for gene A",
+		},
+		{
+			name: "unoriented coding sequence",
+			b:    NewBuilder().Gene("A", Chrom("X"), feat.NotOriented).Transcript("A1").Exon(0, 10).CDS(0, 9),
+			err:  "geneio: coding sequence for transcript A1 of unoriented gene A",
+		},
+		{
+			name: "no exons",
+			b:    NewBuilder().Gene("A", Chrom("X"), feat.Forward).Transcript("A1"),
+			err:  "geneio: no exons for transcript A1 of gene A",
+		},
+		{
+			name: "no transcripts",
+			b:    NewBuilder().Gene("A", Chrom("X"), feat.Forward),
+			err:  "geneio: no transcripts for gene A",
+		},
+		{
+			name: "duplicate gene",
+			b:    NewBuilder().Gene("A", Chrom("X"), feat.Forward).Transcript("A1").Exon(0, 10).Gene("A", Chrom("Y"), feat.Forward),
+			err:  "geneio: duplicate gene A",
+		},
+		{
+			name: "tag without coding sequence",
+			b:    NewBuilder().Gene("A", Chrom("X"), feat.Forward).Transcript("A1").Exon(0, 10).Tag("basic"),
+			err:  "geneio: annotation for non coding transcript A1 of gene A",
+		},
+		{
+			name: "selenocysteine without coding sequence",
+			b:    NewBuilder().Gene("A", Chrom("X"), feat.Forward).Transcript("A1").Exon(0, 10).Selenocysteine(3),
+			err:  "geneio: annotation for non coding transcript A1 of gene A",
+		},
+		{
+			name: "phase with start codon",
+			b:    NewBuilder().Gene("A", Chrom("X"), feat.Forward).Transcript("A1").Exon(0, 10).CDS(0, 10).Phase(1),
+			err:  "geneio: coding phase for transcript A1 of gene A with a start codon",
+		},
+		{
+			name: "bad phase",
+			b:    NewBuilder().Gene("A", Chrom("X"), feat.Forward).Transcript("A1").Exon(0, 10).Phase(3),
+			err:  "geneio: bad phase 3 for transcript A1",
+		},
+		{
+			name: "exon past sequence end",
+			b:    NewBuilder().SetSeqDict(dict).Gene("A", Chrom("X"), feat.Forward).Transcript("A1").Exon(40, 60),
+			err:  "geneio: exons past the end of sequence X for gene A",
+		},
+	} {
+		_, err := test.b.Build()
+		if err == nil || err.Error() != test.err {
+			t.Errorf("%s: error %q, want error %q", test.name, err, test.err)
+		}
+	}
+}
@@ -0,0 +1,291 @@
+// Package catalog provides lookup of genes and transcripts by identifier.
+//
+// A Catalog indexes genes by gene ID, transcripts by transcript ID and genes
+// by names and synonyms. Lookups by ID fall back to comparing IDs without
+// their version suffix, so that ENSG00000139618 and ENSG00000139618.15 find
+// the same gene. Keys can also be searched by prefix.
+package catalog
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/biogo/biogo/feat/gene"
+	"github.com/go-bio/geneio"
+)
+
+// Kind is the kind of a catalog key.
+type Kind int
+
+// Kinds of keys.
+const (
+	GeneID Kind = iota
+	TranscriptID
+	Name
+)
+
+// String returns the name of k.
+func (k Kind) String() string {
+	switch k {
+	case GeneID:
+		return "gene"
+	case TranscriptID:
+		return "transcript"
+	case Name:
+		return "name"
+	}
+	return "unknown"
+}
+
+// Entry is a key of a Catalog with the gene it refers to. Transcript is set
+// for transcript IDs.
+type Entry struct {
+	Key        string
+	Kind       Kind
+	Gene       gene.Interface
+	Transcript gene.Transcript
+}
+
+// Catalog is an index of genes and transcripts by identifier. A Catalog is
+// not safe for concurrent use.
+type Catalog struct {
+	// NameTags are the attributes indexed as names of genes that implement
+	// geneio.Attributer. Values are split at commas to allow lists of
+	// synonyms.
+	NameTags []string
+
+	genes       map[string]gene.Interface
+	transcripts map[string]gene.Transcript
+	names       map[string][]gene.Interface
+
+	// unversioned maps IDs without version to the IDs that have it.
+	unversionedGenes       map[string][]string
+	unversionedTranscripts map[string][]string
+
+	sorted []Entry
+}
+
+// New returns a new empty Catalog indexing the "gene_name" and "Alias"
+// attributes as names.
+func New() *Catalog {
+	return &Catalog{
+		NameTags:               []string{"gene_name", "Alias"},
+		genes:                  make(map[string]gene.Interface),
+		transcripts:            make(map[string]gene.Transcript),
+		names:                  make(map[string][]gene.Interface),
+		unversionedGenes:       make(map[string][]string),
+		unversionedTranscripts: make(map[string][]string),
+	}
+}
+
+// Read reads all genes from r and returns a Catalog of them.
+func Read(r geneio.Reader) (*Catalog, error) {
+	c := New()
+	sc := geneio.NewScanner(r)
+	for sc.Next() {
+		if err := c.Add(sc.Gene()); err != nil {
+			return nil, err
+		}
+	}
+	if err := sc.Error(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Add adds g and its transcripts to c. It returns an error if the ID of g or
+// of one of its transcripts is already in c, in which case c is unchanged.
+func (c *Catalog) Add(g gene.Interface) error {
+	if _, ok := c.genes[g.Name()]; ok {
+		return fmt.Errorf("catalog: duplicate gene ID %s", g.Name())
+	}
+	ts := geneio.Transcripts(g)
+	seen := make(map[string]bool, len(ts))
+	for _, t := range ts {
+		if _, ok := c.transcripts[t.Name()]; ok || seen[t.Name()] {
+			return fmt.Errorf("catalog: duplicate transcript ID %s in gene %s", t.Name(), g.Name())
+		}
+		seen[t.Name()] = true
+	}
+
+	c.genes[g.Name()] = g
+	addUnversioned(c.unversionedGenes, g.Name())
+	for _, t := range ts {
+		c.transcripts[t.Name()] = t
+		addUnversioned(c.unversionedTranscripts, t.Name())
+	}
+	attrs := geneio.Attributes(g)
+	for _, tag := range c.NameTags {
+		for _, n := range strings.Split(attrs[tag], ",") {
+			if n = strings.TrimSpace(n); n != "" {
+				c.addName(n, g)
+			}
+		}
+	}
+	c.sorted = nil
+	return nil
+}
+
+// AddName adds name as a name or synonym of the gene with ID gid. It
+// returns an error if there is no such gene.
+func (c *Catalog) AddName(name, gid string) error {
+	g, ok := c.genes[gid]
+	if !ok {
+		return fmt.Errorf("catalog: unknown gene ID %s", gid)
+	}
+	c.addName(name, g)
+	c.sorted = nil
+	return nil
+}
+
+func (c *Catalog) addName(name string, g gene.Interface) {
+	for _, o := range c.names[name] {
+		if o == g {
+			return
+		}
+	}
+	c.names[name] = append(c.names[name], g)
+}
+
+func addUnversioned(m map[string][]string, id string) {
+	if u := StripVersion(id); u != id {
+		m[u] = append(m[u], id)
+	}
+}
+
+// StripVersion returns id without its version suffix, a dot followed by
+// digits at its end. Other IDs are returned unchanged.
+func StripVersion(id string) string {
+	i := strings.LastIndexByte(id, '.')
+	if i <= 0 || i == len(id)-1 {
+		return id
+	}
+	for _, r := range id[i+1:] {
+		if r < '0' || r > '9' {
+			return id
+		}
+	}
+	return id[:i]
+}
+
+// resolve returns the ID in c that id refers to. An ID that is not in c
+// refers to the ID with the highest version among those with the same
+// unversioned ID.
+func resolve(id string, has func(string) bool, unversioned map[string][]string) (string, bool) {
+	if has(id) {
+		return id, true
+	}
+	u := StripVersion(id)
+	if u != id && has(u) {
+		return u, true
+	}
+	var best string
+	bestVersion := -1
+	for _, v := range unversioned[u] {
+		n := version(v)
+		if n > bestVersion {
+			best, bestVersion = v, n
+		}
+	}
+	return best, bestVersion >= 0
+}
+
+// version returns the numeric version of id, or zero if it has none.
+func version(id string) int {
+	u := StripVersion(id)
+	if u == id {
+		return 0
+	}
+	n := 0
+	for _, r := range id[len(u)+1:] {
+		n = n*10 + int(r-'0')
+	}
+	return n
+}
+
+// Gene returns the gene with the given ID. If there is none, the gene with
+// the same ID without version is returned, choosing the highest version if
+// several match.
+func (c *Catalog) Gene(id string) (gene.Interface, bool) {
+	id, ok := resolve(id, func(s string) bool { _, ok := c.genes[s]; return ok }, c.unversionedGenes)
+	if !ok {
+		return nil, false
+	}
+	return c.genes[id], true
+}
+
+// Transcript returns the transcript with the given ID, with the same
+// version rules as Gene. The gene of the transcript is its location.
+func (c *Catalog) Transcript(id string) (gene.Transcript, bool) {
+	id, ok := resolve(id, func(s string) bool { _, ok := c.transcripts[s]; return ok }, c.unversionedTranscripts)
+	if !ok {
+		return nil, false
+	}
+	return c.transcripts[id], true
+}
+
+// ByName returns the genes with the given name or synonym in the order they
+// were added.
+func (c *Catalog) ByName(name string) []gene.Interface {
+	return append([]gene.Interface(nil), c.names[name]...)
+}
+
+// Lookup returns the genes that key refers to as a gene ID, the gene of the
+// transcript it refers to as a transcript ID, or the genes it names, trying
+// each in turn.
+func (c *Catalog) Lookup(key string) []gene.Interface {
+	if g, ok := c.Gene(key); ok {
+		return []gene.Interface{g}
+	}
+	if t, ok := c.Transcript(key); ok {
+		return []gene.Interface{c.genes[t.Location().Name()]}
+	}
+	return c.ByName(key)
+}
+
+// Prefix returns the entries whose key starts with prefix ordered by key
+// and kind.
+func (c *Catalog) Prefix(prefix string) []Entry {
+	if c.sorted == nil {
+		c.sort()
+	}
+	i := sort.Search(len(c.sorted), func(i int) bool { return c.sorted[i].Key >= prefix })
+	j := i
+	for j < len(c.sorted) && strings.HasPrefix(c.sorted[j].Key, prefix) {
+		j++
+	}
+	return append([]Entry(nil), c.sorted[i:j]...)
+}
+
+// sort builds the sorted entries of c.
+func (c *Catalog) sort() {
+	entries := make([]Entry, 0, len(c.genes)+len(c.transcripts)+len(c.names))
+	for id, g := range c.genes {
+		entries = append(entries, Entry{Key: id, Kind: GeneID, Gene: g})
+	}
+	for id, t := range c.transcripts {
+		entries = append(entries, Entry{Key: id, Kind: TranscriptID, Gene: c.genes[t.Location().Name()], Transcript: t})
+	}
+	for name, gs := range c.names {
+		for _, g := range gs {
+			entries = append(entries, Entry{Key: name, Kind: Name, Gene: g})
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if a.Key != b.Key {
+			return a.Key < b.Key
+		}
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		return a.Gene.Name() < b.Gene.Name()
+	})
+	c.sorted = entries
+}
+
+// Len returns the number of genes in c.
+func (c *Catalog) Len() int {
+	return len(c.genes)
+}
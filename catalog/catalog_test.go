@@ -0,0 +1,155 @@
+package catalog
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/biogo/biogo/feat/gene"
+	"github.com/biogo/biogo/io/featio/gff"
+	ggff "github.com/go-bio/geneio/gff"
+)
+
+var catalogInput = "" +
+	"X\t.\texon\t11\t20\t0\t+\t.\tgene_id ENSG01.5; transcript_id ENST01.2;\n" +
+	"X\t.\texon\t11\t30\t0\t+\t.\tgene_id ENSG01.5; transcript_id ENST02.1;\n" +
+	"X\t.\texon\t51\t60\t0\t+\t.\tgene_id ENSG02.1; transcript_id ENST03.1;\n" +
+	"X\t.\texon\t81\t90\t0\t+\t.\tgene_id ENSG02.3; transcript_id ENST03.3;\n" +
+	"Y\t.\texon\t11\t20\t0\t-\t.\tgene_id G3; transcript_id T4; gene_name BRCA2; Alias \"FANCD1,FAD\";\n" +
+	"Y\t.\texon\t31\t40\t0\t-\t.\tgene_id G3; transcript_id T4; gene_name BRCA2; Alias \"FANCD1,FAD\";\n"
+
+func read(t *testing.T) *Catalog {
+	r := ggff.NewReader(gff.NewReader(strings.NewReader(catalogInput)))
+	if err := r.SetAttributes(true); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	c, err := Read(r)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	return c
+}
+
+func TestStripVersion(t *testing.T) {
+	for _, tt := range []struct{ In, Out string }{
+		{"ENSG00000139618.15", "ENSG00000139618"},
+		{"ENSG00000139618", "ENSG00000139618"},
+		{"NM_000059.4", "NM_000059"},
+		{"gene.a", "gene.a"},
+		{"gene.", "gene."},
+		{".5", ".5"},
+	} {
+		if got := StripVersion(tt.In); got != tt.Out {
+			t.Errorf("%s: out id=%s want %s", tt.In, got, tt.Out)
+		}
+	}
+}
+
+// Test Gene, Transcript and Lookup
+func TestLookup(t *testing.T) {
+	c := read(t)
+	if c.Len() != 4 {
+		t.Errorf("out len=%d want 4", c.Len())
+	}
+	for _, tt := range []struct {
+		Name   string
+		Lookup func(string) string
+		Key    string
+		Want   string
+	}{
+		{"Gene", geneName(c.Gene), "ENSG01.5", "ENSG01.5"},
+		{"Gene", geneName(c.Gene), "ENSG01", "ENSG01.5"},
+		{"Gene", geneName(c.Gene), "ENSG01.4", "ENSG01.5"},
+		{"Gene", geneName(c.Gene), "ENSG02", "ENSG02.3"},
+		{"Gene", geneName(c.Gene), "ENSG02.1", "ENSG02.1"},
+		{"Gene", geneName(c.Gene), "G3.2", "G3"},
+		{"Gene", geneName(c.Gene), "ENSG03", ""},
+		{"Transcript", txName(c.Transcript), "ENST02", "ENST02.1"},
+		{"Transcript", txName(c.Transcript), "ENST03", "ENST03.3"},
+		{"Transcript", txName(c.Transcript), "ENSG01", ""},
+		{"Lookup", lookupNames(c), "ENST01", "ENSG01.5"},
+		{"Lookup", lookupNames(c), "FAD", "G3"},
+		{"Lookup", lookupNames(c), "BRCA2", "G3"},
+		{"Lookup", lookupNames(c), "BRCA1", ""},
+	} {
+		if got := tt.Lookup(tt.Key); got != tt.Want {
+			t.Errorf("%s %s: out=%q want %q", tt.Name, tt.Key, got, tt.Want)
+		}
+	}
+}
+
+func geneName(f func(string) (gene.Interface, bool)) func(string) string {
+	return func(id string) string {
+		g, ok := f(id)
+		if !ok {
+			return ""
+		}
+		return g.Name()
+	}
+}
+
+func txName(f func(string) (gene.Transcript, bool)) func(string) string {
+	return func(id string) string {
+		t, ok := f(id)
+		if !ok {
+			return ""
+		}
+		return t.Name()
+	}
+}
+
+func lookupNames(c *Catalog) func(string) string {
+	return func(key string) string {
+		var names []string
+		for _, g := range c.Lookup(key) {
+			names = append(names, g.Name())
+		}
+		return strings.Join(names, ",")
+	}
+}
+
+func TestAdd(t *testing.T) {
+	c := read(t)
+	g, _ := c.Gene("G3")
+	if err := c.Add(g); err == nil || err.Error() != "catalog: duplicate gene ID G3" {
+		t.Errorf("error %q, want error %q", err, "catalog: duplicate gene ID G3")
+	}
+	if err := c.AddName("BRCA2", "G9"); err == nil || err.Error() != "catalog: unknown gene ID G9" {
+		t.Errorf("error %q, want error %q", err, "catalog: unknown gene ID G9")
+	}
+	if err := c.AddName("BRCA2", "ENSG01.5"); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	if got, want := len(c.ByName("BRCA2")), 2; got != want {
+		t.Errorf("out name count=%d want %d", got, want)
+	}
+}
+
+func TestPrefix(t *testing.T) {
+	c := read(t)
+	for _, tt := range []struct {
+		Prefix string
+		Keys   []string
+	}{
+		{"ENSG0", []string{"ENSG01.5", "ENSG02.1", "ENSG02.3"}},
+		{"ENST03", []string{"ENST03.1", "ENST03.3"}},
+		{"FA", []string{"FAD", "FANCD1"}},
+		{"Z", nil},
+	} {
+		var keys []string
+		for _, e := range c.Prefix(tt.Prefix) {
+			keys = append(keys, e.Key)
+		}
+		if !reflect.DeepEqual(keys, tt.Keys) {
+			t.Errorf("%s: out keys=%q want %q", tt.Prefix, keys, tt.Keys)
+		}
+	}
+	e := c.Prefix("ENST04")
+	if len(e) != 0 {
+		t.Errorf("out entries=%v want none", e)
+	}
+	e = c.Prefix("T4")
+	if len(e) != 1 || e[0].Kind != TranscriptID || e[0].Gene.Name() != "G3" || e[0].Transcript.Name() != "T4" {
+		t.Errorf("out entries=%v want transcript T4 of G3", e)
+	}
+}
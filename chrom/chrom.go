@@ -0,0 +1,187 @@
+// Package chrom normalises the reference sequence names of genes.
+//
+// Annotations from different providers name the same sequence differently,
+// e.g. "chr1" and "1" or "chrM" and "MT". A Mapper rewrites names through an
+// explicit table, which can be loaded from a file, and a list of rules, and a
+// Reader applies a Mapper to the genes of an underlying geneio.Reader.
+package chrom
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/biogo/biogo/feat/gene"
+	"github.com/go-bio/geneio"
+)
+
+// Policy determines how a Mapper handles names that neither its table nor
+// its rules map.
+type Policy int
+
+// Policies for unknown names.
+const (
+	// Pass keeps unknown names unchanged.
+	Pass Policy = iota
+	// Reject makes reading a gene with an unknown name fail.
+	Reject
+	// Skip drops genes with unknown names.
+	Skip
+)
+
+// Rule maps a name. It returns false if it does not apply to name.
+type Rule func(name string) (string, bool)
+
+// UCSC is a Rule that converts the Ensembl style names of the primary
+// assembly sequences, "1" to "22", "X", "Y" and "MT" or "M", to UCSC style by
+// adding the "chr" prefix and renaming "MT" to "chrM". UCSC style names of
+// these sequences are kept. It does not apply to other names, such as those
+// of unplaced contigs.
+func UCSC(name string) (string, bool) {
+	if primary(strings.TrimPrefix(name, "chr")) {
+		if name == "MT" || name == "chrMT" {
+			return "chrM", true
+		}
+		return "chr" + strings.TrimPrefix(name, "chr"), true
+	}
+	return "", false
+}
+
+// Ensembl is a Rule that converts the UCSC style names of the primary
+// assembly sequences, "chr1" to "chr22", "chrX", "chrY" and "chrM", to
+// Ensembl style by removing the "chr" prefix and renaming "chrM" to "MT".
+// Ensembl style names of these sequences are kept. It does not apply to other
+// names, such as those of unplaced contigs.
+func Ensembl(name string) (string, bool) {
+	if primary(strings.TrimPrefix(name, "chr")) {
+		if name = strings.TrimPrefix(name, "chr"); name == "M" {
+			return "MT", true
+		}
+		return name, true
+	}
+	return "", false
+}
+
+// primary returns whether name is the name of a primary assembly sequence
+// without its "chr" prefix: "1" to "22", "X", "Y", "M" or "MT".
+func primary(name string) bool {
+	switch name {
+	case "X", "Y", "M", "MT":
+		return true
+	}
+	n, err := strconv.Atoi(name)
+	return err == nil && n >= 1 && n <= 22 && strconv.Itoa(n) == name
+}
+
+// Mapper maps reference sequence names. Names in its table take precedence
+// over its rules, which are tried in order.
+type Mapper struct {
+	Policy Policy
+	table  map[string]string
+	rules  []Rule
+}
+
+// NewMapper returns a new Mapper with the given policy and rules and an empty
+// table.
+func NewMapper(policy Policy, rules ...Rule) *Mapper {
+	return &Mapper{Policy: policy, table: make(map[string]string), rules: rules}
+}
+
+// Add adds a mapping from name from to name to to the table of m.
+func (m *Mapper) Add(from, to string) {
+	m.table[from] = to
+}
+
+// ReadTable reads mappings into the table of m from r. Each line holds a
+// source and a destination name separated by white space; further columns are
+// ignored. Empty lines and lines starting with "#" are skipped.
+func (m *Mapper) ReadTable(r io.Reader) error {
+	sc := bufio.NewScanner(r)
+	line := 0
+	for sc.Scan() {
+		line++
+		fields := strings.Fields(sc.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if len(fields) < 2 {
+			return fmt.Errorf("chrom: line %d: missing destination name", line)
+		}
+		m.Add(fields[0], fields[1])
+	}
+	return sc.Err()
+}
+
+// Map returns the mapped name for name and whether the table or a rule of m
+// applied to it. Map does not apply the policy of m.
+func (m *Mapper) Map(name string) (string, bool) {
+	if to, ok := m.table[name]; ok {
+		return to, true
+	}
+	for _, r := range m.rules {
+		if to, ok := r(name); ok {
+			return to, true
+		}
+	}
+	return name, false
+}
+
+// UnknownError is returned by a Reader with the Reject policy for a gene on
+// a sequence its Mapper cannot map.
+type UnknownError struct {
+	Name string
+	Gene gene.Interface
+}
+
+// Error returns the error message.
+func (e *UnknownError) Error() string {
+	return fmt.Sprintf("chrom: unknown sequence %q for gene %s", e.Name, e.Gene.Name())
+}
+
+// Reader is a geneio.Reader that rewrites the reference sequence of the
+// genes read from an underlying Reader. Renamed genes are located on a
+// geneio.Chrom. The underlying Reader must return *gene.Gene or
+// *geneio.AttributedGene values.
+type Reader struct {
+	r geneio.Reader
+	m *Mapper
+}
+
+// NewReader returns a new Reader that maps the genes read from r with m.
+func NewReader(r geneio.Reader, m *Mapper) *Reader {
+	return &Reader{r: r, m: m}
+}
+
+// Read reads and maps one gene. At EOF, it returns nil and io.EOF.
+func (r *Reader) Read() (gene.Interface, error) {
+	for {
+		g, err := r.r.Read()
+		if err != nil {
+			return nil, err
+		}
+		name := g.Location().Name()
+		to, ok := r.m.Map(name)
+		if !ok {
+			switch r.m.Policy {
+			case Reject:
+				return nil, &UnknownError{Name: name, Gene: g}
+			case Skip:
+				continue
+			}
+		}
+		if to == name {
+			return g, nil
+		}
+		switch gg := g.(type) {
+		case *gene.Gene:
+			gg.Chrom = geneio.Chrom(to)
+		case *geneio.AttributedGene:
+			gg.Chrom = geneio.Chrom(to)
+		default:
+			return nil, fmt.Errorf("chrom: cannot relocate gene %s of type %T", g.Name(), g)
+		}
+		return g, nil
+	}
+}
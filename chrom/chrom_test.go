@@ -0,0 +1,148 @@
+package chrom
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/biogo/biogo/io/featio/gff"
+	"github.com/go-bio/geneio"
+	ggff "github.com/go-bio/geneio/gff"
+)
+
+// Assert that interfaces are satisfied.
+var (
+	_ geneio.Reader = (*Reader)(nil)
+	_ Rule          = UCSC
+	_ Rule          = Ensembl
+)
+
+var chromInput = "" +
+	"1\t.\texon\t11\t20\t0\t+\t.\tgene_id A; transcript_id A1;\n" +
+	"MT\t.\texon\t11\t20\t0\t+\t.\tgene_id B; transcript_id B1;\n" +
+	"chr2\t.\texon\t11\t20\t0\t+\t.\tgene_id C; transcript_id C1;\n" +
+	"GL000192.1\t.\texon\t11\t20\t0\t+\t.\tgene_id D; transcript_id D1;\n"
+
+// Test Reader
+var readerTests = []struct {
+	Name   string
+	Rules  []Rule
+	Table  string
+	Policy Policy
+	Chroms []string
+	Error  string
+}{
+	{
+		Name:   "UCSC rules",
+		Rules:  []Rule{UCSC},
+		Chroms: []string{"chr1", "chrM", "chr2", "GL000192.1"},
+	},
+	{
+		Name:   "Ensembl rules",
+		Rules:  []Rule{Ensembl},
+		Chroms: []string{"1", "MT", "2", "GL000192.1"},
+	},
+	{
+		Name:   "UCSC rules with skip policy",
+		Rules:  []Rule{UCSC},
+		Policy: Skip,
+		Chroms: []string{"chr1", "chrM", "chr2"},
+	},
+	{
+		Name:   "Ensembl rules with reject policy",
+		Rules:  []Rule{Ensembl},
+		Policy: Reject,
+		Error:  `chrom: unknown sequence "GL000192.1" for gene D`,
+	},
+	{
+		Name:   "Table only with pass policy",
+		Table:  "# alias\n1 chr1\n\nMT chrM extra\n",
+		Policy: Pass,
+		Chroms: []string{"chr1", "chrM", "chr2", "GL000192.1"},
+	},
+	{
+		Name:   "Table only with skip policy",
+		Table:  "1 chr1\nMT chrM\n",
+		Policy: Skip,
+		Chroms: []string{"chr1", "chrM"},
+	},
+	{
+		Name:   "Table only with reject policy",
+		Table:  "1 chr1\nMT chrM\n",
+		Policy: Reject,
+		Error:  `chrom: unknown sequence "chr2" for gene C`,
+	},
+	{
+		Name:   "Table overrides rules",
+		Rules:  []Rule{Ensembl},
+		Table:  "chr2 2_alt\n",
+		Chroms: []string{"1", "MT", "2_alt", "GL000192.1"},
+	},
+	{
+		Name:  "Bad table",
+		Table: "1\n",
+		Error: "chrom: line 1: missing destination name",
+	},
+}
+
+// Test that the rules only apply to primary assembly sequences
+func TestRules(t *testing.T) {
+	for _, tt := range []struct {
+		Name          string
+		UCSC          string
+		Ensembl       string
+		UCSCOK, EnsOK bool
+	}{
+		{Name: "1", UCSC: "chr1", UCSCOK: true, Ensembl: "1", EnsOK: true},
+		{Name: "chr22", UCSC: "chr22", UCSCOK: true, Ensembl: "22", EnsOK: true},
+		{Name: "X", UCSC: "chrX", UCSCOK: true, Ensembl: "X", EnsOK: true},
+		{Name: "chrM", UCSC: "chrM", UCSCOK: true, Ensembl: "MT", EnsOK: true},
+		{Name: "MT", UCSC: "chrM", UCSCOK: true, Ensembl: "MT", EnsOK: true},
+		{Name: "23"},
+		{Name: "01"},
+		{Name: "chr0"},
+		{Name: "GL000192.1"},
+		{Name: "chrUn_gl000220"},
+		{Name: "chr1_gl000191_random"},
+	} {
+		got, ok := UCSC(tt.Name)
+		if got != tt.UCSC || ok != tt.UCSCOK {
+			t.Errorf("%s: out UCSC=%q,%t want %q,%t", tt.Name, got, ok, tt.UCSC, tt.UCSCOK)
+		}
+		got, ok = Ensembl(tt.Name)
+		if got != tt.Ensembl || ok != tt.EnsOK {
+			t.Errorf("%s: out Ensembl=%q,%t want %q,%t", tt.Name, got, ok, tt.Ensembl, tt.EnsOK)
+		}
+	}
+}
+
+func TestReader(t *testing.T) {
+	for _, tt := range readerTests {
+		m := NewMapper(tt.Policy, tt.Rules...)
+		if err := m.ReadTable(strings.NewReader(tt.Table)); err != nil {
+			if tt.Error == "" || !strings.Contains(err.Error(), tt.Error) {
+				t.Errorf("%s: error %q, want error %q", tt.Name, err, tt.Error)
+			}
+			continue
+		}
+		r := NewReader(ggff.NewReader(gff.NewReader(strings.NewReader(chromInput))), m)
+		var chroms []string
+		sc := geneio.NewScanner(r)
+		for sc.Next() {
+			chroms = append(chroms, sc.Gene().Location().Name())
+		}
+		err := sc.Error()
+		if tt.Error != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.Error) {
+				t.Errorf("%s: error %q, want error %q", tt.Name, err, tt.Error)
+			}
+			continue
+		} else if err != nil {
+			t.Errorf("%s: unexpected error %v", tt.Name, err)
+			continue
+		}
+		if !reflect.DeepEqual(chroms, tt.Chroms) {
+			t.Errorf("%s: out chroms=%q want %q", tt.Name, chroms, tt.Chroms)
+		}
+	}
+}
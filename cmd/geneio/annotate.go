@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/go-bio/geneio"
+	"github.com/go-bio/geneio/annotate"
+	"github.com/go-bio/geneio/index"
+)
+
+// runAnnotate implements the annotate command.
+func runAnnotate(args []string) error {
+	fs := flag.NewFlagSet("annotate", flag.ExitOnError)
+	info := fs.String("info", "GENEIO", "name of the INFO field holding the annotations")
+	fasta := fs.String("fasta", "", "reference FASTA file for amino acid changes")
+	distance := fs.Int("distance", 5000, "largest distance of upstream and downstream variants")
+	var rf readerFlags
+	rf.register(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: geneio annotate [flags] annotation variants.vcf[.gz]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return exitError(2)
+	}
+	if *info == "" || strings.ContainsAny(*info, "=;, \t") {
+		return fmt.Errorf("bad INFO field name %q", *info)
+	}
+
+	r, c, err := rf.open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	idx, err := index.Read(r)
+	c.Close()
+	if err != nil {
+		return err
+	}
+	var seq geneio.Sequencer
+	if *fasta != "" {
+		if seq, err = readFasta(*fasta); err != nil {
+			return err
+		}
+	}
+	a := annotate.NewAnnotator(idx, seq)
+	a.Distance = *distance
+
+	vcf, err := openVCF(fs.Arg(1))
+	if err != nil {
+		return err
+	}
+	defer vcf.Close()
+	bw := bufio.NewWriter(os.Stdout)
+	if err := annotateVCF(bw, vcf, a, *info); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// openVCF opens the VCF file at path, decompressing gzip and BGZF files.
+func openVCF(path string) (io.ReadCloser, error) {
+	var f io.ReadCloser = os.Stdin
+	if path != "-" {
+		fh, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		f = fh
+	}
+	br := bufio.NewReader(f)
+	magic, _ := br.Peek(2)
+	if !bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		return struct {
+			io.Reader
+			io.Closer
+		}{br, f}, nil
+	}
+	gz, err := gzip.NewReader(br)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{gz, f}, nil
+}
+
+// annotateVCF copies the VCF in r to w, adding the effects found by a to
+// the INFO field named info of each record. Each effect is written as
+// Allele|Gene|Transcript|Consequences with consequences joined by '&'.
+func annotateVCF(w io.Writer, r io.Reader, a *annotate.Annotator, info string) error {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, 1<<26)
+	line := 0
+	for sc.Scan() {
+		line++
+		text := sc.Text()
+		switch {
+		case strings.HasPrefix(text, "##INFO=<ID="+info+","):
+			continue
+		case strings.HasPrefix(text, "#CHROM"):
+			fmt.Fprintf(w, "##INFO=<ID=%s,Number=.,Type=String,Description=\"Consequences predicted by geneio. Format: Allele|Gene|Transcript|Consequences\">\n", info)
+		case strings.HasPrefix(text, "#"), text == "":
+		default:
+			var err error
+			if text, err = annotateRecord(text, a, info); err != nil {
+				return fmt.Errorf("line %d: %v", line, err)
+			}
+		}
+		if _, err := fmt.Fprintln(w, text); err != nil {
+			return err
+		}
+	}
+	return sc.Err()
+}
+
+// annotateRecord returns the VCF record text with the effects found by a
+// set in the INFO field named info.
+func annotateRecord(text string, a *annotate.Annotator, info string) (string, error) {
+	variants, err := annotate.ParseVCF(text)
+	if err != nil {
+		return "", err
+	}
+	var effects []string
+	for _, v := range variants {
+		es, err := a.Annotate(v)
+		if err != nil {
+			return "", err
+		}
+		for _, e := range es {
+			cons := make([]string, len(e.Consequences))
+			for i, c := range e.Consequences {
+				cons[i] = c.String()
+			}
+			effects = append(effects, strings.Join([]string{v.Alt, e.Gene, e.Transcript, strings.Join(cons, "&")}, "|"))
+		}
+	}
+
+	fields := strings.Split(text, "\t")
+	for len(fields) < 8 {
+		fields = append(fields, ".")
+	}
+	var kept []string
+	if fields[7] != "." && fields[7] != "" {
+		for _, kv := range strings.Split(fields[7], ";") {
+			if kv != info && !strings.HasPrefix(kv, info+"=") {
+				kept = append(kept, kv)
+			}
+		}
+	}
+	if len(effects) != 0 {
+		kept = append(kept, info+"="+strings.Join(effects, ","))
+	}
+	fields[7] = "."
+	if len(kept) != 0 {
+		fields[7] = strings.Join(kept, ";")
+	}
+	return strings.Join(fields, "\t"), nil
+}
+
+// readFasta reads the sequences of the FASTA file at path into memory. The
+// name of a sequence is the first word of its header line.
+func readFasta(path string) (geneio.SeqMap, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	m := make(geneio.SeqMap)
+	var name string
+	sc := bufio.NewScanner(f)
+	sc.Buffer(nil, 1<<26)
+	for sc.Scan() {
+		b := bytes.TrimSpace(sc.Bytes())
+		if len(b) == 0 {
+			continue
+		}
+		if b[0] == '>' {
+			fields := strings.Fields(string(b[1:]))
+			if len(fields) == 0 {
+				return nil, fmt.Errorf("%s: empty FASTA header", path)
+			}
+			name = fields[0]
+			m[name] = nil
+			continue
+		}
+		if name == "" {
+			return nil, fmt.Errorf("%s: sequence before FASTA header", path)
+		}
+		m[name] = append(m[name], bytes.ToUpper(b)...)
+	}
+	return m, sc.Err()
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/biogo/biogo/feat/gene"
+	"github.com/go-bio/geneio"
+	"github.com/go-bio/geneio/align"
+	"github.com/go-bio/geneio/counts"
+)
+
+// runCount implements the count command.
+func runCount(args []string) error {
+	fs := flag.NewFlagSet("count", flag.ExitOnError)
+	strand := fs.Int("s", 0, "strandedness: 0 unstranded, 1 stranded, 2 reverse stranded")
+	overlap := fs.Bool("O", false, "count reads overlapping several genes for each of them")
+	multi := fs.Bool("M", false, "count multi-mapping reads")
+	secondary := fs.Bool("secondary", false, "count secondary and supplementary alignments")
+	mapq := fs.Int("Q", 0, "smallest mapping quality of counted alignments")
+	summary := fs.String("summary", "", "write the assignment summary to `file`")
+	var rf readerFlags
+	rf.register(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: geneio count [flags] annotation alignments.bam|sam...")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() < 2 {
+		fs.Usage()
+		return exitError(2)
+	}
+	if *strand < 0 || *strand > 2 {
+		return fmt.Errorf("bad strandedness %d", *strand)
+	}
+
+	r, c, err := rf.open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	var genes []gene.Interface
+	sc := geneio.NewScanner(r)
+	for sc.Next() {
+		genes = append(genes, sc.Gene())
+	}
+	c.Close()
+	if err := sc.Error(); err != nil {
+		return err
+	}
+
+	var (
+		names    []string
+		counters []*counts.Counter
+	)
+	for _, path := range fs.Args()[1:] {
+		ct := counts.NewCounter(genes)
+		ct.Strand = counts.Strandedness(*strand)
+		ct.MultiOverlap = *overlap
+		ct.MultiMapping = *multi
+		ct.Secondary = *secondary
+		ct.MinMapQ = *mapq
+		if err := countFile(ct, path); err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+		names = append(names, strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)))
+		counters = append(counters, ct)
+	}
+
+	bw := bufio.NewWriter(os.Stdout)
+	if err := counts.WriteMatrix(bw, names, counters); err != nil {
+		return err
+	}
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	if *summary == "" {
+		return nil
+	}
+	f, err := os.Create(*summary)
+	if err != nil {
+		return err
+	}
+	bw = bufio.NewWriter(f)
+	if err := counts.WriteSummary(bw, names, counters); err != nil {
+		f.Close()
+		return err
+	}
+	if err := bw.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// countFile adds the alignments of the SAM or BAM file at path to c.
+func countFile(c *counts.Counter, path string) error {
+	f := os.Stdin
+	if path != "-" {
+		var err error
+		if f, err = os.Open(path); err != nil {
+			return err
+		}
+		defer f.Close()
+	}
+	r, err := align.NewReader(f)
+	if err != nil {
+		return err
+	}
+	return c.Read(r)
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/biogo/biogo/feat/gene"
+	"github.com/go-bio/geneio"
+	"github.com/go-bio/geneio/align"
+	"github.com/go-bio/geneio/coverage"
+)
+
+// runCoverage implements the coverage command.
+func runCoverage(args []string) error {
+	fs := flag.NewFlagSet("coverage", flag.ExitOnError)
+	level := fs.String("level", "gene", "report coverage of each `gene`, transcript or exon")
+	bins := fs.Int("bins", 0, "number of 5' to 3' gene body profile bins to report for genes and transcripts")
+	mapq := fs.Int("Q", 0, "smallest mapping quality of counted alignments")
+	var rf readerFlags
+	rf.register(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: geneio coverage [flags] annotation alignments.bam|sam|bedGraph")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return exitError(2)
+	}
+	switch *level {
+	case "gene", "transcript":
+	case "exon":
+		if *bins != 0 {
+			return fmt.Errorf("profiles are not reported for exons")
+		}
+	default:
+		return fmt.Errorf("bad level %q", *level)
+	}
+
+	r, c, err := rf.open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	var genes []gene.Interface
+	sc := geneio.NewScanner(r)
+	for sc.Next() {
+		genes = append(genes, sc.Gene())
+	}
+	c.Close()
+	if err := sc.Error(); err != nil {
+		return err
+	}
+
+	tr := coverage.NewTrack(genes)
+	tr.MinMapQ = *mapq
+	if err := readCoverage(tr, fs.Arg(1)); err != nil {
+		return fmt.Errorf("%s: %v", fs.Arg(1), err)
+	}
+
+	bw := bufio.NewWriter(os.Stdout)
+	header := []string{"id", "length", "covered", "mean", "max"}
+	for i := 0; i < *bins; i++ {
+		header = append(header, "bin"+strconv.Itoa(i+1))
+	}
+	fmt.Fprintln(bw, strings.Join(header, "\t"))
+	for _, g := range genes {
+		switch *level {
+		case "gene":
+			s, err := tr.Gene(g)
+			if err != nil {
+				return err
+			}
+			var p []float64
+			if *bins != 0 {
+				p, _ = tr.GeneProfile(g, *bins)
+			}
+			writeCoverage(bw, g.Name(), s, p, *bins)
+		case "transcript":
+			for _, t := range geneio.Transcripts(g) {
+				var p []float64
+				if *bins != 0 {
+					p, _ = tr.Profile(t, *bins)
+				}
+				writeCoverage(bw, t.Name(), tr.Transcript(t), p, *bins)
+			}
+		case "exon":
+			for _, t := range geneio.Transcripts(g) {
+				for i, s := range tr.Exons(t) {
+					writeCoverage(bw, t.Name()+":"+strconv.Itoa(i+1), s, nil, 0)
+				}
+			}
+		}
+	}
+	return bw.Flush()
+}
+
+// writeCoverage writes a coverage table row for id to w. Profiles of
+// features shorter than the number of bins are written as NA.
+func writeCoverage(w io.Writer, id string, s coverage.Stats, p []float64, bins int) {
+	fmt.Fprintf(w, "%s\t%d\t%d\t%.4g\t%g", id, s.Len, s.Covered, s.Mean, s.Max)
+	for i := 0; i < bins; i++ {
+		if p == nil {
+			fmt.Fprint(w, "\tNA")
+		} else {
+			fmt.Fprintf(w, "\t%.4g", p[i])
+		}
+	}
+	fmt.Fprintln(w)
+}
+
+// readCoverage adds the depth in the file at path to t. Files with a
+// .bedGraph or .bg extension are read as bedGraph, others as SAM or BAM.
+func readCoverage(t *coverage.Track, path string) error {
+	f := os.Stdin
+	if path != "-" {
+		var err error
+		if f, err = os.Open(path); err != nil {
+			return err
+		}
+		defer f.Close()
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".bedgraph", ".bg":
+		return t.ReadBedGraph(f)
+	}
+	r, err := align.NewReader(f)
+	if err != nil {
+		return err
+	}
+	return t.ReadAlignments(r)
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/go-bio/geneio/genedb"
+)
+
+// runDB implements the db command.
+func runDB(args []string) error {
+	fs := flag.NewFlagSet("db", flag.ExitOnError)
+	var rf readerFlags
+	rf.register(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: geneio db [flags] annotation database.gdb")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return exitError(2)
+	}
+
+	r, c, err := rf.open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	f, err := os.Create(fs.Arg(1))
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(f)
+	if err := genedb.Write(bw, r); err != nil {
+		f.Close()
+		os.Remove(fs.Arg(1))
+		return err
+	}
+	if err := bw.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/go-bio/geneio/diff"
+)
+
+// runDiff implements the diff command. It exits with status 1 if the
+// annotations differ.
+func runDiff(args []string) error {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "write changes as newline delimited JSON")
+	var rf readerFlags
+	rf.register(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: geneio diff [flags] old new")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return exitError(2)
+	}
+
+	from, fc, err := rf.open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer fc.Close()
+	to, tc, err := rf.open(fs.Arg(1))
+	if err != nil {
+		return err
+	}
+	defer tc.Close()
+
+	changes, err := diff.Compare(from, to)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		err = diff.WriteJSON(os.Stdout, changes)
+	} else {
+		err = diff.WriteText(os.Stdout, changes)
+	}
+	if err != nil {
+		return err
+	}
+	if len(changes) > 0 {
+		return exitError(1)
+	}
+	return nil
+}
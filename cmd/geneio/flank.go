@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/biogo/biogo/feat"
+	"github.com/go-bio/geneio"
+	"github.com/go-bio/geneio/flank"
+)
+
+// runFlank implements the flank command.
+func runFlank(args []string) error {
+	fs := flag.NewFlagSet("flank", flag.ExitOnError)
+	mode := fs.String("mode", "promoter", "region to write: `promoter`, upstream, downstream or tss")
+	up := fs.Int("up", 1000, "bases upstream of the 5' end in promoter mode")
+	down := fs.Int("down", 100, "bases downstream of the 5' end in promoter mode")
+	n := fs.Int("n", 1000, "flank length in upstream and downstream mode, or half width in tss mode")
+	transcripts := fs.Bool("transcripts", false, "derive regions from transcripts instead of genes")
+	dictPath := fs.String("dict", "", "clip regions to the sequences of this .fai, .dict or FASTA file")
+	var rf readerFlags
+	rf.register(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: geneio flank [flags] annotation")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return exitError(2)
+	}
+
+	var d *geneio.SeqDict
+	if *dictPath != "" {
+		var err error
+		if d, err = readSeqDict(*dictPath); err != nil {
+			return err
+		}
+	}
+	fl := flank.New(d)
+	var region func(f feat.Feature) (*flank.Region, error)
+	switch *mode {
+	case "promoter":
+		region = func(f feat.Feature) (*flank.Region, error) { return fl.Promoter(f, *up, *down) }
+	case "upstream":
+		region = func(f feat.Feature) (*flank.Region, error) { return fl.Upstream(f, *n) }
+	case "downstream":
+		region = func(f feat.Feature) (*flank.Region, error) { return fl.Downstream(f, *n) }
+	case "tss":
+		region = func(f feat.Feature) (*flank.Region, error) { return fl.TSS(f, *n) }
+	default:
+		return fmt.Errorf("bad mode %q", *mode)
+	}
+
+	r, c, err := rf.open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	bw := bufio.NewWriter(os.Stdout)
+	sc := geneio.NewScanner(r)
+	for sc.Next() {
+		fts := []feat.Feature{sc.Gene()}
+		if *transcripts {
+			fts = fts[:0]
+			for _, t := range geneio.Transcripts(sc.Gene()) {
+				fts = append(fts, t)
+			}
+		}
+		var rs []*flank.Region
+		for _, f := range fts {
+			reg, err := region(f)
+			if err != nil {
+				return err
+			}
+			rs = append(rs, reg)
+		}
+		if err := flank.WriteBED(bw, rs); err != nil {
+			return err
+		}
+	}
+	if err := sc.Error(); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
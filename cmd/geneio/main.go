@@ -0,0 +1,135 @@
+// Command geneio provides tools that operate on gene annotations.
+//
+// Usage:
+//
+//	geneio <command> [flags] [arguments]
+//
+// Annotations are read from GFF v2 files with genes and transcripts grouped
+// by the gene_id and transcript_id tags, from newline-delimited JSON files
+// with a .json, .jsonl or .ndjson extension, or from gene databases with a
+// .gdb extension written by the db command. A file name of "-" reads from
+// standard input.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/biogo/biogo/io/featio/gff"
+	"github.com/go-bio/geneio"
+	"github.com/go-bio/geneio/genedb"
+	ggff "github.com/go-bio/geneio/gff"
+	gjson "github.com/go-bio/geneio/json"
+)
+
+// command is a geneio subcommand.
+type command struct {
+	name, summary string
+	run           func(args []string) error
+}
+
+var commands = []command{
+	{"annotate", "annotate the variants of a VCF file with predicted consequences", runAnnotate},
+	{"closest", "report the closest features of a second annotation", setopCommand("closest")},
+	{"count", "count aligned reads for the genes of an annotation", runCount},
+	{"coverage", "report read coverage of the genes, transcripts or exons of an annotation", runCoverage},
+	{"db", "write a binary gene database for fast loading", runDB},
+	{"diff", "compare the gene models of two annotations", runDiff},
+	{"flank", "write promoter and flanking regions of genes or transcripts as BED", runFlank},
+	{"intersect", "keep the features that overlap a second annotation", setopCommand("intersect")},
+	{"sort", "sort an annotation by sequence, start and gene ID", runSort},
+	{"stats", "report summary statistics of an annotation", runStats},
+	{"subtract", "keep the features that do not overlap a second annotation", setopCommand("subtract")},
+}
+
+// exitError is returned by commands that want a specific exit status without
+// printing an error.
+type exitError int
+
+func (e exitError) Error() string { return fmt.Sprintf("exit status %d", int(e)) }
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: geneio <command> [flags] [arguments]")
+	fmt.Fprintln(os.Stderr, "\nThe commands are:")
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "\t%-10s %s\n", c.name, c.summary)
+	}
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	for _, c := range commands {
+		if c.name != os.Args[1] {
+			continue
+		}
+		err := c.run(os.Args[2:])
+		if e, ok := err.(exitError); ok {
+			os.Exit(int(e))
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "geneio %s: %v\n", c.name, err)
+			os.Exit(1)
+		}
+		return
+	}
+	fmt.Fprintf(os.Stderr, "geneio: unknown command %q\n", os.Args[1])
+	usage()
+	os.Exit(2)
+}
+
+// readerFlags holds the flags that control how annotations are read.
+type readerFlags struct {
+	geneTag, transcriptTag string
+}
+
+// register registers the reader flags on fs.
+func (f *readerFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.geneTag, "gene-tag", "gene_id", "GFF tag that groups features into genes")
+	fs.StringVar(&f.transcriptTag, "transcript-tag", "transcript_id", "GFF tag that groups features into transcripts")
+}
+
+// open opens the annotation at path and returns a reader for its genes. Paths
+// ending in .json, .jsonl or .ndjson are read as newline-delimited JSON,
+// paths ending in .gdb as gene databases and all others as GFF. The returned
+// closer must be closed when reading is done.
+func (f *readerFlags) open(path string) (geneio.Reader, io.Closer, error) {
+	if filepath.Ext(path) == ".gdb" {
+		db, err := genedb.Open(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		return db.NewReader(), db, nil
+	}
+	var rc io.ReadCloser = os.Stdin
+	if path != "-" {
+		fh, err := os.Open(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		rc = fh
+	}
+	switch filepath.Ext(path) {
+	case ".json", ".jsonl", ".ndjson":
+		return gjson.NewReader(rc), rc, nil
+	}
+	r := ggff.NewReader(gff.NewReader(rc))
+	if err := r.SetGeneTag(f.geneTag); err != nil {
+		rc.Close()
+		return nil, nil, err
+	}
+	if err := r.SetTranscriptTag(f.transcriptTag); err != nil {
+		rc.Close()
+		return nil, nil, err
+	}
+	if err := r.SetAttributes(true); err != nil {
+		rc.Close()
+		return nil, nil, err
+	}
+	return r, rc, nil
+}
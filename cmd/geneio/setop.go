@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/biogo/biogo/feat"
+	"github.com/biogo/biogo/feat/gene"
+	"github.com/go-bio/geneio"
+	ggff "github.com/go-bio/geneio/gff"
+	"github.com/go-bio/geneio/index"
+	"github.com/go-bio/geneio/setop"
+)
+
+// setopFlags holds the flags shared by the set operation commands.
+type setopFlags struct {
+	level, strand string
+	rf            readerFlags
+}
+
+func (f *setopFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.level, "level", "gene", "compare `gene`, transcript or exon features")
+	fs.StringVar(&f.strand, "strand", "either", "strand requirement: `either`, same or opposite")
+	f.rf.register(fs)
+}
+
+// options returns the set operation options of f.
+func (f *setopFlags) options() (setop.Options, error) {
+	var opts setop.Options
+	switch f.level {
+	case "gene":
+		opts.Level = setop.Gene
+	case "transcript":
+		opts.Level = setop.Transcript
+	case "exon":
+		opts.Level = setop.Exon
+	default:
+		return opts, fmt.Errorf("bad level %q", f.level)
+	}
+	switch f.strand {
+	case "either":
+		opts.Strand = setop.Either
+	case "same":
+		opts.Strand = setop.Same
+	case "opposite":
+		opts.Strand = setop.Opposite
+	default:
+		return opts, fmt.Errorf("bad strand requirement %q", f.strand)
+	}
+	return opts, nil
+}
+
+// setopCommand returns the run function of the set operation command name,
+// one of intersect, subtract and closest.
+func setopCommand(name string) func(args []string) error {
+	return func(args []string) error {
+		fs := flag.NewFlagSet(name, flag.ExitOnError)
+		var sf setopFlags
+		sf.register(fs)
+		var intervals *bool
+		if name != "closest" {
+			intervals = fs.Bool("intervals", false, "write the overlapping or remaining parts of features as BED instead of GFF")
+		}
+		fs.Usage = func() {
+			fmt.Fprintf(os.Stderr, "usage: geneio %s [flags] a b\n", name)
+			fs.PrintDefaults()
+		}
+		fs.Parse(args)
+		if fs.NArg() != 2 {
+			fs.Usage()
+			return exitError(2)
+		}
+		opts, err := sf.options()
+		if err != nil {
+			return err
+		}
+
+		rb, cb, err := sf.rf.open(fs.Arg(1))
+		if err != nil {
+			return err
+		}
+		b, err := index.Read(rb)
+		cb.Close()
+		if err != nil {
+			return err
+		}
+		ra, ca, err := sf.rf.open(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		defer ca.Close()
+
+		bw := bufio.NewWriter(os.Stdout)
+		switch {
+		case name == "closest":
+			err = writeClosest(bw, ra, b, opts)
+		case *intervals:
+			fn := setop.Overlaps
+			if name == "subtract" {
+				fn = setop.Remainders
+			}
+			err = writeIntervals(bw, ra, b, opts, fn)
+		default:
+			r := setop.Intersect(ra, b, opts)
+			if name == "subtract" {
+				r = setop.Subtract(ra, b, opts)
+			}
+			w := ggff.NewWriter(bw)
+			sc := geneio.NewScanner(r)
+			for sc.Next() {
+				if _, err := w.Write(sc.Gene()); err != nil {
+					return err
+				}
+			}
+			err = sc.Error()
+		}
+		if err != nil {
+			return err
+		}
+		return bw.Flush()
+	}
+}
+
+// writeIntervals writes the intervals found by fn for the genes of r to w as
+// BED6 lines.
+func writeIntervals(w io.Writer, r geneio.Reader, b *index.Index, opts setop.Options, fn func(g gene.Interface, b *index.Index, opts setop.Options) []*setop.Interval) error {
+	sc := geneio.NewScanner(r)
+	for sc.Next() {
+		for _, iv := range fn(sc.Gene(), b, opts) {
+			if _, err := fmt.Fprintf(w, "%s\t%d\t%d\t%s\t0\t%s\n", iv.Location().Name(), iv.Start(), iv.End(), iv.Name(), strand(iv.Orient)); err != nil {
+				return err
+			}
+		}
+	}
+	return sc.Error()
+}
+
+// writeClosest writes the closest features of b to those of the genes of r
+// to w as tab separated lines of the two feature names and their distance.
+// Features without a match have a B name of ".".
+func writeClosest(w io.Writer, r geneio.Reader, b *index.Index, opts setop.Options) error {
+	sc := geneio.NewScanner(r)
+	for sc.Next() {
+		for _, m := range setop.Closest(sc.Gene(), b, opts) {
+			name, dist := ".", "."
+			if m.B != nil {
+				name, dist = m.B.Name(), fmt.Sprint(m.Distance)
+			}
+			if _, err := fmt.Fprintf(w, "%s\t%s\t%s\n", m.A.Name(), name, dist); err != nil {
+				return err
+			}
+		}
+	}
+	return sc.Error()
+}
+
+// strand returns the BED strand of o.
+func strand(o feat.Orientation) string {
+	switch o {
+	case feat.Forward:
+		return "+"
+	case feat.Reverse:
+		return "-"
+	}
+	return "."
+}
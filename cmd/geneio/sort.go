@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/go-bio/geneio"
+	"github.com/go-bio/geneio/genesort"
+	ggff "github.com/go-bio/geneio/gff"
+)
+
+// runSort implements the sort command.
+func runSort(args []string) error {
+	fs := flag.NewFlagSet("sort", flag.ExitOnError)
+	dictPath := fs.String("dict", "", "order sequences as in this .fai, .dict or FASTA file instead of naturally")
+	max := fs.Int("buffer", 100000, "maximum number of genes held in memory")
+	tmp := fs.String("tmp", "", "directory for temporary files")
+	var rf readerFlags
+	rf.register(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: geneio sort [flags] annotation")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return exitError(2)
+	}
+
+	less := genesort.Natural()
+	if *dictPath != "" {
+		d, err := readSeqDict(*dictPath)
+		if err != nil {
+			return err
+		}
+		less = genesort.Dict(d)
+	}
+
+	r, c, err := rf.open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	s, err := genesort.Sort(r, less, *max, *tmp)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	sorted, err := s.Sort()
+	if err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(os.Stdout)
+	w := ggff.NewWriter(bw)
+	sc := geneio.NewScanner(sorted)
+	for sc.Next() {
+		if _, err := w.Write(sc.Gene()); err != nil {
+			return err
+		}
+	}
+	if err := sc.Error(); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// readSeqDict reads a sequence dictionary from path. The format is chosen by
+// the file extension: .fai and .dict files are read as such and anything else
+// as FASTA.
+func readSeqDict(path string) (*geneio.SeqDict, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	switch {
+	case strings.HasSuffix(path, ".fai"):
+		return geneio.ReadFai(f)
+	case strings.HasSuffix(path, ".dict"):
+		return geneio.ReadDict(f)
+	}
+	return geneio.ReadFasta(bufio.NewReader(f))
+}
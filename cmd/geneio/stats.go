@@ -0,0 +1,64 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	ggff "github.com/go-bio/geneio/gff"
+	"github.com/go-bio/geneio/stats"
+)
+
+// runStats implements the stats command.
+func runStats(args []string) error {
+	fs := flag.NewFlagSet("stats", flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "write statistics as JSON")
+	checkFrames := fs.Bool("check-frames", false, "report GFF frames that disagree with the computed CDS phases and exit with status 1")
+	var rf readerFlags
+	rf.register(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: geneio stats [flags] annotation")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return exitError(2)
+	}
+
+	r, c, err := rf.open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	gr, isGFF := r.(*ggff.Reader)
+	if *checkFrames {
+		if !isGFF {
+			return fmt.Errorf("%s: frames can only be checked for GFF input", fs.Arg(0))
+		}
+		if err := gr.SetCheckFrames(true); err != nil {
+			return err
+		}
+	}
+
+	s, err := stats.Read(r)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		err = stats.WriteJSON(os.Stdout, s)
+	} else {
+		err = stats.WriteText(os.Stdout, s)
+	}
+	if err != nil || !*checkFrames {
+		return err
+	}
+	errs := gr.FrameErrors()
+	for _, e := range errs {
+		fmt.Fprintln(os.Stderr, e)
+	}
+	if len(errs) != 0 {
+		return exitError(1)
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package geneio
+
+import (
+	"sort"
+
+	"github.com/biogo/biogo/feat"
+	"github.com/biogo/biogo/feat/gene"
+)
+
+// CollapseUnion returns a new gene with the same ID, location and orientation
+// as g that holds a single gene.NonCodingTranscript. The exons of the
+// transcript are the union of the exons of all transcripts of g, with
+// overlapping and touching exons merged into one. The transcript is named
+// after the gene. It returns nil and an error if g has no transcripts.
+func CollapseUnion(g gene.Interface) (*gene.Gene, error) {
+	var all []interval
+	n := 0
+	for _, t := range Transcripts(g) {
+		all = append(all, exonIntervals(t)...)
+		n++
+	}
+	if n == 0 {
+		return nil, &FeaturesError{Gene: g, Msg: "geneio: no transcripts to collapse"}
+	}
+	return collapsedGene(g, mergeIntervals(all))
+}
+
+// CollapseIntersection returns a new gene with the same ID, location and
+// orientation as g that holds a single gene.NonCodingTranscript. The exons of
+// the transcript are the constitutive exonic regions of g, that is, the
+// regions that are exonic in every transcript of g. The transcript is named
+// after the gene. It returns nil and an error if g has no transcripts or no
+// constitutive exonic region.
+func CollapseIntersection(g gene.Interface) (*gene.Gene, error) {
+	var common []interval
+	n := 0
+	for _, t := range Transcripts(g) {
+		ivs := mergeIntervals(exonIntervals(t))
+		if n == 0 {
+			common = ivs
+		} else {
+			common = intersectIntervals(common, ivs)
+		}
+		n++
+	}
+	if n == 0 {
+		return nil, &FeaturesError{Gene: g, Msg: "geneio: no transcripts to collapse"}
+	}
+	if len(common) == 0 {
+		return nil, &FeaturesError{Gene: g, Msg: "geneio: no constitutive exons"}
+	}
+	return collapsedGene(g, common)
+}
+
+// collapsedGene creates and returns a gene with a single non coding transcript
+// with exons at ivs. ivs are relative to the start of g and must be sorted
+// and non touching. The returned gene starts at the first exon.
+func collapsedGene(g gene.Interface, ivs []interval) (*gene.Gene, error) {
+	cg := &gene.Gene{
+		ID:     g.Name(),
+		Chrom:  g.Location(),
+		Offset: g.Start() + ivs[0].start,
+		Orient: g.Orientation(),
+		Desc:   g.Description(),
+	}
+	t := &gene.NonCodingTranscript{
+		ID:     g.Name(),
+		Loc:    cg,
+		Offset: 0,
+		Orient: feat.Forward,
+	}
+	exons := make([]gene.Exon, len(ivs))
+	for i, iv := range ivs {
+		exons[i] = gene.Exon{
+			Transcript: t,
+			Offset:     iv.start - ivs[0].start,
+			Length:     iv.end - iv.start,
+		}
+	}
+	if err := t.SetExons(exons...); err != nil {
+		return nil, err
+	}
+	if err := cg.SetFeatures(t); err != nil {
+		return nil, err
+	}
+	return cg, nil
+}
+
+// Transcripts returns the features of g that are transcripts.
+func Transcripts(g gene.Interface) []gene.Transcript {
+	var ts []gene.Transcript
+	for _, f := range g.Features() {
+		if t, ok := f.(gene.Transcript); ok {
+			ts = append(ts, t)
+		}
+	}
+	return ts
+}
+
+// interval is a half-open interval.
+type interval struct {
+	start, end int
+}
+
+// exonIntervals returns the exons of t as intervals relative to the
+// location of t.
+func exonIntervals(t gene.Transcript) []interval {
+	exons := t.Exons()
+	ivs := make([]interval, len(exons))
+	for i, e := range exons {
+		ivs[i] = interval{start: t.Start() + e.Start(), end: t.Start() + e.End()}
+	}
+	return ivs
+}
+
+// mergeIntervals returns the sorted union of ivs. Overlapping and touching
+// intervals are merged into one. ivs is sorted in place.
+func mergeIntervals(ivs []interval) []interval {
+	if len(ivs) == 0 {
+		return nil
+	}
+	sort.Slice(ivs, func(i, j int) bool { return ivs[i].start < ivs[j].start })
+	merged := []interval{ivs[0]}
+	for _, iv := range ivs[1:] {
+		last := &merged[len(merged)-1]
+		if iv.start <= last.end {
+			if iv.end > last.end {
+				last.end = iv.end
+			}
+			continue
+		}
+		merged = append(merged, iv)
+	}
+	return merged
+}
+
+// intersectIntervals returns the intersection of a and b. Both must be sorted
+// and non overlapping.
+func intersectIntervals(a, b []interval) []interval {
+	var out []interval
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		start, end := a[i].start, a[i].end
+		if b[j].start > start {
+			start = b[j].start
+		}
+		if b[j].end < end {
+			end = b[j].end
+		}
+		if start < end {
+			out = append(out, interval{start: start, end: end})
+		}
+		if a[i].end < b[j].end {
+			i++
+		} else {
+			j++
+		}
+	}
+	return out
+}
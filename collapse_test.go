@@ -0,0 +1,119 @@
+package geneio
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/biogo/biogo/feat/gene"
+	"github.com/biogo/biogo/io/featio/gff"
+)
+
+// Test CollapseUnion and CollapseIntersection
+var collapseTests = []struct {
+	Name               string
+	Input              string
+	Union, Inter       [][2]int
+	UnionErr, InterErr string
+}{
+	{
+		Name: "Overlapping isoforms",
+		Input: "" +
+			"X\t.\texon\t11\t20\t0\t+\t.\tgene_id A; transcript_id A1;\n" +
+			"X\t.\texon\t31\t50\t0\t+\t.\tgene_id A; transcript_id A1;\n" +
+			"X\t.\texon\t16\t25\t0\t+\t.\tgene_id A; transcript_id A2;\n" +
+			"X\t.\texon\t41\t60\t0\t+\t.\tgene_id A; transcript_id A2;\n" +
+			"X\t.\texon\t61\t70\t0\t+\t.\tgene_id A; transcript_id A2;\n",
+		Union: [][2]int{{10, 25}, {30, 70}},
+		Inter: [][2]int{{15, 20}, {40, 50}},
+	},
+	{
+		Name: "Reverse single transcript",
+		Input: "" +
+			"Y\t.\texon\t30\t50\t0\t-\t.\tgene_id B; transcript_id B1;\n" +
+			"Y\t.\texon\t80\t99\t0\t-\t.\tgene_id B; transcript_id B1;\n" +
+			"Y\t.\tstop_codon\t40\t42\t0\t-\t.\tgene_id B; transcript_id B1;\n" +
+			"Y\t.\tstart_codon\t91\t93\t0\t-\t.\tgene_id B; transcript_id B1;\n",
+		Union: [][2]int{{29, 50}, {79, 99}},
+		Inter: [][2]int{{29, 50}, {79, 99}},
+	},
+	{
+		Name: "Reverse overlapping isoforms",
+		Input: "" +
+			"Y\t.\texon\t101\t130\t0\t-\t.\tgene_id D; transcript_id D1;\n" +
+			"Y\t.\texon\t151\t170\t0\t-\t.\tgene_id D; transcript_id D1;\n" +
+			"Y\t.\texon\t121\t135\t0\t-\t.\tgene_id D; transcript_id D2;\n" +
+			"Y\t.\texon\t156\t180\t0\t-\t.\tgene_id D; transcript_id D2;\n",
+		Union: [][2]int{{100, 135}, {150, 180}},
+		Inter: [][2]int{{120, 130}, {155, 170}},
+	},
+	{
+		Name: "No constitutive exons",
+		Input: "" +
+			"X\t.\texon\t11\t20\t0\t+\t.\tgene_id C; transcript_id C1;\n" +
+			"X\t.\texon\t31\t50\t0\t+\t.\tgene_id C; transcript_id C2;\n",
+		Union:    [][2]int{{10, 20}, {30, 50}},
+		InterErr: "geneio: no constitutive exons for gene C",
+	},
+}
+
+func TestCollapse(t *testing.T) {
+	for _, tt := range collapseTests {
+		r := GeneReader{
+			r: &FeatureReaderImpl{
+				r:   gff.NewReader(strings.NewReader(tt.Input)),
+				GID: "gene_id",
+				TID: "transcript_id",
+			},
+		}
+		g, err := r.Read()
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tt.Name, err)
+			continue
+		}
+
+		for _, c := range []struct {
+			name string
+			fn   func(gene.Interface) (*gene.Gene, error)
+			want [][2]int
+			err  string
+		}{
+			{"union", CollapseUnion, tt.Union, tt.UnionErr},
+			{"intersection", CollapseIntersection, tt.Inter, tt.InterErr},
+		} {
+			cg, err := c.fn(g)
+			if c.err != "" {
+				if err == nil || !strings.Contains(err.Error(), c.err) {
+					t.Errorf("%s %s: error %q, want error %q", tt.Name, c.name, err, c.err)
+				}
+				continue
+			} else if err != nil {
+				t.Errorf("%s %s: unexpected error %v", tt.Name, c.name, err)
+				continue
+			}
+
+			if cg.Name() != g.Name() || cg.Location() != g.Location() ||
+				cg.Orientation() != g.Orientation() {
+				t.Errorf("%s %s: gene identity not preserved", tt.Name, c.name)
+			}
+			feats := cg.Features()
+			if len(feats) != 1 {
+				t.Errorf("%s %s: out feat count=%d want 1", tt.Name, c.name, len(feats))
+				continue
+			}
+			tr, ok := feats[0].(*gene.NonCodingTranscript)
+			if !ok {
+				t.Errorf("%s %s: transcript is %T, want *gene.NonCodingTranscript", tt.Name, c.name, feats[0])
+				continue
+			}
+			var got [][2]int
+			for _, e := range tr.Exons() {
+				s := cg.Start() + tr.Start() + e.Start()
+				got = append(got, [2]int{s, s + e.Len()})
+			}
+			if !reflect.DeepEqual(got, c.want) {
+				t.Errorf("%s %s: out exons=%v want %v", tt.Name, c.name, got, c.want)
+			}
+		}
+	}
+}
@@ -0,0 +1,404 @@
+// Package compare classifies transcripts against a reference annotation in
+// the manner of gffcompare.
+//
+// Each query transcript is assigned the reference transcript it matches best
+// together with a class code describing the relationship between the two.
+// A Comparer also accumulates the sensitivity and precision of the query
+// annotation at base, exon, intron and transcript level.
+package compare
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/biogo/biogo/feat/gene"
+	"github.com/go-bio/geneio"
+	"github.com/go-bio/geneio/index"
+)
+
+// Class is a gffcompare style class code.
+type Class byte
+
+// Class codes, listed from the best to the worst match.
+const (
+	Exact        Class = '=' // Identical intron chain.
+	Contained    Class = 'c' // Contained in the reference intron chain.
+	NovelIsoform Class = 'j' // At least one splice junction shared.
+	Overlap      Class = 'o' // Other exonic overlap on the same strand.
+	Intronic     Class = 'i' // Fully within a reference intron.
+	Antisense    Class = 'x' // Exonic overlap on the opposite strand.
+	Intergenic   Class = 'u' // No reference transcript nearby.
+)
+
+// rank is the preference of each class when choosing the best match.
+var rank = map[Class]int{
+	Exact:        7,
+	Contained:    6,
+	NovelIsoform: 5,
+	Overlap:      4,
+	Intronic:     3,
+	Antisense:    2,
+	Intergenic:   1,
+}
+
+// String returns the class code as a string.
+func (c Class) String() string { return string(c) }
+
+// Match is the best reference match of a query transcript. Ref is nil if
+// Class is Intergenic.
+type Match struct {
+	Query gene.Transcript
+	Ref   gene.Transcript
+	Class Class
+	// Overlap is the number of exonic bases shared by Query and Ref.
+	Overlap int
+}
+
+// Accuracy holds the counts needed to compute sensitivity and precision at
+// one level of comparison.
+type Accuracy struct {
+	// RefMatched and QueryMatched are the numbers of matched reference and
+	// query elements.
+	RefMatched, QueryMatched int
+	// RefTotal and QueryTotal are the total numbers of reference and query
+	// elements.
+	RefTotal, QueryTotal int
+}
+
+// Sensitivity returns the fraction of reference elements that are matched.
+func (a Accuracy) Sensitivity() float64 {
+	if a.RefTotal == 0 {
+		return 0
+	}
+	return float64(a.RefMatched) / float64(a.RefTotal)
+}
+
+// Precision returns the fraction of query elements that are matched.
+func (a Accuracy) Precision() float64 {
+	if a.QueryTotal == 0 {
+		return 0
+	}
+	return float64(a.QueryMatched) / float64(a.QueryTotal)
+}
+
+// Summary holds the accuracy of a query annotation at each level.
+type Summary struct {
+	Base, Exon, Intron, Transcript Accuracy
+}
+
+// WriteTo writes s to w as a table of sensitivity and precision in percent.
+// It implements io.WriterTo.
+func (s *Summary) WriteTo(w io.Writer) (int64, error) {
+	var n int64
+	for _, l := range []struct {
+		name string
+		a    Accuracy
+	}{
+		{"Base", s.Base},
+		{"Exon", s.Exon},
+		{"Intron", s.Intron},
+		{"Transcript", s.Transcript},
+	} {
+		c, err := fmt.Fprintf(w, "%-10s level: %6.1f | %6.1f\n",
+			l.name, 100*l.a.Sensitivity(), 100*l.a.Precision())
+		n += int64(c)
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// Comparer compares query genes against an indexed reference annotation.
+type Comparer struct {
+	ref *index.Index
+
+	refBases, queryBases map[strandKey][][2]int
+	refExons, queryExons map[element]bool
+	refIntrons           map[element]bool
+	queryIntrons         map[element]bool
+	refTranscripts       int
+	queryTranscripts     int
+	exactQueries         int
+	exactRefs            map[gene.Transcript]bool
+}
+
+// strandKey identifies a strand of a reference sequence.
+type strandKey struct {
+	chrom  string
+	strand int
+}
+
+// element is an exon or intron in reference coordinates.
+type element struct {
+	strandKey
+	start, end int
+}
+
+// NewComparer returns a new Comparer against the genes in ref.
+func NewComparer(ref *index.Index) *Comparer {
+	c := &Comparer{
+		ref:          ref,
+		refBases:     make(map[strandKey][][2]int),
+		queryBases:   make(map[strandKey][][2]int),
+		refExons:     make(map[element]bool),
+		queryExons:   make(map[element]bool),
+		refIntrons:   make(map[element]bool),
+		queryIntrons: make(map[element]bool),
+		exactRefs:    make(map[gene.Transcript]bool),
+	}
+	for _, chrom := range ref.Chroms() {
+		for _, g := range ref.Genes(chrom) {
+			for _, t := range geneio.Transcripts(g) {
+				m := newModel(t)
+				c.refBases[m.strandKey] = append(c.refBases[m.strandKey], m.exons...)
+				m.addElements(c.refExons, c.refIntrons)
+				c.refTranscripts++
+			}
+		}
+	}
+	return c
+}
+
+// Classify returns the best reference match of each transcript of g, in the
+// order of the transcripts of g, and records them for the Summary.
+func (c *Comparer) Classify(g gene.Interface) []Match {
+	var matches []Match
+	for _, t := range geneio.Transcripts(g) {
+		q := newModel(t)
+		best := Match{Query: t, Class: Intergenic}
+		if len(q.exons) == 0 {
+			matches = append(matches, best)
+			continue
+		}
+		for _, rg := range c.ref.Overlapping(q.chrom, q.start(), q.end()) {
+			for _, rt := range geneio.Transcripts(rg) {
+				m := Match{Query: t, Ref: rt}
+				m.Class, m.Overlap = classify(q, newModel(rt))
+				if rank[m.Class] > rank[best.Class] ||
+					(m.Class == best.Class && m.Overlap > best.Overlap) {
+					best = m
+				}
+			}
+		}
+		c.record(q, best)
+		matches = append(matches, best)
+	}
+	return matches
+}
+
+// record adds q and its best match m to the accumulated summary.
+func (c *Comparer) record(q *model, m Match) {
+	c.queryBases[q.strandKey] = append(c.queryBases[q.strandKey], q.exons...)
+	q.addElements(c.queryExons, c.queryIntrons)
+	c.queryTranscripts++
+	if m.Class == Exact {
+		c.exactQueries++
+		c.exactRefs[m.Ref] = true
+	}
+}
+
+// Summary returns the accuracy of all genes classified so far.
+func (c *Comparer) Summary() *Summary {
+	s := &Summary{
+		Exon:   setAccuracy(c.refExons, c.queryExons),
+		Intron: setAccuracy(c.refIntrons, c.queryIntrons),
+		Transcript: Accuracy{
+			RefMatched:   len(c.exactRefs),
+			QueryMatched: c.exactQueries,
+			RefTotal:     c.refTranscripts,
+			QueryTotal:   c.queryTranscripts,
+		},
+	}
+	for k, ivs := range c.refBases {
+		r := merge(ivs)
+		s.Base.RefTotal += length(r)
+		shared := overlap(r, merge(c.queryBases[k]))
+		s.Base.RefMatched += shared
+		s.Base.QueryMatched += shared
+	}
+	for _, ivs := range c.queryBases {
+		s.Base.QueryTotal += length(merge(ivs))
+	}
+	return s
+}
+
+// setAccuracy returns the accuracy of the query elements against the
+// reference elements.
+func setAccuracy(ref, query map[element]bool) Accuracy {
+	a := Accuracy{RefTotal: len(ref), QueryTotal: len(query)}
+	for e := range query {
+		if ref[e] {
+			a.RefMatched++
+			a.QueryMatched++
+		}
+	}
+	return a
+}
+
+// classify returns the class code of q against r and their exonic overlap.
+func classify(q, r *model) (Class, int) {
+	if len(r.exons) == 0 {
+		return Intergenic, 0
+	}
+	ov := overlap(q.exons, r.exons)
+	if q.strand != r.strand {
+		if ov > 0 {
+			return Antisense, ov
+		}
+		return Intergenic, 0
+	}
+	if ov == 0 {
+		for _, in := range r.introns() {
+			if q.start() >= in[0] && q.end() <= in[1] {
+				return Intronic, 0
+			}
+		}
+		return Intergenic, 0
+	}
+
+	qi, ri := q.introns(), r.introns()
+	switch {
+	case len(qi) == 0 && len(ri) == 0:
+		longest := q.end() - q.start()
+		if l := r.end() - r.start(); l > longest {
+			longest = l
+		}
+		if 5*ov >= 4*longest {
+			return Exact, ov
+		}
+	case equalChains(qi, ri):
+		return Exact, ov
+	}
+	if ov == length(q.exons) && subChain(qi, ri) {
+		return Contained, ov
+	}
+	for _, a := range qi {
+		for _, b := range ri {
+			if a == b {
+				return NovelIsoform, ov
+			}
+		}
+	}
+	return Overlap, ov
+}
+
+// equalChains returns whether a and b are the same intron chain.
+func equalChains(a, b [][2]int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// subChain returns whether a is a contiguous part of b. An empty chain is
+// part of any chain.
+func subChain(a, b [][2]int) bool {
+	if len(a) == 0 {
+		return true
+	}
+	for i := 0; i+len(a) <= len(b); i++ {
+		if equalChains(a, b[i:i+len(a)]) {
+			return true
+		}
+	}
+	return false
+}
+
+// model is a transcript in reference coordinates with sorted exons.
+type model struct {
+	strandKey
+	exons [][2]int
+}
+
+// newModel returns the model of t.
+func newModel(t gene.Transcript) *model {
+	_, ref := geneio.RefPosition(t, 0)
+	m := &model{strandKey: strandKey{chrom: ref.Name(), strand: int(geneio.RefOrientation(t))}}
+	for _, e := range t.Exons() {
+		s, _ := geneio.RefPosition(t, e.Start())
+		m.exons = append(m.exons, [2]int{s, s + e.Len()})
+	}
+	return m
+}
+
+func (m *model) start() int { return m.exons[0][0] }
+func (m *model) end() int   { return m.exons[len(m.exons)-1][1] }
+
+// introns returns the intron chain of m.
+func (m *model) introns() [][2]int {
+	var in [][2]int
+	for i := 1; i < len(m.exons); i++ {
+		in = append(in, [2]int{m.exons[i-1][1], m.exons[i][0]})
+	}
+	return in
+}
+
+// addElements adds the exons and introns of m to the given sets.
+func (m *model) addElements(exons, introns map[element]bool) {
+	for _, e := range m.exons {
+		exons[element{m.strandKey, e[0], e[1]}] = true
+	}
+	for _, in := range m.introns() {
+		introns[element{m.strandKey, in[0], in[1]}] = true
+	}
+}
+
+// merge returns the sorted union of ivs. ivs is sorted in place.
+func merge(ivs [][2]int) [][2]int {
+	if len(ivs) == 0 {
+		return nil
+	}
+	sort.Slice(ivs, func(i, j int) bool { return ivs[i][0] < ivs[j][0] })
+	merged := [][2]int{ivs[0]}
+	for _, iv := range ivs[1:] {
+		last := &merged[len(merged)-1]
+		if iv[0] <= last[1] {
+			if iv[1] > last[1] {
+				last[1] = iv[1]
+			}
+			continue
+		}
+		merged = append(merged, iv)
+	}
+	return merged
+}
+
+// overlap returns the number of bases shared by the sorted, non overlapping
+// intervals a and b.
+func overlap(a, b [][2]int) int {
+	n := 0
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		start, end := a[i][0], a[i][1]
+		if b[j][0] > start {
+			start = b[j][0]
+		}
+		if b[j][1] < end {
+			end = b[j][1]
+		}
+		if start < end {
+			n += end - start
+		}
+		if a[i][1] < b[j][1] {
+			i++
+		} else {
+			j++
+		}
+	}
+	return n
+}
+
+// length returns the total length of ivs.
+func length(ivs [][2]int) int {
+	n := 0
+	for _, iv := range ivs {
+		n += iv[1] - iv[0]
+	}
+	return n
+}
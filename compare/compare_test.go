@@ -0,0 +1,96 @@
+package compare
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/biogo/biogo/io/featio/gff"
+	"github.com/go-bio/geneio"
+	ggff "github.com/go-bio/geneio/gff"
+	"github.com/go-bio/geneio/index"
+)
+
+var refInput = "" +
+	"X\t.\texon\t101\t200\t0\t+\t.\tgene_id R; transcript_id R1;\n" +
+	"X\t.\texon\t301\t400\t0\t+\t.\tgene_id R; transcript_id R1;\n" +
+	"X\t.\texon\t501\t600\t0\t+\t.\tgene_id R; transcript_id R1;\n" +
+	"X\t.\texon\t1001\t1100\t0\t+\t.\tgene_id S; transcript_id S1;\n"
+
+var queryInput = "" +
+	"X\t.\texon\t101\t200\t0\t+\t.\tgene_id Q; transcript_id exact;\n" +
+	"X\t.\texon\t301\t400\t0\t+\t.\tgene_id Q; transcript_id exact;\n" +
+	"X\t.\texon\t501\t600\t0\t+\t.\tgene_id Q; transcript_id exact;\n" +
+	"X\t.\texon\t151\t200\t0\t+\t.\tgene_id Q; transcript_id contained;\n" +
+	"X\t.\texon\t301\t350\t0\t+\t.\tgene_id Q; transcript_id contained;\n" +
+	"X\t.\texon\t101\t200\t0\t+\t.\tgene_id Q; transcript_id novel;\n" +
+	"X\t.\texon\t301\t450\t0\t+\t.\tgene_id Q; transcript_id novel;\n" +
+	"X\t.\texon\t551\t600\t0\t+\t.\tgene_id Q; transcript_id novel;\n" +
+	"X\t.\texon\t211\t250\t0\t+\t.\tgene_id Q; transcript_id intronic;\n" +
+	"X\t.\texon\t1051\t1060\t0\t-\t.\tgene_id T; transcript_id antisense;\n" +
+	"X\t.\texon\t2001\t2100\t0\t+\t.\tgene_id U; transcript_id intergenic;\n"
+
+// Test Classify and Summary
+func TestComparer(t *testing.T) {
+	ref, err := index.Read(ggff.NewReader(gff.NewReader(strings.NewReader(refInput))))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	c := NewComparer(ref)
+
+	classes := map[string]Class{}
+	refs := map[string]string{}
+	sc := geneio.NewScanner(ggff.NewReader(gff.NewReader(strings.NewReader(queryInput))))
+	for sc.Next() {
+		for _, m := range c.Classify(sc.Gene()) {
+			classes[m.Query.Name()] = m.Class
+			if m.Ref != nil {
+				refs[m.Query.Name()] = m.Ref.Name()
+			}
+		}
+	}
+	if err := sc.Error(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	wantClasses := map[string]Class{
+		"exact":      Exact,
+		"contained":  Contained,
+		"novel":      NovelIsoform,
+		"intronic":   Intronic,
+		"antisense":  Antisense,
+		"intergenic": Intergenic,
+	}
+	if !reflect.DeepEqual(classes, wantClasses) {
+		t.Errorf("out classes=%q want %q", classes, wantClasses)
+	}
+	wantRefs := map[string]string{
+		"exact":     "R1",
+		"contained": "R1",
+		"novel":     "R1",
+		"intronic":  "R1",
+		"antisense": "S1",
+	}
+	if !reflect.DeepEqual(refs, wantRefs) {
+		t.Errorf("out refs=%q want %q", refs, wantRefs)
+	}
+
+	s := c.Summary()
+	want := Summary{
+		Base:       Accuracy{RefMatched: 300, QueryMatched: 300, RefTotal: 400, QueryTotal: 500},
+		Exon:       Accuracy{RefMatched: 3, QueryMatched: 3, RefTotal: 4, QueryTotal: 10},
+		Intron:     Accuracy{RefMatched: 2, QueryMatched: 2, RefTotal: 2, QueryTotal: 3},
+		Transcript: Accuracy{RefMatched: 1, QueryMatched: 1, RefTotal: 2, QueryTotal: 6},
+	}
+	if *s != want {
+		t.Errorf("out summary=%+v want %+v", *s, want)
+	}
+	var buf bytes.Buffer
+	if _, err := s.WriteTo(&buf); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if !strings.Contains(buf.String(), "Intron     level:  100.0 |   66.7") {
+		t.Errorf("unexpected summary table:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,54 @@
+package geneio
+
+import (
+	"github.com/biogo/biogo/feat"
+)
+
+// RefPosition returns the position on the reference sequence of position pos
+// relative to f, together with the reference sequence. The reference is the
+// first feature up the location chain of f that has no location, e.g. the
+// chromosome of a gene.
+func RefPosition(f feat.Feature, pos int) (int, feat.Feature) {
+	for f.Location() != nil {
+		pos += f.Start()
+		f = f.Location()
+	}
+	return pos, f
+}
+
+// RefOrientation returns the orientation of f relative to its reference
+// sequence by combining the orientations along the location chain of f. It
+// returns feat.NotOriented if any feature on the chain is not oriented.
+func RefOrientation(f feat.Feature) feat.Orientation {
+	o := feat.Forward
+	for ; f.Location() != nil; f = f.Location() {
+		or, ok := f.(feat.Orienter)
+		if !ok {
+			return feat.NotOriented
+		}
+		o *= or.Orientation()
+	}
+	return o
+}
+
+// Chrom is a named reference sequence that genes can be located on when no
+// format specific location is available. It implements feat.Feature.
+type Chrom string
+
+// Start returns 0.
+func (c Chrom) Start() int { return 0 }
+
+// End returns 0; the length of a Chrom is not known.
+func (c Chrom) End() int { return 0 }
+
+// Len returns 0; the length of a Chrom is not known.
+func (c Chrom) Len() int { return 0 }
+
+// Name returns the name of c.
+func (c Chrom) Name() string { return string(c) }
+
+// Description returns "chromosome".
+func (c Chrom) Description() string { return "chromosome" }
+
+// Location returns nil; a Chrom is a reference sequence.
+func (c Chrom) Location() feat.Feature { return nil }
@@ -0,0 +1,316 @@
+// Package coordmap converts positions between genomic and transcript
+// coordinates.
+//
+// A Mapper follows the exon structure of a transcript to convert between
+// zero based positions on the reference sequence, positions along the
+// spliced transcript, coding sequence positions in the numbering of HGVS c.
+// descriptions and codon positions in the protein. Positions in introns are
+// described relative to the nearest exon boundary, as in c.123+4, and
+// transcripts on the reverse strand are numbered from their 5' end.
+package coordmap
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/biogo/biogo/feat"
+	"github.com/biogo/biogo/feat/gene"
+	"github.com/go-bio/geneio"
+)
+
+// Pos is a position in transcript coordinates. Base is the zero based
+// position along the spliced transcript from its 5' end. Positions before
+// the transcript have negative Base and positions after it Base of at least
+// the transcript length. Offset is non zero for intronic positions and is
+// the distance from the exon base at Base, positive towards the 3' end.
+type Pos struct {
+	Base   int
+	Offset int
+}
+
+// CDSPos is a position in coding sequence coordinates numbered as in HGVS
+// c. descriptions. In the coding sequence, Pos is the one based position
+// from the first base of the start codon. In the 5' UTR and upstream, Pos is
+// negative and counts back from the start codon. In the 3' UTR and
+// downstream, UTR3 is true and Pos is the one based position after the
+// last base of the stop codon. Offset is as for Pos.
+type CDSPos struct {
+	Pos    int
+	UTR3   bool
+	Offset int
+}
+
+// String returns p as in an HGVS c. description without the prefix, e.g.
+// "123+4", "-15" or "*7-2".
+func (p CDSPos) String() string {
+	s := strconv.Itoa(p.Pos)
+	if p.UTR3 {
+		s = "*" + s
+	}
+	switch {
+	case p.Offset > 0:
+		s += "+" + strconv.Itoa(p.Offset)
+	case p.Offset < 0:
+		s += strconv.Itoa(p.Offset)
+	}
+	return s
+}
+
+// exon is an exon in reference coordinates. first and last are the
+// reference positions of its 5' and 3' bases and tstart is the transcript
+// position of its 5' base.
+type exon struct {
+	first, last int
+	tstart      int
+	len         int
+}
+
+// Mapper converts positions for a transcript.
+type Mapper struct {
+	t      gene.Transcript
+	strand int
+	exons  []exon
+	len    int
+
+	coding           bool
+	cdsStart, cdsEnd int
+	phase            int
+}
+
+// New returns a Mapper for t. The transcript must be oriented on its
+// reference sequence.
+func New(t gene.Transcript) (*Mapper, error) {
+	m := &Mapper{t: t}
+	switch geneio.RefOrientation(t) {
+	case feat.Forward:
+		m.strand = 1
+	case feat.Reverse:
+		m.strand = -1
+	default:
+		return nil, fmt.Errorf("coordmap: transcript %s is not oriented", t.Name())
+	}
+	s, _ := geneio.RefPosition(t, 0)
+	exons := t.Exons()
+	if len(exons) == 0 {
+		return nil, fmt.Errorf("coordmap: transcript %s has no exons", t.Name())
+	}
+	m.exons = make([]exon, len(exons))
+	for i, e := range exons {
+		j := i
+		if m.strand < 0 {
+			j = len(exons) - 1 - i
+		}
+		x := exon{first: s + e.Start(), last: s + e.End() - 1, len: e.Len()}
+		if m.strand < 0 {
+			x.first, x.last = x.last, x.first
+		}
+		m.exons[j] = x
+	}
+	for i := range m.exons {
+		m.exons[i].tstart = m.len
+		m.len += m.exons[i].len
+	}
+
+	if ct, ok := geneio.Coding(t); ok {
+		m.coding = true
+		first, last := s+ct.CDSstart, s+ct.CDSend-1
+		if m.strand < 0 {
+			first, last = last, first
+		}
+		start, err := m.GenomicToTranscript(first)
+		if err != nil {
+			return nil, err
+		}
+		end, err := m.GenomicToTranscript(last)
+		if err != nil {
+			return nil, err
+		}
+		if start.Offset != 0 || end.Offset != 0 || start.Base < 0 || end.Base >= m.len {
+			return nil, fmt.Errorf("coordmap: coding sequence outside exons of transcript %s", t.Name())
+		}
+		m.cdsStart, m.cdsEnd = start.Base, end.Base+1
+		if a, ok := t.(geneio.Annotated); ok {
+			m.phase = a.CodingPhase()
+		}
+	}
+	return m, nil
+}
+
+// Transcript returns the transcript of m.
+func (m *Mapper) Transcript() gene.Transcript { return m.t }
+
+// Len returns the length of the spliced transcript.
+func (m *Mapper) Len() int { return m.len }
+
+// Coding returns whether the transcript of m is coding.
+func (m *Mapper) Coding() bool { return m.coding }
+
+// CDSLen returns the length of the coding sequence including the stop
+// codon, or zero for non coding transcripts.
+func (m *Mapper) CDSLen() int { return m.cdsEnd - m.cdsStart }
+
+// dist returns the distance from reference position a to b in the direction
+// of the transcript.
+func (m *Mapper) dist(a, b int) int { return (b - a) * m.strand }
+
+// GenomicToTranscript returns the transcript position of reference position
+// pos. Intronic positions are described relative to the nearest exon; a
+// position in the middle of an intron is described from the upstream exon.
+func (m *Mapper) GenomicToTranscript(pos int) (Pos, error) {
+	for i, e := range m.exons {
+		if m.dist(e.first, pos) < 0 {
+			if i == 0 {
+				return Pos{Base: m.dist(e.first, pos)}, nil
+			}
+			prev := m.exons[i-1]
+			up, down := m.dist(prev.last, pos), m.dist(pos, e.first)
+			if up <= down {
+				return Pos{Base: prev.tstart + prev.len - 1, Offset: up}, nil
+			}
+			return Pos{Base: e.tstart, Offset: -down}, nil
+		}
+		if m.dist(pos, e.last) >= 0 {
+			return Pos{Base: e.tstart + m.dist(e.first, pos)}, nil
+		}
+	}
+	last := m.exons[len(m.exons)-1]
+	return Pos{Base: m.len - 1 + m.dist(last.last, pos)}, nil
+}
+
+// TranscriptToGenomic returns the reference position of transcript position
+// p. It returns an error if p has an offset that does not lead into the
+// adjacent intron.
+func (m *Mapper) TranscriptToGenomic(p Pos) (int, error) {
+	var pos int
+	switch {
+	case p.Base < 0:
+		pos = m.exons[0].first + p.Base*m.strand
+	case p.Base >= m.len:
+		pos = m.exons[len(m.exons)-1].last + (p.Base-m.len+1)*m.strand
+	default:
+		for i, e := range m.exons {
+			if p.Base >= e.tstart+e.len {
+				continue
+			}
+			pos = e.first + (p.Base-e.tstart)*m.strand
+			switch {
+			case p.Offset > 0:
+				if p.Base != e.tstart+e.len-1 || i == len(m.exons)-1 ||
+					p.Offset >= m.dist(e.last, m.exons[i+1].first) {
+					return 0, m.notIntronic(p)
+				}
+			case p.Offset < 0:
+				if p.Base != e.tstart || i == 0 ||
+					-p.Offset >= m.dist(m.exons[i-1].last, e.first) {
+					return 0, m.notIntronic(p)
+				}
+			}
+			break
+		}
+	}
+	if p.Offset != 0 && (p.Base < 0 || p.Base >= m.len) {
+		return 0, m.notIntronic(p)
+	}
+	return pos + p.Offset*m.strand, nil
+}
+
+func (m *Mapper) notIntronic(p Pos) error {
+	return fmt.Errorf("coordmap: offset %d from base %d is not in an intron of transcript %s", p.Offset, p.Base, m.t.Name())
+}
+
+// errNotCoding returns the error for coding operations on a non coding
+// transcript.
+func (m *Mapper) errNotCoding() error {
+	return fmt.Errorf("coordmap: transcript %s is not coding", m.t.Name())
+}
+
+// TranscriptToCDS returns the coding sequence position of p.
+func (m *Mapper) TranscriptToCDS(p Pos) (CDSPos, error) {
+	if !m.coding {
+		return CDSPos{}, m.errNotCoding()
+	}
+	switch {
+	case p.Base < m.cdsStart:
+		return CDSPos{Pos: p.Base - m.cdsStart, Offset: p.Offset}, nil
+	case p.Base < m.cdsEnd:
+		return CDSPos{Pos: p.Base - m.cdsStart + 1, Offset: p.Offset}, nil
+	}
+	return CDSPos{Pos: p.Base - m.cdsEnd + 1, UTR3: true, Offset: p.Offset}, nil
+}
+
+// CDSToTranscript returns the transcript position of c.
+func (m *Mapper) CDSToTranscript(c CDSPos) (Pos, error) {
+	if !m.coding {
+		return Pos{}, m.errNotCoding()
+	}
+	switch {
+	case c.UTR3:
+		if c.Pos < 1 {
+			return Pos{}, fmt.Errorf("coordmap: bad 3' UTR position *%d", c.Pos)
+		}
+		return Pos{Base: m.cdsEnd + c.Pos - 1, Offset: c.Offset}, nil
+	case c.Pos < 0:
+		return Pos{Base: m.cdsStart + c.Pos, Offset: c.Offset}, nil
+	case c.Pos > 0:
+		if c.Pos > m.cdsEnd-m.cdsStart {
+			return Pos{}, fmt.Errorf("coordmap: position %d past the end of the coding sequence of transcript %s", c.Pos, m.t.Name())
+		}
+		return Pos{Base: m.cdsStart + c.Pos - 1, Offset: c.Offset}, nil
+	}
+	return Pos{}, errors.New("coordmap: coding position 0 does not exist")
+}
+
+// GenomicToCDS returns the coding sequence position of reference position
+// pos.
+func (m *Mapper) GenomicToCDS(pos int) (CDSPos, error) {
+	p, err := m.GenomicToTranscript(pos)
+	if err != nil {
+		return CDSPos{}, err
+	}
+	return m.TranscriptToCDS(p)
+}
+
+// CDSToGenomic returns the reference position of coding sequence position
+// c.
+func (m *Mapper) CDSToGenomic(c CDSPos) (int, error) {
+	p, err := m.CDSToTranscript(c)
+	if err != nil {
+		return 0, err
+	}
+	return m.TranscriptToGenomic(p)
+}
+
+// Phase returns the number of bases at the 5' end of the coding sequence
+// before the first complete codon. It is non zero only for geneio.Annotated
+// transcripts whose coding sequence start was not found.
+func (m *Mapper) Phase() int { return m.phase }
+
+// CDSToProtein returns the one based codon number of c in the protein and
+// the zero based position of c in the codon. Codons are counted from the
+// first complete codon of the coding sequence. c must be in the coding
+// sequence and not before its first complete codon.
+func (m *Mapper) CDSToProtein(c CDSPos) (codon, frame int, err error) {
+	if !m.coding {
+		return 0, 0, m.errNotCoding()
+	}
+	if c.UTR3 || c.Pos < 1 || c.Pos > m.cdsEnd-m.cdsStart || c.Offset != 0 {
+		return 0, 0, fmt.Errorf("coordmap: %s is not in the coding sequence of transcript %s", c, m.t.Name())
+	}
+	if c.Pos <= m.phase {
+		return 0, 0, fmt.Errorf("coordmap: %s is before the first complete codon of transcript %s", c, m.t.Name())
+	}
+	return (c.Pos-1-m.phase)/3 + 1, (c.Pos - 1 - m.phase) % 3, nil
+}
+
+// ProteinToCDS returns the coding sequence position of the first base of
+// the one based codon number codon, counted as by CDSToProtein.
+func (m *Mapper) ProteinToCDS(codon int) (CDSPos, error) {
+	if !m.coding {
+		return CDSPos{}, m.errNotCoding()
+	}
+	if codon < 1 || m.phase+3*codon > m.cdsEnd-m.cdsStart {
+		return CDSPos{}, fmt.Errorf("coordmap: codon %d out of range for transcript %s", codon, m.t.Name())
+	}
+	return CDSPos{Pos: m.phase + 3*(codon-1) + 1}, nil
+}
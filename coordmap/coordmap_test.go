@@ -0,0 +1,231 @@
+package coordmap
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/biogo/biogo/feat"
+	"github.com/biogo/biogo/io/featio/gff"
+	"github.com/go-bio/geneio"
+	ggff "github.com/go-bio/geneio/gff"
+)
+
+var mapperInput = "" +
+	"X\t.\texon\t11\t20\t0\t+\t.\tgene_id F; transcript_id F1;\n" +
+	"X\t.\texon\t31\t40\t0\t+\t.\tgene_id F; transcript_id F1;\n" +
+	"X\t.\tstart_codon\t13\t15\t0\t+\t.\tgene_id F; transcript_id F1;\n" +
+	"X\t.\tstop_codon\t35\t37\t0\t+\t.\tgene_id F; transcript_id F1;\n" +
+	"X\t.\texon\t11\t20\t0\t-\t.\tgene_id R; transcript_id R1;\n" +
+	"X\t.\texon\t31\t40\t0\t-\t.\tgene_id R; transcript_id R1;\n" +
+	"X\t.\tstart_codon\t35\t37\t0\t-\t.\tgene_id R; transcript_id R1;\n" +
+	"X\t.\tstop_codon\t13\t15\t0\t-\t.\tgene_id R; transcript_id R1;\n" +
+	"X\t.\texon\t11\t20\t0\t+\t.\tgene_id N; transcript_id N1;\n"
+
+// mappers returns the mappers of the transcripts of mapperInput by
+// transcript ID.
+func mappers(t *testing.T) map[string]*Mapper {
+	ms := make(map[string]*Mapper)
+	sc := geneio.NewScanner(ggff.NewReader(gff.NewReader(strings.NewReader(mapperInput))))
+	for sc.Next() {
+		for _, tr := range geneio.Transcripts(sc.Gene()) {
+			m, err := New(tr)
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			ms[tr.Name()] = m
+		}
+	}
+	if err := sc.Error(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	return ms
+}
+
+// Test GenomicToCDS and CDSToGenomic
+var cdsTests = []struct {
+	TID     string
+	Genomic int
+	Pos     Pos
+	CDS     string
+}{
+	{"F1", 12, Pos{2, 0}, "1"},
+	{"F1", 9, Pos{-1, 0}, "-3"},
+	{"F1", 11, Pos{1, 0}, "-1"},
+	{"F1", 20, Pos{9, 1}, "8+1"},
+	{"F1", 24, Pos{9, 5}, "8+5"},
+	{"F1", 25, Pos{10, -5}, "9-5"},
+	{"F1", 29, Pos{10, -1}, "9-1"},
+	{"F1", 36, Pos{16, 0}, "15"},
+	{"F1", 37, Pos{17, 0}, "*1"},
+	{"F1", 45, Pos{25, 0}, "*9"},
+	{"R1", 36, Pos{3, 0}, "1"},
+	{"R1", 40, Pos{-1, 0}, "-4"},
+	{"R1", 29, Pos{9, 1}, "7+1"},
+	{"R1", 20, Pos{10, -1}, "8-1"},
+	{"R1", 12, Pos{17, 0}, "15"},
+	{"R1", 9, Pos{20, 0}, "*3"},
+}
+
+func TestGenomicToCDS(t *testing.T) {
+	ms := mappers(t)
+	for _, tt := range cdsTests {
+		m := ms[tt.TID]
+		p, err := m.GenomicToTranscript(tt.Genomic)
+		if err != nil {
+			t.Errorf("%s %d: unexpected error %v", tt.TID, tt.Genomic, err)
+			continue
+		}
+		if p != tt.Pos {
+			t.Errorf("%s %d: out pos=%+v want %+v", tt.TID, tt.Genomic, p, tt.Pos)
+		}
+		c, err := m.GenomicToCDS(tt.Genomic)
+		if err != nil {
+			t.Errorf("%s %d: unexpected error %v", tt.TID, tt.Genomic, err)
+			continue
+		}
+		if c.String() != tt.CDS {
+			t.Errorf("%s %d: out c.=%s want %s", tt.TID, tt.Genomic, c, tt.CDS)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	for id, m := range mappers(t) {
+		for g := 0; g < 50; g++ {
+			p, err := m.GenomicToTranscript(g)
+			if err != nil {
+				t.Fatalf("%s %d: unexpected error %v", id, g, err)
+			}
+			got, err := m.TranscriptToGenomic(p)
+			if err != nil || got != g {
+				t.Errorf("%s %d: out genomic=%d error %v via %+v", id, g, got, err, p)
+			}
+			if !m.Coding() {
+				continue
+			}
+			c, err := m.TranscriptToCDS(p)
+			if err != nil {
+				t.Fatalf("%s %d: unexpected error %v", id, g, err)
+			}
+			got, err = m.CDSToGenomic(c)
+			if err != nil || got != g {
+				t.Errorf("%s %d: out genomic=%d error %v via c.%s", id, g, got, err, c)
+			}
+		}
+	}
+}
+
+func TestProtein(t *testing.T) {
+	m := mappers(t)["R1"]
+	for _, tt := range []struct {
+		CDS          CDSPos
+		Codon, Frame int
+		Error        string
+	}{
+		{CDS: CDSPos{Pos: 1}, Codon: 1},
+		{CDS: CDSPos{Pos: 4}, Codon: 2},
+		{CDS: CDSPos{Pos: 15}, Codon: 5, Frame: 2},
+		{CDS: CDSPos{Pos: 16}, Error: "coordmap: 16 is not in the coding sequence of transcript R1"},
+		{CDS: CDSPos{Pos: 3, Offset: 1}, Error: "coordmap: 3+1 is not in the coding sequence of transcript R1"},
+		{CDS: CDSPos{Pos: 1, UTR3: true}, Error: "coordmap: *1 is not in the coding sequence of transcript R1"},
+	} {
+		codon, frame, err := m.CDSToProtein(tt.CDS)
+		if tt.Error != "" {
+			if err == nil || err.Error() != tt.Error {
+				t.Errorf("c.%s: error %q, want error %q", tt.CDS, err, tt.Error)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("c.%s: unexpected error %v", tt.CDS, err)
+			continue
+		}
+		if codon != tt.Codon || frame != tt.Frame {
+			t.Errorf("c.%s: out codon=%d frame=%d want %d %d", tt.CDS, codon, frame, tt.Codon, tt.Frame)
+		}
+	}
+	if c, err := m.ProteinToCDS(5); err != nil || c != (CDSPos{Pos: 13}) {
+		t.Errorf("out c.=%s error %v want c.13", c, err)
+	}
+	if _, err := m.ProteinToCDS(6); err == nil {
+		t.Error("expected error for codon 6")
+	}
+}
+
+func TestProteinPhase(t *testing.T) {
+	genes, err := geneio.NewBuilder().
+		Gene("P", geneio.Chrom("X"), feat.Forward).
+		Transcript("P1").Exon(10, 30).CDS(10, 24).Tag(geneio.TagCDSStartNF).Phase(2).
+		Build()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	m, err := New(geneio.Transcripts(genes[0])[0])
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if m.Phase() != 2 {
+		t.Errorf("out phase=%d want 2", m.Phase())
+	}
+	for _, tt := range []struct {
+		CDS          CDSPos
+		Codon, Frame int
+		Error        string
+	}{
+		{CDS: CDSPos{Pos: 2}, Error: "coordmap: 2 is before the first complete codon of transcript P1"},
+		{CDS: CDSPos{Pos: 3}, Codon: 1},
+		{CDS: CDSPos{Pos: 7}, Codon: 2, Frame: 1},
+		{CDS: CDSPos{Pos: 14}, Codon: 4, Frame: 2},
+	} {
+		codon, frame, err := m.CDSToProtein(tt.CDS)
+		if tt.Error != "" {
+			if err == nil || err.Error() != tt.Error {
+				t.Errorf("c.%s: error %q, want error %q", tt.CDS, err, tt.Error)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("c.%s: unexpected error %v", tt.CDS, err)
+			continue
+		}
+		if codon != tt.Codon || frame != tt.Frame {
+			t.Errorf("c.%s: out codon=%d frame=%d want %d %d", tt.CDS, codon, frame, tt.Codon, tt.Frame)
+		}
+	}
+	if c, err := m.ProteinToCDS(4); err != nil || c != (CDSPos{Pos: 12}) {
+		t.Errorf("out c.=%s error %v want c.12", c, err)
+	}
+	if _, err := m.ProteinToCDS(5); err == nil {
+		t.Error("expected error for codon 5")
+	}
+}
+
+func TestErrors(t *testing.T) {
+	ms := mappers(t)
+	for _, tt := range []struct {
+		TID   string
+		Pos   Pos
+		Error string
+	}{
+		{"F1", Pos{5, 1}, "coordmap: offset 1 from base 5 is not in an intron of transcript F1"},
+		{"F1", Pos{9, 11}, "coordmap: offset 11 from base 9 is not in an intron of transcript F1"},
+		{"F1", Pos{10, 1}, "coordmap: offset 1 from base 10 is not in an intron of transcript F1"},
+		{"F1", Pos{0, -1}, "coordmap: offset -1 from base 0 is not in an intron of transcript F1"},
+		{"F1", Pos{-2, 1}, "coordmap: offset 1 from base -2 is not in an intron of transcript F1"},
+	} {
+		_, err := ms[tt.TID].TranscriptToGenomic(tt.Pos)
+		if err == nil || err.Error() != tt.Error {
+			t.Errorf("%s %+v: error %q, want error %q", tt.TID, tt.Pos, err, tt.Error)
+		}
+	}
+	if g, err := ms["F1"].TranscriptToGenomic(Pos{9, 10}); err != nil || g != 29 {
+		t.Errorf("out genomic=%d error %v want 29", g, err)
+	}
+	want := "coordmap: transcript N1 is not coding"
+	if _, err := ms["N1"].GenomicToCDS(10); err == nil || err.Error() != want {
+		t.Errorf("error %q, want error %q", err, want)
+	}
+	if _, err := ms["F1"].CDSToGenomic(CDSPos{}); err == nil {
+		t.Error("expected error for c.0")
+	}
+}
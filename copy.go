@@ -0,0 +1,73 @@
+package geneio
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/biogo/biogo/feat"
+	"github.com/biogo/biogo/feat/gene"
+)
+
+// CopyGene returns a new gene with the ID, location, orientation, description
+// and attributes of proto holding copies of ts, which keep their reference
+// coordinates. The gene starts at the start of its first transcript. It
+// returns an error if ts is empty.
+func CopyGene(proto gene.Interface, ts []gene.Transcript) (gene.Interface, error) {
+	if len(ts) == 0 {
+		return nil, errors.New("geneio: no transcripts to copy for gene " + proto.Name())
+	}
+	g := &gene.Gene{
+		ID:     proto.Name(),
+		Chrom:  proto.Location(),
+		Orient: proto.Orientation(),
+		Desc:   proto.Description(),
+	}
+	g.Offset, _ = RefPosition(ts[0], 0)
+	for _, t := range ts {
+		if s, _ := RefPosition(t, 0); s < g.Offset {
+			g.Offset = s
+		}
+	}
+	feats := make([]feat.Feature, len(ts))
+	for i, t := range ts {
+		c, err := CopyTranscript(t, g)
+		if err != nil {
+			return nil, err
+		}
+		feats[i] = c
+	}
+	if err := g.SetFeatures(feats...); err != nil {
+		return nil, err
+	}
+	return WithAttributes(g, copyAttributes(Attributes(proto))), nil
+}
+
+// CopyTranscript returns a copy of t located on g at the same reference
+// coordinates, keeping the annotation and attributes of t. The location of
+// g must be the reference sequence of t.
+func CopyTranscript(t gene.Transcript, g *gene.Gene) (gene.Transcript, error) {
+	start, _ := RefPosition(t, 0)
+	offset := start - g.Offset
+	var c gene.Transcript
+	if ct, ok := Coding(t); ok {
+		c = &gene.CodingTranscript{
+			ID: ct.ID, Loc: g, Offset: offset, Orient: ct.Orient, Desc: ct.Desc,
+			CDSstart: ct.CDSstart, CDSend: ct.CDSend,
+		}
+	} else if nt, ok := NonCoding(t); ok {
+		c = &gene.NonCodingTranscript{ID: nt.ID, Loc: g, Offset: offset, Orient: nt.Orient, Desc: nt.Desc}
+	} else {
+		return nil, fmt.Errorf("geneio: cannot copy transcript %s of type %T", t.Name(), t)
+	}
+	exons := make([]gene.Exon, len(t.Exons()))
+	for i, e := range t.Exons() {
+		exons[i] = gene.Exon{Transcript: c, Offset: e.Offset, Length: e.Length, Desc: e.Desc}
+	}
+	if err := c.SetExons(exons...); err != nil {
+		return nil, err
+	}
+	if ct, ok := c.(*gene.CodingTranscript); ok {
+		return CopyAnnotation(t, ct), nil
+	}
+	return TranscriptWithAttributes(c, copyAttributes(Attributes(t))), nil
+}
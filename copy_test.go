@@ -0,0 +1,59 @@
+package geneio
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/biogo/biogo/feat"
+)
+
+// Test that CopyGene keeps the structure, annotation and attributes of genes
+// and transcripts
+func TestCopyGene(t *testing.T) {
+	genes, err := NewBuilder().
+		Gene("S", Chrom("X"), feat.Forward).
+		Transcript("S1").Exon(0, 9).Exon(12, 24).CDS(0, 24).Selenocysteine(3).Tag("basic").
+		Transcript("S2").Exon(4, 30).
+		Build()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	g := genes[0]
+	ts := Transcripts(g)
+	ts[1] = TranscriptWithAttributes(ts[1], map[string]string{"transcript_name": "S-2"})
+	proto := WithAttributes(g, map[string]string{"gene_name": "SEL"})
+
+	c, err := CopyGene(proto, ts[1:])
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if got, want := describe(c), "S X:4-30 forward; S2 *geneio.AttributedTranscript 4-30;"; got != want {
+		t.Errorf("out gene=%q want %q", got, want)
+	}
+	if got, want := Attributes(c), map[string]string{"gene_name": "SEL"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("out gene attributes=%v want %v", got, want)
+	}
+	if got, want := Attributes(Transcripts(c)[0]), map[string]string{"transcript_name": "S-2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("out transcript attributes=%v want %v", got, want)
+	}
+
+	c, err = CopyGene(proto, ts)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if got, want := describe(c), "S X:0-30 forward; S1 *geneio.AnnotatedTranscript 0-9 12-24 cds 0-24; S2 *geneio.AttributedTranscript 4-30;"; got != want {
+		t.Errorf("out gene=%q want %q", got, want)
+	}
+	s1 := Transcripts(c)[0]
+	if got, want := ExceptionCodons(s1), ExceptionCodons(ts[0]); !reflect.DeepEqual(got, want) {
+		t.Errorf("out exception codons=%v want %v", got, want)
+	}
+	if !HasTag(s1, "basic") {
+		t.Errorf("out tagged=false want true")
+	}
+
+	_, err = CopyGene(g, nil)
+	if want := "geneio: no transcripts to copy for gene S"; err == nil || err.Error() != want {
+		t.Errorf("error %q, want error %q", err, want)
+	}
+}
@@ -0,0 +1,362 @@
+// Package counts assigns aligned reads to genes for expression
+// quantification.
+//
+// A Counter assigns each alignment to the genes whose exons it overlaps, in
+// the manner of featureCounts: the exons of all transcripts of a gene are
+// merged into one set of exonic regions, an alignment overlaps a gene if any
+// of its aligned blocks overlaps one of these regions, and alignments that
+// cannot be assigned to exactly one gene are left unassigned unless the
+// multi-overlap and multi-mapping policies allow them to be counted for all
+// of their genes. The two mates of a pair are counted once, as a fragment
+// covering the aligned blocks of both, and secondary and supplementary
+// alignments are not counted unless asked for.
+package counts
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/biogo/biogo/feat"
+	"github.com/biogo/biogo/feat/gene"
+	"github.com/go-bio/geneio"
+	"github.com/go-bio/geneio/align"
+	"github.com/go-bio/geneio/index"
+)
+
+// Strandedness is the relation between the strand of a read and the strand
+// of the gene it comes from.
+type Strandedness int
+
+// Strandedness values.
+const (
+	// Unstranded reads are counted for genes on either strand.
+	Unstranded Strandedness = iota
+	// Stranded reads are counted for genes on the strand of the read, or
+	// of its mate for second reads of pairs.
+	Stranded
+	// ReverseStranded reads are counted for genes on the strand opposite to
+	// that of Stranded reads.
+	ReverseStranded
+)
+
+// Summary holds the number of reads or fragments in each assignment
+// category. Skipped secondary and supplementary alignments are counted in
+// Secondary.
+type Summary struct {
+	Assigned     int
+	Unmapped     int
+	LowMapQ      int
+	MultiMapping int
+	NoFeatures   int
+	Ambiguity    int
+	Secondary    int
+}
+
+// Counter counts alignments for genes.
+type Counter struct {
+	idx   *index.Index
+	exons map[gene.Interface][][2]int
+	genes []gene.Interface
+
+	counts  map[gene.Interface]int
+	summary Summary
+
+	// pending holds the first mates of pairs whose other mate has not been
+	// added, keyed by read name.
+	pending map[string]*align.Record
+
+	// Strand is the strandedness of the library.
+	Strand Strandedness
+	// MultiOverlap specifies that alignments overlapping more than one gene
+	// are counted for each of them.
+	MultiOverlap bool
+	// MultiMapping specifies that the alignments of reads with more than
+	// one reported alignment are counted. Otherwise such reads are not
+	// counted. Secondary alignments are only counted if Secondary is set.
+	MultiMapping bool
+	// Secondary specifies that secondary and supplementary alignments are
+	// counted, each as a single read. Otherwise they are skipped.
+	Secondary bool
+	// MinMapQ is the smallest mapping quality of counted alignments.
+	MinMapQ int
+}
+
+// NewCounter returns a new Counter of alignments for genes. Genes without
+// transcripts are never assigned alignments.
+func NewCounter(genes []gene.Interface) *Counter {
+	c := &Counter{
+		idx:     index.New(genes...),
+		exons:   make(map[gene.Interface][][2]int),
+		genes:   genes,
+		counts:  make(map[gene.Interface]int),
+		pending: make(map[string]*align.Record),
+	}
+	for _, g := range genes {
+		cg, err := geneio.CollapseUnion(g)
+		if err != nil {
+			continue
+		}
+		for _, t := range geneio.Transcripts(cg) {
+			s, _ := geneio.RefPosition(t, 0)
+			for _, e := range t.Exons() {
+				c.exons[g] = append(c.exons[g], [2]int{s + e.Start(), s + e.End()})
+			}
+		}
+		sort.Slice(c.exons[g], func(i, j int) bool { return c.exons[g][i][0] < c.exons[g][j][0] })
+	}
+	return c
+}
+
+// Add assigns the alignment rec. The first mate of a pair with both mates
+// mapped is held until its other mate is added, and the pair is then
+// assigned as one fragment. A mate that is unmapped while its other mate is
+// mapped is not counted.
+func (c *Counter) Add(rec *align.Record) {
+	if rec.Flags&(align.Secondary|align.Supplementary) != 0 {
+		if !c.Secondary {
+			c.summary.Secondary++
+			return
+		}
+		c.assign(rec)
+		return
+	}
+	if rec.Flags&align.Paired == 0 || rec.Flags&(align.Unmapped|align.MateUnmapped) == align.MateUnmapped {
+		c.assign(rec)
+		return
+	}
+	if rec.Flags&align.Unmapped != 0 {
+		// A pair with neither mate mapped is counted with its first read.
+		if rec.Flags&align.MateUnmapped != 0 && rec.Flags&align.Read2 == 0 {
+			c.summary.Unmapped++
+		}
+		return
+	}
+	mate, ok := c.pending[rec.Name]
+	if !ok {
+		c.pending[rec.Name] = rec
+		return
+	}
+	delete(c.pending, rec.Name)
+	c.assign(mate, rec)
+}
+
+// Flush assigns the held mates of pairs whose other mate was not added as
+// single reads. Read calls Flush at the end of its input.
+func (c *Counter) Flush() {
+	names := make([]string, 0, len(c.pending))
+	for name := range c.pending {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		c.assign(c.pending[name])
+		delete(c.pending, name)
+	}
+}
+
+// assign assigns the fragment with the alignments recs. Blocks on another
+// reference sequence than that of the first alignment are ignored.
+func (c *Counter) assign(recs ...*align.Record) {
+	var blocks [][2]int
+	for _, rec := range recs {
+		switch {
+		case rec.Flags&align.Unmapped != 0 || rec.Ref == "":
+			c.summary.Unmapped++
+			return
+		case !c.MultiMapping && rec.NH > 1:
+			c.summary.MultiMapping++
+			return
+		case int(rec.MapQ) < c.MinMapQ:
+			c.summary.LowMapQ++
+			return
+		}
+		if rec.Ref == recs[0].Ref {
+			blocks = append(blocks, rec.Blocks()...)
+		}
+	}
+	if len(blocks) == 0 {
+		c.summary.Unmapped++
+		return
+	}
+	sort.Slice(blocks, func(i, j int) bool { return blocks[i][0] < blocks[j][0] })
+	start, end := blocks[0][0], blocks[0][1]
+	for _, b := range blocks {
+		if b[1] > end {
+			end = b[1]
+		}
+	}
+	var hits []gene.Interface
+	for _, g := range c.idx.Overlapping(recs[0].Ref, start, end) {
+		if c.strandMatch(recs[0], g) && overlaps(blocks, c.exons[g]) {
+			hits = append(hits, g)
+		}
+	}
+	switch {
+	case len(hits) == 0:
+		c.summary.NoFeatures++
+	case len(hits) > 1 && !c.MultiOverlap:
+		c.summary.Ambiguity++
+	default:
+		c.summary.Assigned++
+		for _, g := range hits {
+			c.counts[g]++
+		}
+	}
+}
+
+// strandMatch returns whether rec may come from g under the strandedness of
+// c.
+func (c *Counter) strandMatch(rec *align.Record, g gene.Interface) bool {
+	if c.Strand == Unstranded {
+		return true
+	}
+	reverse := rec.Flags&align.Reverse != 0
+	if rec.Flags&align.Paired != 0 && rec.Flags&align.Read2 != 0 {
+		reverse = !reverse
+	}
+	if c.Strand == ReverseStranded {
+		reverse = !reverse
+	}
+	switch geneio.RefOrientation(g) {
+	case feat.Forward:
+		return !reverse
+	case feat.Reverse:
+		return reverse
+	}
+	return true
+}
+
+// overlaps returns whether any of the sorted intervals a overlaps any of the
+// sorted intervals b.
+func overlaps(a, b [][2]int) bool {
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i][1] <= b[j][0]:
+			i++
+		case b[j][1] <= a[i][0]:
+			j++
+		default:
+			return true
+		}
+	}
+	return false
+}
+
+// Read adds all alignments from r and flushes the held mates.
+func (c *Counter) Read(r align.Reader) error {
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			c.Flush()
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		c.Add(rec)
+	}
+}
+
+// Count returns the number of reads or fragments assigned to g.
+func (c *Counter) Count(g gene.Interface) int { return c.counts[g] }
+
+// Summary returns the number of alignments in each assignment category.
+func (c *Counter) Summary() Summary { return c.summary }
+
+// Genes returns the genes of c in the order given to NewCounter.
+func (c *Counter) Genes() []gene.Interface {
+	return append([]gene.Interface(nil), c.genes...)
+}
+
+// Length returns the total length of the merged exons of g.
+func (c *Counter) Length(g gene.Interface) int {
+	n := 0
+	for _, e := range c.exons[g] {
+		n += e[1] - e[0]
+	}
+	return n
+}
+
+// WriteMatrix writes a tab separated count matrix to w with one row for each
+// gene of the first counter and one column for each counter, headed by
+// names. Each row holds the gene ID, the exonic length of the gene and its
+// counts. All counters must count the same genes.
+func WriteMatrix(w io.Writer, names []string, counters []*Counter) error {
+	if len(names) != len(counters) {
+		return fmt.Errorf("counts: %d names for %d counters", len(names), len(counters))
+	}
+	if len(counters) == 0 {
+		return nil
+	}
+	if _, err := fmt.Fprint(w, "gene_id\tlength"); err != nil {
+		return err
+	}
+	for _, n := range names {
+		if _, err := fmt.Fprint(w, "\t", n); err != nil {
+			return err
+		}
+	}
+	if _, err := fmt.Fprintln(w); err != nil {
+		return err
+	}
+	for _, g := range counters[0].genes {
+		if _, err := fmt.Fprintf(w, "%s\t%d", g.Name(), counters[0].Length(g)); err != nil {
+			return err
+		}
+		for _, c := range counters {
+			if _, err := fmt.Fprintf(w, "\t%d", c.counts[g]); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintln(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteSummary writes a tab separated table of the assignment summaries of
+// counters to w with one column for each counter, headed by names.
+func WriteSummary(w io.Writer, names []string, counters []*Counter) error {
+	if len(names) != len(counters) {
+		return fmt.Errorf("counts: %d names for %d counters", len(names), len(counters))
+	}
+	rows := []struct {
+		name string
+		n    func(Summary) int
+	}{
+		{"Assigned", func(s Summary) int { return s.Assigned }},
+		{"Unassigned_Unmapped", func(s Summary) int { return s.Unmapped }},
+		{"Unassigned_MappingQuality", func(s Summary) int { return s.LowMapQ }},
+		{"Unassigned_MultiMapping", func(s Summary) int { return s.MultiMapping }},
+		{"Unassigned_NoFeatures", func(s Summary) int { return s.NoFeatures }},
+		{"Unassigned_Ambiguity", func(s Summary) int { return s.Ambiguity }},
+		{"Unassigned_Secondary", func(s Summary) int { return s.Secondary }},
+	}
+	if _, err := fmt.Fprint(w, "status"); err != nil {
+		return err
+	}
+	for _, n := range names {
+		if _, err := fmt.Fprint(w, "\t", n); err != nil {
+			return err
+		}
+	}
+	if _, err := fmt.Fprintln(w); err != nil {
+		return err
+	}
+	for _, r := range rows {
+		if _, err := fmt.Fprint(w, r.name); err != nil {
+			return err
+		}
+		for _, c := range counters {
+			if _, err := fmt.Fprintf(w, "\t%d", r.n(c.summary)); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintln(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
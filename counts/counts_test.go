@@ -0,0 +1,201 @@
+package counts
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/go-bio/geneio/align"
+	"github.com/go-bio/geneio/internal/testgff"
+)
+
+// Gene A on the forward strand has exons at [10,20), [30,40) and [60,70),
+// gene B on the reverse strand an exon at [65,80).
+var countsInput = "" +
+	"X\t.\texon\t11\t20\t0\t+\t.\tgene_id A; transcript_id A1;\n" +
+	"X\t.\texon\t31\t40\t0\t+\t.\tgene_id A; transcript_id A1;\n" +
+	"X\t.\texon\t11\t20\t0\t+\t.\tgene_id A; transcript_id A2;\n" +
+	"X\t.\texon\t61\t70\t0\t+\t.\tgene_id A; transcript_id A2;\n" +
+	"X\t.\texon\t66\t80\t0\t-\t.\tgene_id B; transcript_id B1;\n"
+
+var samInput = "" +
+	"@SQ\tSN:X\tLN:1000\n" +
+	// Spliced read on A.
+	"r1\t0\tX\t16\t60\t5M10N5M\t*\t0\t0\t*\t*\n" +
+	// Intronic read of A.
+	"r2\t16\tX\t21\t60\t5M\t*\t0\t0\t*\t*\n" +
+	// Read on the overlap of A and B.
+	"r3\t16\tX\t66\t60\t5M\t*\t0\t0\t*\t*\n" +
+	// Read on B only.
+	"r4\t16\tX\t71\t60\t5M\t*\t0\t0\t*\t*\n" +
+	// Multi-mapping read on A.
+	"r5\t0\tX\t11\t60\t5M\t*\t0\t0\t*\t*\tNH:i:2\n" +
+	"r5\t256\tX\t31\t60\t5M\t*\t0\t0\t*\t*\tNH:i:2\n" +
+	// Low mapping quality read on A.
+	"r6\t0\tX\t11\t1\t5M\t*\t0\t0\t*\t*\n" +
+	// Unmapped read.
+	"r7\t4\t*\t0\t0\t*\t*\t0\t0\t*\t*\n" +
+	// Second read of a pair on the reverse strand, from A.
+	"r8\t145\tX\t36\t60\t5M\t*\t0\t0\t*\t*\n"
+
+// Test Counter
+func TestCounter(t *testing.T) {
+	genes := testgff.Genes(t, countsInput)
+	for _, test := range []struct {
+		name    string
+		strand  Strandedness
+		overlap bool
+		multi   bool
+		second  bool
+		counts  []int
+		summary Summary
+	}{
+		{
+			name:    "unstranded",
+			counts:  []int{2, 1},
+			summary: Summary{Assigned: 3, Unmapped: 1, LowMapQ: 1, MultiMapping: 1, NoFeatures: 1, Ambiguity: 1, Secondary: 1},
+		},
+		{
+			name:    "stranded",
+			strand:  Stranded,
+			counts:  []int{2, 2},
+			summary: Summary{Assigned: 4, Unmapped: 1, LowMapQ: 1, MultiMapping: 1, NoFeatures: 1, Secondary: 1},
+		},
+		{
+			name:    "reverse stranded",
+			strand:  ReverseStranded,
+			counts:  []int{1, 0},
+			summary: Summary{Assigned: 1, Unmapped: 1, LowMapQ: 1, MultiMapping: 1, NoFeatures: 4, Secondary: 1},
+		},
+		{
+			name:    "multi",
+			overlap: true,
+			multi:   true,
+			counts:  []int{4, 2},
+			summary: Summary{Assigned: 5, Unmapped: 1, LowMapQ: 1, NoFeatures: 1, Secondary: 1},
+		},
+		{
+			name:    "multi with secondary",
+			overlap: true,
+			multi:   true,
+			second:  true,
+			counts:  []int{5, 2},
+			summary: Summary{Assigned: 6, Unmapped: 1, LowMapQ: 1, NoFeatures: 1},
+		},
+	} {
+		c := NewCounter(genes)
+		c.Strand = test.strand
+		c.MultiOverlap = test.overlap
+		c.MultiMapping = test.multi
+		c.Secondary = test.second
+		c.MinMapQ = 10
+		if err := c.Read(align.NewSAMReader(strings.NewReader(samInput))); err != nil {
+			t.Fatalf("%s: unexpected error %v", test.name, err)
+		}
+		for i, g := range c.Genes() {
+			if got := c.Count(g); got != test.counts[i] {
+				t.Errorf("%s: out count %s=%d want %d", test.name, g.Name(), got, test.counts[i])
+			}
+		}
+		if got := c.Summary(); got != test.summary {
+			t.Errorf("%s: out summary=%+v want %+v", test.name, got, test.summary)
+		}
+	}
+}
+
+// Gene A holds the first mates of pairs p1 and p2 and the second mate of p1
+// in another exon, and p3 has an unmapped second mate. The alignments of p4
+// are secondary and supplementary.
+var pairedInput = "" +
+	"@SQ\tSN:X\tLN:1000\n" +
+	"p1\t99\tX\t11\t60\t5M\t=\t31\t25\t*\t*\n" +
+	"p2\t99\tX\t61\t60\t5M\t=\t71\t15\t*\t*\n" +
+	"p1\t147\tX\t31\t60\t5M\t=\t11\t-25\t*\t*\n" +
+	"p3\t73\tX\t16\t60\t5M\t=\t16\t0\t*\t*\n" +
+	"p3\t133\tX\t16\t0\t*\t=\t16\t0\t*\t*\n" +
+	"p4\t256\tX\t11\t60\t5M\t*\t0\t0\t*\t*\n" +
+	"p4\t2048\tX\t71\t60\t5M\t*\t0\t0\t*\t*\n" +
+	"p2\t147\tX\t71\t60\t5M\t=\t61\t-15\t*\t*\n"
+
+// Test that pairs are counted once per fragment
+func TestCounterPairs(t *testing.T) {
+	genes := testgff.Genes(t, countsInput)
+	c := NewCounter(genes)
+	c.Strand = Stranded
+	if err := c.Read(align.NewSAMReader(strings.NewReader(pairedInput))); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	// p2 spans exons of A and B, but its first mate only matches the strand
+	// of A.
+	for i, want := range []int{3, 0} {
+		if got := c.Count(c.Genes()[i]); got != want {
+			t.Errorf("out count %s=%d want %d", c.Genes()[i].Name(), got, want)
+		}
+	}
+	if got, want := c.Summary(), (Summary{Assigned: 3, Secondary: 2}); got != want {
+		t.Errorf("out summary=%+v want %+v", got, want)
+	}
+}
+
+// Test that secondary and supplementary alignments are only counted when
+// asked for
+func TestCounterSecondary(t *testing.T) {
+	genes := testgff.Genes(t, countsInput)
+	c := NewCounter(genes)
+	c.Secondary = true
+	for _, rec := range []*align.Record{
+		{Name: "p4", Ref: "X", Pos: 10, Flags: align.Secondary, MapQ: 60, Cigar: []align.CigarOp{{Op: 'M', Len: 5}}},
+		{Name: "p4", Ref: "X", Pos: 70, Flags: align.Supplementary, MapQ: 60, Cigar: []align.CigarOp{{Op: 'M', Len: 5}}},
+	} {
+		c.Add(rec)
+	}
+	c.Flush()
+	for i, want := range []int{1, 1} {
+		if got := c.Count(c.Genes()[i]); got != want {
+			t.Errorf("out count %s=%d want %d", c.Genes()[i].Name(), got, want)
+		}
+	}
+	if got, want := c.Summary(), (Summary{Assigned: 2}); got != want {
+		t.Errorf("out summary=%+v want %+v", got, want)
+	}
+}
+
+// Test WriteMatrix and WriteSummary
+func TestWrite(t *testing.T) {
+	genes := testgff.Genes(t, countsInput)
+	a, b := NewCounter(genes), NewCounter(genes)
+	b.Strand = Stranded
+	for _, c := range []*Counter{a, b} {
+		if err := c.Read(align.NewSAMReader(strings.NewReader(samInput))); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+	}
+	var buf bytes.Buffer
+	if err := WriteMatrix(&buf, []string{"a", "b"}, []*Counter{a, b}); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	want := "gene_id\tlength\ta\tb\nA\t30\t3\t3\nB\t15\t1\t2\n"
+	if buf.String() != want {
+		t.Errorf("out matrix=%q want %q", buf.String(), want)
+	}
+
+	buf.Reset()
+	if err := WriteSummary(&buf, []string{"a", "b"}, []*Counter{a, b}); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	want = "status\ta\tb\n" +
+		"Assigned\t4\t5\n" +
+		"Unassigned_Unmapped\t1\t1\n" +
+		"Unassigned_MappingQuality\t0\t0\n" +
+		"Unassigned_MultiMapping\t1\t1\n" +
+		"Unassigned_NoFeatures\t1\t1\n" +
+		"Unassigned_Ambiguity\t1\t0\n" +
+		"Unassigned_Secondary\t1\t1\n"
+	if buf.String() != want {
+		t.Errorf("out summary=%q want %q", buf.String(), want)
+	}
+
+	if err := WriteMatrix(&buf, []string{"a"}, []*Counter{a, b}); err == nil || err.Error() != "counts: 1 names for 2 counters" {
+		t.Errorf("error %q, want error %q", err, "counts: 1 names for 2 counters")
+	}
+}
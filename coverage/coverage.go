@@ -0,0 +1,298 @@
+// Package coverage computes read depth over the exons of gene models.
+//
+// A Track holds the per base depth of the exonic regions of a set of genes,
+// filled from alignments or from bedGraph intervals. Depth outside the exons
+// of the genes the Track was built for is not kept. Coverage summaries are
+// computed for genes, over the union of the exons of their transcripts, for
+// transcripts and for single exons, and gene body profiles give the mean
+// depth in bins along a spliced transcript from its 5' to its 3' end.
+package coverage
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/biogo/biogo/feat"
+	"github.com/biogo/biogo/feat/gene"
+	"github.com/go-bio/geneio"
+	"github.com/go-bio/geneio/align"
+)
+
+// region is a run of tracked positions with their depths.
+type region struct {
+	start int
+	depth []float64
+}
+
+func (r *region) end() int { return r.start + len(r.depth) }
+
+// Track holds the depth over the exons of genes.
+type Track struct {
+	regions map[string][]*region
+
+	// MinMapQ is the smallest mapping quality of alignments added by
+	// ReadAlignments.
+	MinMapQ int
+}
+
+// NewTrack returns a new Track with zero depth over the exons of genes.
+func NewTrack(genes []gene.Interface) *Track {
+	ivs := make(map[string][][2]int)
+	for _, g := range genes {
+		for _, t := range geneio.Transcripts(g) {
+			s, ref := geneio.RefPosition(t, 0)
+			for _, e := range t.Exons() {
+				ivs[ref.Name()] = append(ivs[ref.Name()], [2]int{s + e.Start(), s + e.End()})
+			}
+		}
+	}
+	tr := &Track{regions: make(map[string][]*region)}
+	for chrom, iv := range ivs {
+		sort.Slice(iv, func(i, j int) bool { return iv[i][0] < iv[j][0] })
+		var regs []*region
+		start, end := iv[0][0], iv[0][1]
+		for _, x := range iv[1:] {
+			if x[0] > end {
+				regs = append(regs, &region{start: start, depth: make([]float64, end-start)})
+				start, end = x[0], x[1]
+			} else if x[1] > end {
+				end = x[1]
+			}
+		}
+		tr.regions[chrom] = append(regs, &region{start: start, depth: make([]float64, end-start)})
+	}
+	return tr
+}
+
+// each calls fn for each tracked region of chrom overlapping [start, end)
+// with the overlapping part of the interval.
+func (t *Track) each(chrom string, start, end int, fn func(r *region, s, e int)) {
+	regs := t.regions[chrom]
+	i := sort.Search(len(regs), func(i int) bool { return regs[i].end() > start })
+	for ; i < len(regs) && regs[i].start < end; i++ {
+		s, e := start, end
+		if s < regs[i].start {
+			s = regs[i].start
+		}
+		if e > regs[i].end() {
+			e = regs[i].end()
+		}
+		fn(regs[i], s, e)
+	}
+}
+
+// Add adds v to the depth of the tracked positions in [start, end) of chrom.
+func (t *Track) Add(chrom string, start, end int, v float64) {
+	t.each(chrom, start, end, func(r *region, s, e int) {
+		for i := s - r.start; i < e-r.start; i++ {
+			r.depth[i] += v
+		}
+	})
+}
+
+// Depth returns the depth at pos of chrom, which is zero for positions that
+// are not tracked.
+func (t *Track) Depth(chrom string, pos int) float64 {
+	var d float64
+	t.each(chrom, pos, pos+1, func(r *region, s, _ int) { d = r.depth[s-r.start] })
+	return d
+}
+
+// AddAlignment adds one to the depth of the bases aligned by rec. Deleted
+// and skipped reference bases are not covered.
+func (t *Track) AddAlignment(rec *align.Record) {
+	pos := rec.Pos
+	for _, c := range rec.Cigar {
+		switch c.Op {
+		case 'M', '=', 'X':
+			t.Add(rec.Ref, pos, pos+c.Len, 1)
+			pos += c.Len
+		case 'D', 'N':
+			pos += c.Len
+		}
+	}
+}
+
+// ReadAlignments adds the alignments from r. Unmapped, secondary,
+// supplementary, duplicate and QC failed alignments and alignments with a
+// mapping quality below MinMapQ are skipped.
+func (t *Track) ReadAlignments(r align.Reader) error {
+	const skip = align.Unmapped | align.Secondary | align.Supplementary | align.Duplicate | align.QCFail
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if rec.Flags&skip != 0 || rec.Ref == "" || int(rec.MapQ) < t.MinMapQ {
+			continue
+		}
+		t.AddAlignment(rec)
+	}
+}
+
+// ReadBedGraph adds the intervals of the bedGraph data in r. Track, browser
+// and comment lines are skipped.
+func (t *Track) ReadBedGraph(r io.Reader) error {
+	sc := bufio.NewScanner(r)
+	line := 0
+	for sc.Scan() {
+		line++
+		text := strings.TrimSpace(sc.Text())
+		if text == "" || text[0] == '#' || strings.HasPrefix(text, "track") || strings.HasPrefix(text, "browser") {
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) < 4 {
+			return fmt.Errorf("coverage: line %d: too few bedGraph fields", line)
+		}
+		start, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return fmt.Errorf("coverage: line %d: bad start %q", line, fields[1])
+		}
+		end, err := strconv.Atoi(fields[2])
+		if err != nil || end < start {
+			return fmt.Errorf("coverage: line %d: bad end %q", line, fields[2])
+		}
+		v, err := strconv.ParseFloat(fields[3], 64)
+		if err != nil {
+			return fmt.Errorf("coverage: line %d: bad value %q", line, fields[3])
+		}
+		t.Add(fields[0], start, end, v)
+	}
+	return sc.Err()
+}
+
+// Stats summarises the depth over a set of positions.
+type Stats struct {
+	// Len is the number of positions.
+	Len int
+	// Covered is the number of positions with non zero depth.
+	Covered int
+	// Mean and Max are the mean and largest depth.
+	Mean, Max float64
+}
+
+// stats returns the Stats of the reference intervals ivs on chrom.
+func (t *Track) stats(chrom string, ivs [][2]int) Stats {
+	var (
+		s   Stats
+		sum float64
+	)
+	for _, iv := range ivs {
+		s.Len += iv[1] - iv[0]
+		t.each(chrom, iv[0], iv[1], func(r *region, b, e int) {
+			for _, d := range r.depth[b-r.start : e-r.start] {
+				if d != 0 {
+					s.Covered++
+				}
+				if d > s.Max {
+					s.Max = d
+				}
+				sum += d
+			}
+		})
+	}
+	if s.Len != 0 {
+		s.Mean = sum / float64(s.Len)
+	}
+	return s
+}
+
+// exons returns the exons of tr as reference intervals in 5' to 3' order,
+// and the name of the reference sequence.
+func exons(tr gene.Transcript) ([][2]int, string) {
+	s, ref := geneio.RefPosition(tr, 0)
+	es := tr.Exons()
+	ivs := make([][2]int, len(es))
+	for i, e := range es {
+		ivs[i] = [2]int{s + e.Start(), s + e.End()}
+	}
+	sort.Slice(ivs, func(i, j int) bool { return ivs[i][0] < ivs[j][0] })
+	if geneio.RefOrientation(tr) == feat.Reverse {
+		for i, j := 0, len(ivs)-1; i < j; i, j = i+1, j-1 {
+			ivs[i], ivs[j] = ivs[j], ivs[i]
+		}
+	}
+	return ivs, ref.Name()
+}
+
+// Gene returns the coverage of the union of the exons of the transcripts of
+// g. It returns an error if g has no transcripts.
+func (t *Track) Gene(g gene.Interface) (Stats, error) {
+	cg, err := geneio.CollapseUnion(g)
+	if err != nil {
+		return Stats{}, err
+	}
+	return t.Transcript(geneio.Transcripts(cg)[0]), nil
+}
+
+// GeneProfile returns the Profile of the union of the exons of the
+// transcripts of g.
+func (t *Track) GeneProfile(g gene.Interface, bins int) ([]float64, error) {
+	cg, err := geneio.CollapseUnion(g)
+	if err != nil {
+		return nil, err
+	}
+	return t.Profile(geneio.Transcripts(cg)[0], bins)
+}
+
+// Transcript returns the coverage of the exons of tr.
+func (t *Track) Transcript(tr gene.Transcript) Stats {
+	ivs, chrom := exons(tr)
+	return t.stats(chrom, ivs)
+}
+
+// Exons returns the coverage of each exon of tr in 5' to 3' order.
+func (t *Track) Exons(tr gene.Transcript) []Stats {
+	ivs, chrom := exons(tr)
+	s := make([]Stats, len(ivs))
+	for i, iv := range ivs {
+		s[i] = t.stats(chrom, [][2]int{iv})
+	}
+	return s
+}
+
+// Profile returns the mean depth in the given number of equal length bins along the
+// spliced transcript tr from its 5' to its 3' end. Bin boundaries are
+// rounded down, so bins differ in length by at most one base. It returns an
+// error if tr is shorter than bins.
+func (t *Track) Profile(tr gene.Transcript, bins int) ([]float64, error) {
+	ivs, chrom := exons(tr)
+	reverse := geneio.RefOrientation(tr) == feat.Reverse
+	var depth []float64
+	for _, iv := range ivs {
+		d := make([]float64, iv[1]-iv[0])
+		t.each(chrom, iv[0], iv[1], func(r *region, s, e int) {
+			copy(d[s-iv[0]:], r.depth[s-r.start:e-r.start])
+		})
+		if reverse {
+			for i, j := 0, len(d)-1; i < j; i, j = i+1, j-1 {
+				d[i], d[j] = d[j], d[i]
+			}
+		}
+		depth = append(depth, d...)
+	}
+	if bins < 1 {
+		return nil, fmt.Errorf("coverage: bad number of bins %d", bins)
+	}
+	if len(depth) < bins {
+		return nil, fmt.Errorf("coverage: transcript %s of length %d has fewer than %d bases", tr.Name(), len(depth), bins)
+	}
+	p := make([]float64, bins)
+	for i := range p {
+		s, e := i*len(depth)/bins, (i+1)*len(depth)/bins
+		var sum float64
+		for _, d := range depth[s:e] {
+			sum += d
+		}
+		p[i] = sum / float64(e-s)
+	}
+	return p, nil
+}
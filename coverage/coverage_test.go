@@ -0,0 +1,134 @@
+package coverage
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/biogo/biogo/feat/gene"
+	"github.com/go-bio/geneio"
+	"github.com/go-bio/geneio/align"
+	"github.com/go-bio/geneio/internal/testgff"
+)
+
+// Gene A on the forward strand has exons at [10,20) and [30,40), gene B on
+// the reverse strand exons at [50,55) and [60,65).
+var coverageInput = "" +
+	"X\t.\texon\t11\t20\t0\t+\t.\tgene_id A; transcript_id A1;\n" +
+	"X\t.\texon\t31\t40\t0\t+\t.\tgene_id A; transcript_id A1;\n" +
+	"X\t.\texon\t51\t55\t0\t-\t.\tgene_id B; transcript_id B1;\n" +
+	"X\t.\texon\t61\t65\t0\t-\t.\tgene_id B; transcript_id B1;\n"
+
+var samInput = "" +
+	"r1\t0\tX\t11\t60\t10M\t*\t0\t0\t*\t*\n" +
+	"r2\t0\tX\t16\t60\t5M10N2M1D2M\t*\t0\t0\t*\t*\n" +
+	"r3\t1024\tX\t31\t60\t5M\t*\t0\t0\t*\t*\n" +
+	"r4\t16\tX\t51\t60\t5M\t*\t0\t0\t*\t*\n" +
+	"r5\t16\tX\t61\t5\t5M\t*\t0\t0\t*\t*\n" +
+	"r6\t4\t*\t0\t0\t*\t*\t0\t0\t*\t*\n"
+
+// Test coverage from alignments
+func TestAlignments(t *testing.T) {
+	genes := testgff.Genes(t, coverageInput)
+	tr := NewTrack(genes)
+	tr.MinMapQ = 10
+	if err := tr.ReadAlignments(align.NewSAMReader(strings.NewReader(samInput))); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	a, b := geneio.Transcripts(genes[0])[0], geneio.Transcripts(genes[1])[0]
+
+	for _, test := range []struct {
+		pos  int
+		want float64
+	}{
+		{5, 0}, {10, 1}, {15, 2}, {30, 1}, {32, 0}, {33, 1}, {35, 0}, {52, 1}, {62, 0},
+	} {
+		if got := tr.Depth("X", test.pos); got != test.want {
+			t.Errorf("%d: out depth=%v want %v", test.pos, got, test.want)
+		}
+	}
+
+	want := Stats{Len: 20, Covered: 14, Mean: 19.0 / 20, Max: 2}
+	if got := tr.Transcript(a); got != want {
+		t.Errorf("out transcript=%+v want %+v", got, want)
+	}
+	if got, err := tr.Gene(genes[0]); err != nil || got != want {
+		t.Errorf("out gene=%+v, %v want %+v", got, err, want)
+	}
+	wantExons := []Stats{{Len: 5}, {Len: 5, Covered: 5, Mean: 1, Max: 1}}
+	if got := tr.Exons(b); !reflect.DeepEqual(got, wantExons) {
+		t.Errorf("out exons=%+v want %+v", got, wantExons)
+	}
+
+	for _, test := range []struct {
+		tr   gene.Transcript
+		bins int
+		want []float64
+		err  string
+	}{
+		{tr: a, bins: 4, want: []float64{1, 2, 0.8, 0}},
+		{tr: b, bins: 2, want: []float64{0, 1}},
+		{tr: b, bins: 5, want: []float64{0, 0, 0.5, 1, 1}},
+		{tr: b, bins: 3, want: []float64{0, 1.0 / 3, 1}},
+		{tr: b, bins: 11, err: "coverage: transcript B1 of length 10 has fewer than 11 bases"},
+		{tr: b, bins: 0, err: "coverage: bad number of bins 0"},
+	} {
+		got, err := tr.Profile(test.tr, test.bins)
+		if test.err != "" {
+			if err == nil || err.Error() != test.err {
+				t.Errorf("%s/%d: error %q, want error %q", test.tr.Name(), test.bins, err, test.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s/%d: unexpected error %v", test.tr.Name(), test.bins, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s/%d: out profile=%v want %v", test.tr.Name(), test.bins, got, test.want)
+		}
+	}
+	if got, err := tr.GeneProfile(genes[1], 2); err != nil || !reflect.DeepEqual(got, []float64{0, 1}) {
+		t.Errorf("out gene profile=%v, %v want %v", got, err, []float64{0, 1})
+	}
+}
+
+// Test ReadBedGraph
+func TestReadBedGraph(t *testing.T) {
+	tr := NewTrack(testgff.Genes(t, coverageInput))
+	in := "track type=bedGraph\n" +
+		"# comment\n" +
+		"X\t0\t12\t2.5\n" +
+		"X\t11\t31\t1\n" +
+		"Y\t0\t100\t7\n"
+	if err := tr.ReadBedGraph(strings.NewReader(in)); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	for _, test := range []struct {
+		pos  int
+		want float64
+	}{
+		{5, 0}, {10, 2.5}, {11, 3.5}, {12, 1}, {25, 0}, {30, 1}, {31, 0},
+	} {
+		if got := tr.Depth("X", test.pos); got != test.want {
+			t.Errorf("%d: out depth=%v want %v", test.pos, got, test.want)
+		}
+	}
+	if got := tr.Depth("Y", 5); got != 0 {
+		t.Errorf("out untracked depth=%v want 0", got)
+	}
+
+	for _, test := range []struct {
+		in, err string
+	}{
+		{"X\t0\t12\n", "coverage: line 1: too few bedGraph fields"},
+		{"X\t0\tx\t1\n", "coverage: line 1: bad end \"x\""},
+		{"X\t5\t1\t1\n", "coverage: line 1: bad end \"1\""},
+		{"X\t0\t12\tmany\n", "coverage: line 1: bad value \"many\""},
+	} {
+		err := NewTrack(nil).ReadBedGraph(strings.NewReader(test.in))
+		if err == nil || err.Error() != test.err {
+			t.Errorf("%q: error %q, want error %q", test.in, err, test.err)
+		}
+	}
+}
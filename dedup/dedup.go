@@ -0,0 +1,283 @@
+// Package dedup merges duplicated genes.
+//
+// GeneReader creates separate genes for non consecutive features with the
+// same gene ID and annotations combined from several sources repeat genes. A
+// Reader collects the genes of an underlying geneio.Reader, merges genes that
+// share an ID into one gene when they are on the same sequence and strand
+// and overlap or lie close together, and optionally drops genes with the
+// same structure as an earlier gene. Everything it does is recorded as a
+// Report; inconsistencies that prevent a merge are reported as conflicts
+// instead of silently keeping both genes.
+package dedup
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/biogo/biogo/feat/gene"
+	"github.com/go-bio/geneio"
+)
+
+// Kind is the kind of a Report.
+type Kind int
+
+// Kinds of reports.
+const (
+	// Merged means genes with the same ID were merged.
+	Merged Kind = iota
+	// Duplicate means a gene or transcript was identical to an earlier one
+	// and was dropped.
+	Duplicate
+	// LocationConflict means genes with the same ID are on different
+	// sequences.
+	LocationConflict
+	// StrandConflict means genes with the same ID are on different strands.
+	StrandConflict
+	// TranscriptConflict means transcripts with the same ID in genes with the
+	// same ID have different structures.
+	TranscriptConflict
+	// PositionConflict means genes with the same ID on the same sequence and
+	// strand are further apart than the MaxGap of the Reader.
+	PositionConflict
+)
+
+var kindNames = [...]string{
+	Merged:             "merged",
+	Duplicate:          "duplicate",
+	LocationConflict:   "location conflict",
+	StrandConflict:     "strand conflict",
+	TranscriptConflict: "transcript conflict",
+	PositionConflict:   "position conflict",
+}
+
+// String returns the name of k.
+func (k Kind) String() string {
+	if k < 0 || int(k) >= len(kindNames) {
+		return "unknown"
+	}
+	return kindNames[k]
+}
+
+// Report describes a merge, a dropped duplicate or a conflict. For
+// conflicts, the conflicting gene or transcript was dropped and the first
+// one kept.
+type Report struct {
+	Kind Kind
+	GID  string
+	// TID is the transcript concerned, if any.
+	TID string
+	// Msg gives details.
+	Msg string
+}
+
+// Conflict returns whether r reports a conflict.
+func (r *Report) Conflict() bool {
+	return r.Kind >= LocationConflict
+}
+
+// Error returns a description of r.
+func (r *Report) Error() string {
+	if r.TID != "" {
+		return fmt.Sprintf("dedup: %s for transcript %s of gene %s: %s", r.Kind, r.TID, r.GID, r.Msg)
+	}
+	return fmt.Sprintf("dedup: %s for gene %s: %s", r.Kind, r.GID, r.Msg)
+}
+
+// Reader is a geneio.Reader that deduplicates the genes of an underlying
+// Reader. It reads the whole underlying stream on the first call to Read and
+// returns genes in the order of the first appearance of their ID.
+type Reader struct {
+	r geneio.Reader
+
+	// ByStructure makes the Reader also drop genes whose transcripts have
+	// the same structure as those of an earlier gene on the same sequence
+	// and strand, regardless of IDs.
+	ByStructure bool
+	// Strict makes Read return the first conflict as an error.
+	Strict bool
+	// MaxGap is the largest distance between genes with the same ID that
+	// are merged. Genes further apart are reported as position conflicts.
+	// The default of zero merges only genes that overlap or touch.
+	MaxGap int
+
+	genes   []gene.Interface
+	reports []*Report
+	done    bool
+}
+
+// NewReader returns a new Reader that deduplicates the genes of r.
+func NewReader(r geneio.Reader) *Reader {
+	return &Reader{r: r}
+}
+
+// Read returns the next deduplicated gene. At EOF, it returns nil and
+// io.EOF.
+func (r *Reader) Read() (gene.Interface, error) {
+	if !r.done {
+		r.done = true
+		if err := r.collect(); err != nil {
+			return nil, err
+		}
+		if r.Strict {
+			for _, rep := range r.reports {
+				if rep.Conflict() {
+					r.genes = nil
+					return nil, rep
+				}
+			}
+		}
+	}
+	if len(r.genes) == 0 {
+		return nil, io.EOF
+	}
+	g := r.genes[0]
+	r.genes = r.genes[1:]
+	return g, nil
+}
+
+// Reports returns the reports of all genes read. Reports are available
+// after the first call to Read.
+func (r *Reader) Reports() []*Report {
+	return r.reports
+}
+
+// collect reads and deduplicates all genes of the underlying Reader.
+func (r *Reader) collect() error {
+	var order []string
+	groups := make(map[string][]gene.Interface)
+	sc := geneio.NewScanner(r.r)
+	for sc.Next() {
+		g := sc.Gene()
+		if _, ok := groups[g.Name()]; !ok {
+			order = append(order, g.Name())
+		}
+		groups[g.Name()] = append(groups[g.Name()], g)
+	}
+	if err := sc.Error(); err != nil {
+		return err
+	}
+
+	seen := make(map[string]string)
+	for _, id := range order {
+		g, err := r.merge(groups[id])
+		if err != nil {
+			return err
+		}
+		if r.ByStructure {
+			key := geneKey(g)
+			if first, ok := seen[key]; ok {
+				r.reports = append(r.reports, &Report{
+					Kind: Duplicate, GID: id,
+					Msg: "same structure as gene " + first,
+				})
+				continue
+			}
+			seen[key] = id
+		}
+		r.genes = append(r.genes, g)
+	}
+	return nil
+}
+
+// merge merges the genes gs that share an ID into one gene.
+func (r *Reader) merge(gs []gene.Interface) (gene.Interface, error) {
+	first := gs[0]
+	if len(gs) == 1 {
+		return first, nil
+	}
+	chrom := first.Location().Name()
+	strand := geneio.RefOrientation(first)
+	start, _ := geneio.RefPosition(first, 0)
+	end := start + first.Len()
+
+	var ts []gene.Transcript
+	keys := make(map[string]string)
+	merged := 0
+	for i, g := range gs {
+		if i > 0 {
+			gStart, _ := geneio.RefPosition(g, 0)
+			gEnd := gStart + g.Len()
+			switch {
+			case g.Location().Name() != chrom:
+				r.reports = append(r.reports, &Report{
+					Kind: LocationConflict, GID: g.Name(),
+					Msg: fmt.Sprintf("on %s and %s", chrom, g.Location().Name()),
+				})
+				continue
+			case geneio.RefOrientation(g) != strand:
+				r.reports = append(r.reports, &Report{
+					Kind: StrandConflict, GID: g.Name(),
+					Msg: fmt.Sprintf("on strands %s and %s", strand, geneio.RefOrientation(g)),
+				})
+				continue
+			case gStart-end > r.MaxGap || start-gEnd > r.MaxGap:
+				r.reports = append(r.reports, &Report{
+					Kind: PositionConflict, GID: g.Name(),
+					Msg: fmt.Sprintf("at %d-%d and %d-%d on %s", start, end, gStart, gEnd, chrom),
+				})
+				continue
+			}
+			if gStart < start {
+				start = gStart
+			}
+			if gEnd > end {
+				end = gEnd
+			}
+		}
+		merged++
+		for _, t := range geneio.Transcripts(g) {
+			key := transcriptKey(t)
+			if k, ok := keys[t.Name()]; ok {
+				rep := &Report{Kind: Duplicate, GID: g.Name(), TID: t.Name(), Msg: "identical transcript"}
+				if k != key {
+					rep.Kind, rep.Msg = TranscriptConflict, "different structures"
+				}
+				r.reports = append(r.reports, rep)
+				continue
+			}
+			keys[t.Name()] = key
+			ts = append(ts, t)
+		}
+	}
+	if merged == 1 {
+		return first, nil
+	}
+
+	g, err := geneio.CopyGene(first, ts)
+	if err != nil {
+		return nil, err
+	}
+	r.reports = append(r.reports, &Report{
+		Kind: Merged, GID: first.Name(),
+		Msg: fmt.Sprintf("%d genes with %d transcripts", merged, len(ts)),
+	})
+	return g, nil
+}
+
+// transcriptKey returns a key that is equal for transcripts with the same
+// structure in reference coordinates.
+func transcriptKey(t gene.Transcript) string {
+	var b strings.Builder
+	s, _ := geneio.RefPosition(t, 0)
+	for _, e := range t.Exons() {
+		fmt.Fprintf(&b, "%d-%d,", s+e.Start(), s+e.End())
+	}
+	if ct, ok := geneio.Coding(t); ok {
+		fmt.Fprintf(&b, "cds%d-%d", s+ct.CDSstart, s+ct.CDSend)
+	}
+	return b.String()
+}
+
+// geneKey returns a key that is equal for genes with the same location,
+// strand and transcript structures.
+func geneKey(g gene.Interface) string {
+	ts := geneio.Transcripts(g)
+	keys := make([]string, len(ts))
+	for i, t := range ts {
+		keys[i] = transcriptKey(t)
+	}
+	sort.Strings(keys)
+	return fmt.Sprintf("%s:%d:%s", g.Location().Name(), geneio.RefOrientation(g), strings.Join(keys, ";"))
+}
@@ -0,0 +1,153 @@
+package dedup
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/biogo/biogo/io/featio/gff"
+	"github.com/go-bio/geneio"
+	ggff "github.com/go-bio/geneio/gff"
+)
+
+// Assert that interfaces are satisfied.
+var (
+	_ geneio.Reader = (*Reader)(nil)
+	_ error         = (*Report)(nil)
+)
+
+var dedupInput = "" +
+	"X\t.\texon\t11\t20\t0\t+\t.\tgene_id A; transcript_id A1;\n" +
+	"X\t.\texon\t31\t40\t0\t+\t.\tgene_id A; transcript_id A1;\n" +
+	"X\t.\texon\t101\t120\t0\t+\t.\tgene_id B; transcript_id B1;\n" +
+	"X\t.\texon\t5\t20\t0\t+\t.\tgene_id A; transcript_id A2;\n" +
+	"X\t.\texon\t11\t20\t0\t+\t.\tgene_id A; transcript_id A1;\n" +
+	"X\t.\texon\t31\t40\t0\t+\t.\tgene_id A; transcript_id A1;\n" +
+	"X\t.\texon\t101\t120\t0\t+\t.\tgene_id C; transcript_id C1;\n" +
+	"X\t.\texon\t11\t40\t0\t+\t.\tgene_id A; transcript_id A1;\n" +
+	"X\t.\texon\t201\t210\t0\t+\t.\tgene_id D; transcript_id D1;\n" +
+	"Y\t.\texon\t11\t40\t0\t+\t.\tgene_id A; transcript_id A3;\n" +
+	"X\t.\texon\t301\t310\t0\t+\t.\tgene_id E; transcript_id E1;\n" +
+	"X\t.\texon\t11\t40\t0\t-\t.\tgene_id A; transcript_id A4;\n" +
+	"Y\t.\texon\t301\t310\t0\t+\t.\tgene_id E; transcript_id E2;\n" +
+	"X\t.\texon\t1001\t1010\t0\t+\t.\tgene_id A; transcript_id A5;\n"
+
+type report struct {
+	Kind Kind
+	GID  string
+	TID  string
+}
+
+// Test Reader
+var readerTests = []struct {
+	Name        string
+	ByStructure bool
+	Strict      bool
+	MaxGap      int
+	IDs         []string
+	Reports     []report
+	Error       string
+}{
+	{
+		Name: "By ID",
+		IDs:  []string{"A", "B", "C", "D", "E"},
+		Reports: []report{
+			{Duplicate, "A", "A1"},
+			{TranscriptConflict, "A", "A1"},
+			{LocationConflict, "A", ""},
+			{StrandConflict, "A", ""},
+			{PositionConflict, "A", ""},
+			{Merged, "A", ""},
+			{LocationConflict, "E", ""},
+		},
+	},
+	{
+		Name:        "By structure",
+		ByStructure: true,
+		IDs:         []string{"A", "B", "D", "E"},
+		Reports: []report{
+			{Duplicate, "A", "A1"},
+			{TranscriptConflict, "A", "A1"},
+			{LocationConflict, "A", ""},
+			{StrandConflict, "A", ""},
+			{PositionConflict, "A", ""},
+			{Merged, "A", ""},
+			{Duplicate, "C", ""},
+			{LocationConflict, "E", ""},
+		},
+	},
+	{
+		Name:   "Large gap",
+		MaxGap: 1000,
+		IDs:    []string{"A", "B", "C", "D", "E"},
+		Reports: []report{
+			{Duplicate, "A", "A1"},
+			{TranscriptConflict, "A", "A1"},
+			{LocationConflict, "A", ""},
+			{StrandConflict, "A", ""},
+			{Merged, "A", ""},
+			{LocationConflict, "E", ""},
+		},
+	},
+	{
+		Name:   "Strict",
+		Strict: true,
+		Error:  "dedup: transcript conflict for transcript A1 of gene A: different structures",
+	},
+}
+
+func TestReader(t *testing.T) {
+	for _, tt := range readerTests {
+		r := NewReader(ggff.NewReader(gff.NewReader(strings.NewReader(dedupInput))))
+		r.ByStructure = tt.ByStructure
+		r.Strict = tt.Strict
+		r.MaxGap = tt.MaxGap
+		var ids []string
+		sc := geneio.NewScanner(r)
+		for sc.Next() {
+			ids = append(ids, sc.Gene().Name())
+		}
+		err := sc.Error()
+		if tt.Error != "" {
+			if err == nil || err.Error() != tt.Error {
+				t.Errorf("%s: error %q, want error %q", tt.Name, err, tt.Error)
+			}
+			continue
+		} else if err != nil {
+			t.Errorf("%s: unexpected error %v", tt.Name, err)
+			continue
+		}
+		if !reflect.DeepEqual(ids, tt.IDs) {
+			t.Errorf("%s: out ids=%q want %q", tt.Name, ids, tt.IDs)
+		}
+		var reports []report
+		for _, rep := range r.Reports() {
+			reports = append(reports, report{rep.Kind, rep.GID, rep.TID})
+		}
+		if !reflect.DeepEqual(reports, tt.Reports) {
+			t.Errorf("%s: out reports=%v want %v", tt.Name, reports, tt.Reports)
+		}
+	}
+}
+
+func TestMerged(t *testing.T) {
+	r := NewReader(ggff.NewReader(gff.NewReader(strings.NewReader(dedupInput))))
+	g, err := r.Read()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if g.Start() != 4 || g.End() != 40 {
+		t.Errorf("out location=[%d,%d) want [4,40)", g.Start(), g.End())
+	}
+	want := map[string]string{
+		"A1": "10-20,30-40,",
+		"A2": "4-20,",
+	}
+	got := make(map[string]string)
+	for _, tr := range geneio.Transcripts(g) {
+		got[tr.Name()] = transcriptKey(tr)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("out transcripts=%v want %v", got, want)
+	}
+}
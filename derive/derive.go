@@ -0,0 +1,269 @@
+// Package derive derives features such as introns, splice sites and UTRs
+// from the exon structure of transcripts.
+//
+// Derived features are located on the transcript they are derived from and
+// their coordinates are relative to it, as for gene.Exon. Features are
+// returned in the order of the transcripts of the gene and, within a
+// transcript, in increasing coordinate order.
+package derive
+
+import (
+	"strings"
+
+	"github.com/biogo/biogo/feat"
+	"github.com/biogo/biogo/feat/gene"
+	"github.com/go-bio/geneio"
+)
+
+// Kind is the kind of a derived feature.
+type Kind int
+
+// Kinds of derived features.
+const (
+	Intron Kind = iota
+	Donor
+	Acceptor
+	Junction
+	UTR5
+	UTR3
+	TSS
+	TES
+)
+
+var kindNames = [...]string{
+	Intron:   "intron",
+	Donor:    "splice_donor",
+	Acceptor: "splice_acceptor",
+	Junction: "splice_junction",
+	UTR5:     "five_prime_UTR",
+	UTR3:     "three_prime_UTR",
+	TSS:      "TSS",
+	TES:      "TES",
+}
+
+// String returns the sequence ontology style name of k.
+func (k Kind) String() string {
+	if k < 0 || int(k) >= len(kindNames) {
+		return "unknown"
+	}
+	return kindNames[k]
+}
+
+// Feature is a feature derived from a transcript. It implements feat.Feature
+// and feat.Orienter with the transcript as its location.
+type Feature struct {
+	Kind       Kind
+	Transcript gene.Transcript
+	Offset     int
+	Length     int
+}
+
+// Start returns the start position of f relative to its transcript.
+func (f *Feature) Start() int { return f.Offset }
+
+// End returns the end position of f relative to its transcript.
+func (f *Feature) End() int { return f.Offset + f.Length }
+
+// Len returns the length of f.
+func (f *Feature) Len() int { return f.Length }
+
+// Name returns the transcript name and the kind of f.
+func (f *Feature) Name() string { return f.Transcript.Name() + ":" + f.Kind.String() }
+
+// Description returns the kind of f.
+func (f *Feature) Description() string { return f.Kind.String() }
+
+// Location returns the transcript of f.
+func (f *Feature) Location() feat.Feature { return f.Transcript }
+
+// Orientation returns feat.Forward; derived features always follow the
+// orientation of their transcript.
+func (f *Feature) Orientation() feat.Orientation { return feat.Forward }
+
+// JunctionFeature is a splice junction. Its coordinates are those of the
+// intron it spans.
+type JunctionFeature struct {
+	Feature
+	// Motif is the donor and acceptor dinucleotides in transcript
+	// orientation joined by a dash, e.g. "GT-AG". It is empty if no sequence
+	// was available.
+	Motif string
+}
+
+// canonicalMotifs are the splice motifs considered canonical.
+var canonicalMotifs = map[string]bool{
+	"GT-AG": true,
+	"GC-AG": true,
+	"AT-AC": true,
+}
+
+// Canonical returns whether the motif of j is one of GT-AG, GC-AG or AT-AC.
+func (j *JunctionFeature) Canonical() bool {
+	return canonicalMotifs[j.Motif]
+}
+
+// Introns returns the introns of all transcripts of g.
+func Introns(g gene.Interface) []*Feature {
+	var fs []*Feature
+	for _, t := range geneio.Transcripts(g) {
+		fs = append(fs, introns(t)...)
+	}
+	return fs
+}
+
+// DonorSites returns the two base donor sites at the 5' end of each intron
+// of all transcripts of g.
+func DonorSites(g gene.Interface) []*Feature {
+	return sites(g, Donor)
+}
+
+// AcceptorSites returns the two base acceptor sites at the 3' end of each
+// intron of all transcripts of g.
+func AcceptorSites(g gene.Interface) []*Feature {
+	return sites(g, Acceptor)
+}
+
+// sites returns the donor or acceptor sites of g depending on k.
+func sites(g gene.Interface, k Kind) []*Feature {
+	var fs []*Feature
+	for _, t := range geneio.Transcripts(g) {
+		rev := geneio.RefOrientation(t) == feat.Reverse
+		for _, in := range introns(t) {
+			s := &Feature{Kind: k, Transcript: t, Length: 2}
+			if (k == Donor) != rev {
+				s.Offset = in.Start()
+			} else {
+				s.Offset = in.End() - 2
+			}
+			fs = append(fs, s)
+		}
+	}
+	return fs
+}
+
+// Junctions returns the splice junctions of all transcripts of g. If s is
+// not nil, the splice motif of each junction is read from s. It returns nil
+// and an error if s fails.
+func Junctions(g gene.Interface, s geneio.Sequencer) ([]*JunctionFeature, error) {
+	var js []*JunctionFeature
+	for _, t := range geneio.Transcripts(g) {
+		rev := geneio.RefOrientation(t) == feat.Reverse
+		for _, in := range introns(t) {
+			j := &JunctionFeature{Feature: *in}
+			j.Kind = Junction
+			if s != nil {
+				m, err := motif(s, in, rev)
+				if err != nil {
+					return nil, err
+				}
+				j.Motif = m
+			}
+			js = append(js, j)
+		}
+	}
+	return js, nil
+}
+
+// motif reads the splice motif of intron in from s.
+func motif(s geneio.Sequencer, in *Feature, rev bool) (string, error) {
+	start, ref := geneio.RefPosition(in.Transcript, in.Start())
+	end := start + in.Len()
+	if in.Len() < 4 {
+		return "", nil
+	}
+	five, err := s.Seq(ref.Name(), start, start+2)
+	if err != nil {
+		return "", err
+	}
+	three, err := s.Seq(ref.Name(), end-2, end)
+	if err != nil {
+		return "", err
+	}
+	if rev {
+		five, three = geneio.ReverseComplement(three), geneio.ReverseComplement(five)
+	}
+	return strings.ToUpper(string(five) + "-" + string(three)), nil
+}
+
+// UTR5s returns the 5' untranslated exonic segments of all coding
+// transcripts of g.
+func UTR5s(g gene.Interface) []*Feature {
+	return utrs(g, UTR5)
+}
+
+// UTR3s returns the 3' untranslated exonic segments of all coding
+// transcripts of g.
+func UTR3s(g gene.Interface) []*Feature {
+	return utrs(g, UTR3)
+}
+
+// utrs returns the 5' or 3' UTR segments of g depending on k.
+func utrs(g gene.Interface, k Kind) []*Feature {
+	var fs []*Feature
+	for _, t := range geneio.Transcripts(g) {
+		ct, ok := geneio.Coding(t)
+		if !ok {
+			continue
+		}
+		rev := geneio.RefOrientation(t) == feat.Reverse
+		upstream := (k == UTR5) != rev
+		for _, e := range ct.Exons() {
+			start, end := e.Start(), e.End()
+			if upstream {
+				if end > ct.CDSstart {
+					end = ct.CDSstart
+				}
+			} else if start < ct.CDSend {
+				start = ct.CDSend
+			}
+			if start < end {
+				fs = append(fs, &Feature{Kind: k, Transcript: t, Offset: start, Length: end - start})
+			}
+		}
+	}
+	return fs
+}
+
+// TSSs returns the one base transcription start site of all transcripts of
+// g.
+func TSSs(g gene.Interface) []*Feature {
+	return ends(g, TSS)
+}
+
+// TESs returns the one base transcription end site of all transcripts of g.
+func TESs(g gene.Interface) []*Feature {
+	return ends(g, TES)
+}
+
+// ends returns the start or end sites of the transcripts of g depending on k.
+func ends(g gene.Interface, k Kind) []*Feature {
+	var fs []*Feature
+	for _, t := range geneio.Transcripts(g) {
+		exons := t.Exons()
+		if len(exons) == 0 {
+			continue
+		}
+		rev := geneio.RefOrientation(t) == feat.Reverse
+		f := &Feature{Kind: k, Transcript: t, Length: 1}
+		if (k == TSS) != rev {
+			f.Offset = exons[0].Start()
+		} else {
+			f.Offset = exons[len(exons)-1].End() - 1
+		}
+		fs = append(fs, f)
+	}
+	return fs
+}
+
+// introns returns the introns of t.
+func introns(t gene.Transcript) []*Feature {
+	var fs []*Feature
+	exons := t.Exons()
+	for i := 1; i < len(exons); i++ {
+		start, end := exons[i-1].End(), exons[i].Start()
+		if start < end {
+			fs = append(fs, &Feature{Kind: Intron, Transcript: t, Offset: start, Length: end - start})
+		}
+	}
+	return fs
+}
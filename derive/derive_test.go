@@ -0,0 +1,128 @@
+package derive
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/biogo/biogo/feat/gene"
+	"github.com/biogo/biogo/io/featio/gff"
+	"github.com/go-bio/geneio"
+	ggff "github.com/go-bio/geneio/gff"
+)
+
+// readGene reads the first gene from the GFF v2 input.
+func readGene(t *testing.T, input string) gene.Interface {
+	g, err := ggff.NewReader(gff.NewReader(strings.NewReader(input))).Read()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	return g
+}
+
+// refCoords returns the reference coordinates of fs.
+func refCoords(fs []*Feature) [][2]int {
+	var cs [][2]int
+	for _, f := range fs {
+		s, _ := geneio.RefPosition(f.Transcript, f.Start())
+		cs = append(cs, [2]int{s, s + f.Len()})
+	}
+	return cs
+}
+
+var forwardGene = "" +
+	"X\t.\texon\t3\t10\t0\t+\t.\tgene_id A; transcript_id A1;\n" +
+	"X\t.\texon\t21\t30\t0\t+\t.\tgene_id A; transcript_id A1;\n" +
+	"X\t.\texon\t41\t50\t0\t+\t.\tgene_id A; transcript_id A1;\n" +
+	"X\t.\tstart_codon\t6\t8\t0\t+\t.\tgene_id A; transcript_id A1;\n" +
+	"X\t.\tstop_codon\t43\t45\t0\t+\t.\tgene_id A; transcript_id A1;\n"
+
+var reverseGene = "" +
+	"X\t.\texon\t3\t10\t0\t-\t.\tgene_id B; transcript_id B1;\n" +
+	"X\t.\texon\t21\t30\t0\t-\t.\tgene_id B; transcript_id B1;\n" +
+	"X\t.\tstop_codon\t6\t8\t0\t-\t.\tgene_id B; transcript_id B1;\n" +
+	"X\t.\tstart_codon\t25\t27\t0\t-\t.\tgene_id B; transcript_id B1;\n"
+
+// Test derived features
+var deriveTests = []struct {
+	Name  string
+	Input string
+	Fn    func(gene.Interface) []*Feature
+	Want  [][2]int
+}{
+	{"forward introns", forwardGene, Introns, [][2]int{{10, 20}, {30, 40}}},
+	{"forward donors", forwardGene, DonorSites, [][2]int{{10, 12}, {30, 32}}},
+	{"forward acceptors", forwardGene, AcceptorSites, [][2]int{{18, 20}, {38, 40}}},
+	{"forward 5' UTR", forwardGene, UTR5s, [][2]int{{2, 5}}},
+	{"forward 3' UTR", forwardGene, UTR3s, [][2]int{{45, 50}}},
+	{"forward TSS", forwardGene, TSSs, [][2]int{{2, 3}}},
+	{"forward TES", forwardGene, TESs, [][2]int{{49, 50}}},
+	{"reverse donors", reverseGene, DonorSites, [][2]int{{18, 20}}},
+	{"reverse acceptors", reverseGene, AcceptorSites, [][2]int{{10, 12}}},
+	{"reverse 5' UTR", reverseGene, UTR5s, [][2]int{{27, 30}}},
+	{"reverse 3' UTR", reverseGene, UTR3s, [][2]int{{2, 5}}},
+	{"reverse TSS", reverseGene, TSSs, [][2]int{{29, 30}}},
+	{"reverse TES", reverseGene, TESs, [][2]int{{2, 3}}},
+}
+
+func TestDerive(t *testing.T) {
+	for _, tt := range deriveTests {
+		g := readGene(t, tt.Input)
+		fs := tt.Fn(g)
+		for _, f := range fs {
+			if f.Location() != g.Features()[0] {
+				t.Errorf("%s: feature not located on its transcript", tt.Name)
+			}
+		}
+		if got := refCoords(fs); !reflect.DeepEqual(got, tt.Want) {
+			t.Errorf("%s: out coords=%v want %v", tt.Name, got, tt.Want)
+		}
+	}
+}
+
+// Test Junctions
+var junctionTests = []struct {
+	Name      string
+	Input     string
+	Seq       string
+	Motifs    []string
+	Canonical []bool
+}{
+	{
+		Name:      "forward",
+		Input:     forwardGene,
+		Seq:       "AAAAAAAAAAGTAAAAAAAGAAAAAAAAAACTAAAAAACGAAAAAAAAAA",
+		Motifs:    []string{"GT-AG", "CT-CG"},
+		Canonical: []bool{true, false},
+	},
+	{
+		Name:      "reverse",
+		Input:     reverseGene,
+		Seq:       "AAAAAAAAAACTAAAAAAACAAAAAAAAAA",
+		Motifs:    []string{"GT-AG"},
+		Canonical: []bool{true},
+	},
+}
+
+func TestJunctions(t *testing.T) {
+	for _, tt := range junctionTests {
+		g := readGene(t, tt.Input)
+		js, err := Junctions(g, geneio.SeqMap{"X": []byte(tt.Seq)})
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tt.Name, err)
+			continue
+		}
+		var motifs []string
+		var canonical []bool
+		for _, j := range js {
+			motifs = append(motifs, j.Motif)
+			canonical = append(canonical, j.Canonical())
+		}
+		if !reflect.DeepEqual(motifs, tt.Motifs) {
+			t.Errorf("%s: out motifs=%q want %q", tt.Name, motifs, tt.Motifs)
+		}
+		if !reflect.DeepEqual(canonical, tt.Canonical) {
+			t.Errorf("%s: out canonical=%v want %v", tt.Name, canonical, tt.Canonical)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package geneio
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// SeqDict is a sequence dictionary. It holds the names and lengths of
+// reference sequences in a defined order, e.g. the order of a FASTA index.
+type SeqDict struct {
+	names   []string
+	lengths map[string]int
+	index   map[string]int
+}
+
+// NewSeqDict returns a new empty SeqDict.
+func NewSeqDict() *SeqDict {
+	return &SeqDict{lengths: make(map[string]int), index: make(map[string]int)}
+}
+
+// Add appends the sequence name with the given length to d. It returns an
+// error if name is already in d or length is negative.
+func (d *SeqDict) Add(name string, length int) error {
+	if _, ok := d.index[name]; ok {
+		return fmt.Errorf("geneio: duplicate sequence %q in dictionary", name)
+	}
+	if length < 0 {
+		return fmt.Errorf("geneio: negative length for sequence %q", name)
+	}
+	d.index[name] = len(d.names)
+	d.names = append(d.names, name)
+	d.lengths[name] = length
+	return nil
+}
+
+// Length returns the length of the sequence name and whether it is in d.
+func (d *SeqDict) Length(name string) (int, bool) {
+	l, ok := d.lengths[name]
+	return l, ok
+}
+
+// Index returns the position of the sequence name in the order of d and
+// whether it is in d.
+func (d *SeqDict) Index(name string) (int, bool) {
+	i, ok := d.index[name]
+	return i, ok
+}
+
+// Names returns the names of the sequences in d in order.
+func (d *SeqDict) Names() []string {
+	return append([]string(nil), d.names...)
+}
+
+// Less returns whether sequence a is ordered before sequence b in d.
+// Sequences that are not in d are ordered after those that are, in lexical
+// order.
+func (d *SeqDict) Less(a, b string) bool {
+	i, iok := d.index[a]
+	j, jok := d.index[b]
+	switch {
+	case iok && jok:
+		return i < j
+	case iok != jok:
+		return iok
+	}
+	return a < b
+}
+
+// ReadFai reads a sequence dictionary from a FASTA index (.fai) in r.
+func ReadFai(r io.Reader) (*SeqDict, error) {
+	d := NewSeqDict()
+	err := scanLines(r, func(line int, text string) error {
+		fields := strings.Split(text, "\t")
+		if len(fields) < 2 {
+			return fmt.Errorf("geneio: fai line %d: too few fields", line)
+		}
+		l, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return fmt.Errorf("geneio: fai line %d: bad length %q", line, fields[1])
+		}
+		return d.Add(fields[0], l)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// ReadDict reads a sequence dictionary from the @SQ lines of a SAM style
+// sequence dictionary (.dict) in r. Other lines are ignored.
+func ReadDict(r io.Reader) (*SeqDict, error) {
+	d := NewSeqDict()
+	err := scanLines(r, func(line int, text string) error {
+		if !strings.HasPrefix(text, "@SQ\t") {
+			return nil
+		}
+		var name string
+		length := -1
+		for _, f := range strings.Split(text, "\t")[1:] {
+			switch {
+			case strings.HasPrefix(f, "SN:"):
+				name = f[3:]
+			case strings.HasPrefix(f, "LN:"):
+				l, err := strconv.Atoi(f[3:])
+				if err != nil {
+					return fmt.Errorf("geneio: dict line %d: bad length %q", line, f[3:])
+				}
+				length = l
+			}
+		}
+		if name == "" || length < 0 {
+			return fmt.Errorf("geneio: dict line %d: missing SN or LN", line)
+		}
+		return d.Add(name, length)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// ReadFasta reads a sequence dictionary from the FASTA sequences in r. The
+// name of a sequence is the first word of its header line.
+func ReadFasta(r io.Reader) (*SeqDict, error) {
+	d := NewSeqDict()
+	var name string
+	length := -1
+	flush := func() error {
+		if length < 0 {
+			return nil
+		}
+		return d.Add(name, length)
+	}
+	err := scanLines(r, func(line int, text string) error {
+		if strings.HasPrefix(text, ">") {
+			if err := flush(); err != nil {
+				return err
+			}
+			fields := strings.Fields(text[1:])
+			if len(fields) == 0 {
+				return fmt.Errorf("geneio: fasta line %d: empty header", line)
+			}
+			name, length = fields[0], 0
+			return nil
+		}
+		if length < 0 {
+			return fmt.Errorf("geneio: fasta line %d: sequence before header", line)
+		}
+		length += len(bytes.Join(bytes.Fields([]byte(text)), nil))
+		return nil
+	})
+	if err == nil {
+		err = flush()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// scanLines calls fn with each non empty line of r and its line number. It
+// stops at the first error.
+func scanLines(r io.Reader, fn func(line int, text string) error) error {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, 1<<26)
+	line := 0
+	for sc.Scan() {
+		line++
+		text := strings.TrimRight(sc.Text(), "\r")
+		if text == "" {
+			continue
+		}
+		if err := fn(line, text); err != nil {
+			return err
+		}
+	}
+	return sc.Err()
+}
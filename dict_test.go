@@ -0,0 +1,166 @@
+package geneio
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/biogo/biogo/io/featio/gff"
+)
+
+// Test ReadFai, ReadDict and ReadFasta
+var readSeqDictTests = []struct {
+	Name    string
+	Read    func(string) (*SeqDict, error)
+	Input   string
+	Names   []string
+	Lengths []int
+	Error   string
+}{
+	{
+		Name:    "fai",
+		Read:    func(s string) (*SeqDict, error) { return ReadFai(strings.NewReader(s)) },
+		Input:   "chr2\t200\t6\t60\t61\nchr1\t100\t300\t60\t61\n",
+		Names:   []string{"chr2", "chr1"},
+		Lengths: []int{200, 100},
+	},
+	{
+		Name:  "fai bad length",
+		Read:  func(s string) (*SeqDict, error) { return ReadFai(strings.NewReader(s)) },
+		Input: "chr2\tx\t6\t60\t61\n",
+		Error: `geneio: fai line 1: bad length "x"`,
+	},
+	{
+		Name:    "dict",
+		Read:    func(s string) (*SeqDict, error) { return ReadDict(strings.NewReader(s)) },
+		Input:   "@HD\tVN:1.6\n@SQ\tSN:X\tLN:1000\tM5:abc\n@SQ\tLN:50\tSN:Y\n",
+		Names:   []string{"X", "Y"},
+		Lengths: []int{1000, 50},
+	},
+	{
+		Name:  "dict missing length",
+		Read:  func(s string) (*SeqDict, error) { return ReadDict(strings.NewReader(s)) },
+		Input: "@SQ\tSN:X\n",
+		Error: "geneio: dict line 1: missing SN or LN",
+	},
+	{
+		Name:    "fasta",
+		Read:    func(s string) (*SeqDict, error) { return ReadFasta(strings.NewReader(s)) },
+		Input:   ">X description\nACGT\nAC\n>Y\n\n>Z\nA C\n",
+		Names:   []string{"X", "Y", "Z"},
+		Lengths: []int{6, 0, 2},
+	},
+	{
+		Name:  "fasta duplicate",
+		Read:  func(s string) (*SeqDict, error) { return ReadFasta(strings.NewReader(s)) },
+		Input: ">X\nA\n>X\nC\n",
+		Error: `geneio: duplicate sequence "X" in dictionary`,
+	},
+}
+
+func TestReadSeqDict(t *testing.T) {
+	for _, tt := range readSeqDictTests {
+		d, err := tt.Read(tt.Input)
+		if tt.Error != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.Error) {
+				t.Errorf("%s: error %q, want error %q", tt.Name, err, tt.Error)
+			}
+			continue
+		} else if err != nil {
+			t.Errorf("%s: unexpected error %v", tt.Name, err)
+			continue
+		}
+		if !reflect.DeepEqual(d.Names(), tt.Names) {
+			t.Errorf("%s: out names=%q want %q", tt.Name, d.Names(), tt.Names)
+		}
+		var lengths []int
+		for _, n := range d.Names() {
+			l, _ := d.Length(n)
+			lengths = append(lengths, l)
+		}
+		if !reflect.DeepEqual(lengths, tt.Lengths) {
+			t.Errorf("%s: out lengths=%d want %d", tt.Name, lengths, tt.Lengths)
+		}
+	}
+}
+
+func TestSeqDictLess(t *testing.T) {
+	d := NewSeqDict()
+	for _, n := range []string{"chr2", "chr10", "chr1"} {
+		if err := d.Add(n, 1); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+	}
+	names := []string{"chrUn", "chr1", "chrM", "chr10", "chr2"}
+	want := []string{"chr2", "chr10", "chr1", "chrM", "chrUn"}
+	for i := range names {
+		for j := i + 1; j < len(names); j++ {
+			if d.Less(names[j], names[i]) {
+				names[i], names[j] = names[j], names[i]
+			}
+		}
+	}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("out order=%q want %q", names, want)
+	}
+}
+
+// Test GeneReader with a sequence dictionary
+var seqDictReadTests = []struct {
+	Name      string
+	Input     string
+	Error     string
+	FeatCount int
+}{
+	{
+		Name:  "Valid",
+		Input: "X\t.\texon\t10\t100\t0\t+\t.\tgene_id A; transcript_id A1;\n",
+	},
+	{
+		Name:      "Unknown sequence",
+		Input:     "Z\t.\texon\t10\t20\t0\t+\t.\tgene_id A; transcript_id A1;\n",
+		Error:     "geneio: unknown sequence Z for gene A",
+		FeatCount: 1,
+	},
+	{
+		Name: "Exon past end",
+		Input: "" +
+			"X\t.\texon\t10\t20\t0\t+\t.\tgene_id A; transcript_id A1;\n" +
+			"X\t.\texon\t90\t101\t0\t+\t.\tgene_id A; transcript_id A1;\n" +
+			"X\t.\texon\t95\t102\t0\t+\t.\tgene_id A; transcript_id A2;\n",
+		Error:     "geneio: exons past the end of sequence X for gene A",
+		FeatCount: 2,
+	},
+}
+
+func TestGeneReaderSeqDict(t *testing.T) {
+	d := NewSeqDict()
+	if err := d.Add("X", 100); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	for _, tt := range seqDictReadTests {
+		r := GeneReader{
+			r: &FeatureReaderImpl{
+				r:   gff.NewReader(strings.NewReader(tt.Input)),
+				GID: "gene_id",
+				TID: "transcript_id",
+			},
+		}
+		r.SetSeqDict(d)
+		_, err := r.ReadAll()
+		if tt.Error == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error %v", tt.Name, err)
+			}
+			continue
+		}
+		fe, ok := err.(*FeaturesError)
+		if !ok || !strings.Contains(err.Error(), tt.Error) {
+			t.Errorf("%s: error %q, want error %q", tt.Name, err, tt.Error)
+			continue
+		}
+		if len(fe.Feats) != tt.FeatCount {
+			t.Errorf("%s: out offending feature count=%d want %d", tt.Name, len(fe.Feats), tt.FeatCount)
+		}
+	}
+}
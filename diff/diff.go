@@ -0,0 +1,326 @@
+// Package diff compares two gene annotations and reports their structural
+// differences.
+//
+// Genes are matched by gene ID and transcripts by transcript ID. Genes that
+// share an ID across non consecutive blocks of a stream are compared as one.
+// Differences are reported as a slice of Change values that can be written in
+// a human readable form with WriteText or as newline delimited JSON with
+// WriteJSON.
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/biogo/biogo/feat"
+	"github.com/biogo/biogo/feat/gene"
+	"github.com/go-bio/geneio"
+)
+
+// Type is the type of a Change.
+type Type int
+
+// Types of changes.
+const (
+	GeneAdded Type = iota
+	GeneRemoved
+	StrandChanged
+	LocationChanged
+	TranscriptAdded
+	TranscriptRemoved
+	ExonsChanged
+	CDSChanged
+)
+
+var typeNames = [...]string{
+	GeneAdded:         "gene_added",
+	GeneRemoved:       "gene_removed",
+	StrandChanged:     "strand_changed",
+	LocationChanged:   "location_changed",
+	TranscriptAdded:   "transcript_added",
+	TranscriptRemoved: "transcript_removed",
+	ExonsChanged:      "exons_changed",
+	CDSChanged:        "cds_changed",
+}
+
+// String returns the name of t.
+func (t Type) String() string {
+	if t < 0 || int(t) >= len(typeNames) {
+		return "unknown"
+	}
+	return typeNames[t]
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (t Type) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (t *Type) UnmarshalText(text []byte) error {
+	for i, n := range typeNames {
+		if n == string(text) {
+			*t = Type(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("diff: unknown change type %q", text)
+}
+
+// Model is the structure of a gene or transcript in reference coordinates.
+// Coordinates are zero based and half open.
+type Model struct {
+	Chrom  string   `json:"chrom"`
+	Strand string   `json:"strand"`
+	Start  int      `json:"start"`
+	End    int      `json:"end"`
+	Exons  [][2]int `json:"exons,omitempty"`
+	CDS    *[2]int  `json:"cds,omitempty"`
+}
+
+// Change is a single difference between the old and the new annotation. Old
+// is nil for additions and New is nil for removals. TID is empty for gene
+// level changes.
+type Change struct {
+	Type Type   `json:"type"`
+	GID  string `json:"gene_id"`
+	TID  string `json:"transcript_id,omitempty"`
+	Old  *Model `json:"old,omitempty"`
+	New  *Model `json:"new,omitempty"`
+}
+
+// String returns a human readable description of c. Coordinates are one
+// based and closed as in GFF.
+func (c Change) String() string {
+	switch c.Type {
+	case GeneAdded:
+		return fmt.Sprintf("+ gene %s %s", c.GID, span(c.New))
+	case GeneRemoved:
+		return fmt.Sprintf("- gene %s %s", c.GID, span(c.Old))
+	case StrandChanged:
+		return fmt.Sprintf("~ gene %s strand: %s -> %s", c.GID, c.Old.Strand, c.New.Strand)
+	case LocationChanged:
+		return fmt.Sprintf("~ gene %s location: %s -> %s", c.GID, span(c.Old), span(c.New))
+	case TranscriptAdded:
+		return fmt.Sprintf("+ transcript %s/%s %s", c.GID, c.TID, span(c.New))
+	case TranscriptRemoved:
+		return fmt.Sprintf("- transcript %s/%s %s", c.GID, c.TID, span(c.Old))
+	case ExonsChanged:
+		return fmt.Sprintf("~ transcript %s/%s exons: %s -> %s", c.GID, c.TID, exons(c.Old), exons(c.New))
+	case CDSChanged:
+		return fmt.Sprintf("~ transcript %s/%s cds: %s -> %s", c.GID, c.TID, cds(c.Old), cds(c.New))
+	}
+	return fmt.Sprintf("? %s %s/%s", c.Type, c.GID, c.TID)
+}
+
+// span formats the extent of m.
+func span(m *Model) string {
+	return fmt.Sprintf("%s:%d-%d(%s)", m.Chrom, m.Start+1, m.End, m.Strand)
+}
+
+// exons formats the exons of m.
+func exons(m *Model) string {
+	s := make([]string, len(m.Exons))
+	for i, e := range m.Exons {
+		s[i] = fmt.Sprintf("%d-%d", e[0]+1, e[1])
+	}
+	return m.Chrom + ":" + strings.Join(s, ",")
+}
+
+// cds formats the CDS of m.
+func cds(m *Model) string {
+	if m.CDS == nil {
+		return "none"
+	}
+	return fmt.Sprintf("%s:%d-%d", m.Chrom, m.CDS[0]+1, m.CDS[1])
+}
+
+// Compare reads all genes from the old annotation in from and the new one in
+// to and returns the changes that turn the old into the new. Changes are
+// ordered by the first appearance of their gene in from followed by genes only
+// present in to. It returns nil and an error if reading fails or a transcript
+// ID occurs more than once in a gene.
+func Compare(from, to geneio.Reader) ([]Change, error) {
+	oa, err := readAnnotation(from)
+	if err != nil {
+		return nil, err
+	}
+	na, err := readAnnotation(to)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []Change
+	for _, gid := range oa.order {
+		og := oa.genes[gid]
+		ng, ok := na.genes[gid]
+		if !ok {
+			changes = append(changes, Change{Type: GeneRemoved, GID: gid, Old: og.model})
+			continue
+		}
+		changes = append(changes, compareGenes(gid, og, ng)...)
+	}
+	for _, gid := range na.order {
+		if _, ok := oa.genes[gid]; !ok {
+			changes = append(changes, Change{Type: GeneAdded, GID: gid, New: na.genes[gid].model})
+		}
+	}
+	return changes, nil
+}
+
+// compareGenes returns the changes between og and ng.
+func compareGenes(gid string, og, ng *geneModel) []Change {
+	var changes []Change
+	if o, n := og.model, ng.model; o.Chrom != n.Chrom || o.Start != n.Start || o.End != n.End {
+		changes = append(changes, Change{Type: LocationChanged, GID: gid, Old: og.model, New: ng.model})
+	}
+	if og.model.Strand != ng.model.Strand {
+		changes = append(changes, Change{Type: StrandChanged, GID: gid, Old: og.model, New: ng.model})
+	}
+	for _, tid := range og.order {
+		ot := og.transcripts[tid]
+		nt, ok := ng.transcripts[tid]
+		if !ok {
+			changes = append(changes, Change{Type: TranscriptRemoved, GID: gid, TID: tid, Old: ot})
+			continue
+		}
+		if !equalExons(ot.Exons, nt.Exons) {
+			changes = append(changes, Change{Type: ExonsChanged, GID: gid, TID: tid, Old: ot, New: nt})
+		}
+		if !equalCDS(ot.CDS, nt.CDS) {
+			changes = append(changes, Change{Type: CDSChanged, GID: gid, TID: tid, Old: ot, New: nt})
+		}
+	}
+	for _, tid := range ng.order {
+		if _, ok := og.transcripts[tid]; !ok {
+			changes = append(changes, Change{Type: TranscriptAdded, GID: gid, TID: tid, New: ng.transcripts[tid]})
+		}
+	}
+	return changes
+}
+
+// equalExons returns whether a and b hold the same exons.
+func equalExons(a, b [][2]int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// equalCDS returns whether a and b are the same CDS.
+func equalCDS(a, b *[2]int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// annotation holds the models of all genes of a stream keyed by ID.
+type annotation struct {
+	order []string
+	genes map[string]*geneModel
+}
+
+// geneModel holds the models of a gene and its transcripts keyed by ID.
+type geneModel struct {
+	model       *Model
+	order       []string
+	transcripts map[string]*Model
+}
+
+// readAnnotation reads all genes from r. Genes with the same ID are merged
+// and it is an error for their transcripts to share an ID.
+func readAnnotation(r geneio.Reader) (*annotation, error) {
+	a := &annotation{genes: make(map[string]*geneModel)}
+	sc := geneio.NewScanner(r)
+	for sc.Next() {
+		g := sc.Gene()
+		gm, ok := a.genes[g.Name()]
+		if !ok {
+			gm = &geneModel{model: NewModel(g), transcripts: make(map[string]*Model)}
+			a.genes[g.Name()] = gm
+			a.order = append(a.order, g.Name())
+		} else {
+			m := NewModel(g)
+			if m.Start < gm.model.Start {
+				gm.model.Start = m.Start
+			}
+			if m.End > gm.model.End {
+				gm.model.End = m.End
+			}
+		}
+		for _, t := range geneio.Transcripts(g) {
+			if _, ok := gm.transcripts[t.Name()]; ok {
+				return nil, fmt.Errorf("diff: duplicate transcript ID %s in gene %s", t.Name(), g.Name())
+			}
+			gm.order = append(gm.order, t.Name())
+			gm.transcripts[t.Name()] = NewModel(t)
+		}
+	}
+	return a, sc.Error()
+}
+
+// NewModel returns the model of f, which should be a gene.Interface or a
+// gene.Transcript located on a gene.
+func NewModel(f feat.Feature) *Model {
+	start, ref := geneio.RefPosition(f, 0)
+	m := &Model{
+		Chrom:  ref.Name(),
+		Strand: strand(geneio.RefOrientation(f)),
+		Start:  start,
+		End:    start + f.Len(),
+	}
+	t, ok := f.(gene.Transcript)
+	if !ok {
+		return m
+	}
+	for _, e := range t.Exons() {
+		s, _ := geneio.RefPosition(t, e.Start())
+		m.Exons = append(m.Exons, [2]int{s, s + e.Len()})
+	}
+	if ct, ok := geneio.Coding(t); ok {
+		s, _ := geneio.RefPosition(t, ct.CDSstart)
+		e, _ := geneio.RefPosition(t, ct.CDSend)
+		m.CDS = &[2]int{s, e}
+	}
+	return m
+}
+
+// strand returns the GFF strand symbol for o.
+func strand(o feat.Orientation) string {
+	switch o {
+	case feat.Forward:
+		return "+"
+	case feat.Reverse:
+		return "-"
+	}
+	return "."
+}
+
+// WriteText writes changes to w in human readable form, one per line.
+func WriteText(w io.Writer, changes []Change) error {
+	for _, c := range changes {
+		if _, err := fmt.Fprintln(w, c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteJSON writes changes to w as newline delimited JSON.
+func WriteJSON(w io.Writer, changes []Change) error {
+	enc := json.NewEncoder(w)
+	for _, c := range changes {
+		if err := enc.Encode(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
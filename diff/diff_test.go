@@ -0,0 +1,89 @@
+package diff
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/biogo/biogo/io/featio/gff"
+	ggff "github.com/go-bio/geneio/gff"
+)
+
+var oldInput = "" +
+	"X\t.\texon\t11\t20\t0\t+\t.\tgene_id A; transcript_id A1;\n" +
+	"X\t.\texon\t31\t50\t0\t+\t.\tgene_id A; transcript_id A1;\n" +
+	"X\t.\tstart_codon\t15\t17\t0\t+\t.\tgene_id A; transcript_id A1;\n" +
+	"X\t.\tstop_codon\t41\t43\t0\t+\t.\tgene_id A; transcript_id A1;\n" +
+	"X\t.\texon\t11\t50\t0\t+\t.\tgene_id A; transcript_id A2;\n" +
+	"X\t.\texon\t61\t70\t0\t+\t.\tgene_id B; transcript_id B1;\n" +
+	"X\t.\texon\t81\t90\t0\t+\t.\tgene_id C; transcript_id C1;\n" +
+	"X\t.\texon\t101\t110\t0\t+\t.\tgene_id E; transcript_id E1;\n"
+
+var newInput = "" +
+	"X\t.\texon\t11\t20\t0\t+\t.\tgene_id A; transcript_id A1;\n" +
+	"X\t.\texon\t31\t55\t0\t+\t.\tgene_id A; transcript_id A1;\n" +
+	"X\t.\tstart_codon\t18\t20\t0\t+\t.\tgene_id A; transcript_id A1;\n" +
+	"X\t.\tstop_codon\t41\t43\t0\t+\t.\tgene_id A; transcript_id A1;\n" +
+	"X\t.\texon\t11\t50\t0\t+\t.\tgene_id A; transcript_id A3;\n" +
+	"X\t.\texon\t61\t70\t0\t-\t.\tgene_id B; transcript_id B1;\n" +
+	"Y\t.\texon\t1\t10\t0\t+\t.\tgene_id D; transcript_id D1;\n" +
+	"Y\t.\texon\t101\t110\t0\t+\t.\tgene_id E; transcript_id E1;\n"
+
+// Test Compare
+func TestCompare(t *testing.T) {
+	from := ggff.NewReader(gff.NewReader(strings.NewReader(oldInput)))
+	to := ggff.NewReader(gff.NewReader(strings.NewReader(newInput)))
+	changes, err := Compare(from, to)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteText(&buf, changes); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	want := []string{
+		"~ gene A location: X:11-50(+) -> X:11-55(+)",
+		"~ transcript A/A1 exons: X:11-20,31-50 -> X:11-20,31-55",
+		"~ transcript A/A1 cds: X:15-43 -> X:18-43",
+		"- transcript A/A2 X:11-50(+)",
+		"+ transcript A/A3 X:11-50(+)",
+		"~ gene B strand: + -> -",
+		"- gene C X:81-90(+)",
+		"~ gene E location: X:101-110(+) -> Y:101-110(+)",
+		"+ gene D Y:1-10(+)",
+	}
+	if got := strings.Split(strings.TrimSpace(buf.String()), "\n"); !reflect.DeepEqual(got, want) {
+		t.Errorf("out text=\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	buf.Reset()
+	if err := WriteJSON(&buf, changes); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	dec := json.NewDecoder(&buf)
+	for i := range changes {
+		var c Change
+		if err := dec.Decode(&c); err != nil {
+			t.Fatalf("unexpected error decoding change %d: %v", i, err)
+		}
+		if !reflect.DeepEqual(c, changes[i]) {
+			t.Errorf("change %d: out json round trip=%+v want %+v", i, c, changes[i])
+		}
+	}
+}
+
+func TestCompareDuplicate(t *testing.T) {
+	input := "" +
+		"X\t.\texon\t11\t20\t0\t+\t.\tgene_id A; transcript_id A1;\n" +
+		"X\t.\texon\t61\t70\t0\t+\t.\tgene_id B; transcript_id B1;\n" +
+		"X\t.\texon\t81\t90\t0\t+\t.\tgene_id A; transcript_id A1;\n"
+	from := ggff.NewReader(gff.NewReader(strings.NewReader(input)))
+	to := ggff.NewReader(gff.NewReader(strings.NewReader(oldInput)))
+	want := "diff: duplicate transcript ID A1 in gene A"
+	if _, err := Compare(from, to); err == nil || err.Error() != want {
+		t.Errorf("error %q, want error %q", err, want)
+	}
+}
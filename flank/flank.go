@@ -0,0 +1,154 @@
+// Package flank derives flanking regions and promoter windows from genes and
+// transcripts.
+//
+// Regions are strand aware: upstream is towards the 5' end of the feature
+// they are derived from and downstream towards its 3' end, and regions
+// around the transcription start site are placed at the 5' end. Features
+// that are not oriented are treated as on the forward strand. Regions are
+// located on the reference sequence of their feature and are clipped to its
+// start and, when a sequence dictionary is given, to its end.
+package flank
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/biogo/biogo/feat"
+	"github.com/biogo/biogo/feat/gene"
+	"github.com/go-bio/geneio"
+)
+
+// Region is a region derived from a feature. It implements feat.Feature and
+// feat.Orienter with the reference sequence as its location.
+type Region struct {
+	ID     string
+	Chrom  feat.Feature
+	Offset int
+	Length int
+	Orient feat.Orientation
+	Desc   string
+}
+
+// Start returns the start position of r on its reference sequence.
+func (r *Region) Start() int { return r.Offset }
+
+// End returns the end position of r on its reference sequence.
+func (r *Region) End() int { return r.Offset + r.Length }
+
+// Len returns the length of r.
+func (r *Region) Len() int { return r.Length }
+
+// Name returns the name of the feature r is derived from.
+func (r *Region) Name() string { return r.ID }
+
+// Description returns the kind of r, e.g. "upstream" or "promoter".
+func (r *Region) Description() string { return r.Desc }
+
+// Location returns the reference sequence of r.
+func (r *Region) Location() feat.Feature { return r.Chrom }
+
+// Orientation returns the orientation of the feature r is derived from.
+func (r *Region) Orientation() feat.Orientation { return r.Orient }
+
+// Flanker derives regions from features.
+type Flanker struct {
+	dict *geneio.SeqDict
+}
+
+// New returns a new Flanker clipping regions to the sequences of d. If d is
+// nil, regions are only clipped to the start of their sequence.
+func New(d *geneio.SeqDict) *Flanker {
+	return &Flanker{dict: d}
+}
+
+// region returns the region of f from a to b, relative to the 5' end of f
+// in its direction, clipped to the reference sequence.
+func (fl *Flanker) region(f feat.Feature, a, b int, desc string) (*Region, error) {
+	start, ref := geneio.RefPosition(f, 0)
+	end := start + f.Len()
+	o := geneio.RefOrientation(f)
+	r := &Region{ID: f.Name(), Chrom: ref, Orient: o, Desc: desc}
+	var s, e int
+	if o == feat.Reverse {
+		s, e = end-b, end-a
+	} else {
+		s, e = start+a, start+b
+	}
+	if s < 0 {
+		s = 0
+	}
+	if fl.dict != nil {
+		l, ok := fl.dict.Length(ref.Name())
+		if !ok {
+			return nil, fmt.Errorf("flank: sequence %q of %s not in dictionary", ref.Name(), f.Name())
+		}
+		if e > l {
+			e = l
+		}
+	}
+	if e < s {
+		e = s
+	}
+	r.Offset, r.Length = s, e-s
+	return r, nil
+}
+
+// Upstream returns the n bases upstream of the 5' end of f. The region may
+// be empty after clipping.
+func (fl *Flanker) Upstream(f feat.Feature, n int) (*Region, error) {
+	return fl.region(f, -n, 0, "upstream")
+}
+
+// Downstream returns the n bases downstream of the 3' end of f. The region
+// may be empty after clipping.
+func (fl *Flanker) Downstream(f feat.Feature, n int) (*Region, error) {
+	return fl.region(f, f.Len(), f.Len()+n, "downstream")
+}
+
+// Promoter returns the region from up bases upstream of the 5' end of f to
+// down bases downstream of it, including the first base of f in the down
+// bases.
+func (fl *Flanker) Promoter(f feat.Feature, up, down int) (*Region, error) {
+	return fl.region(f, -up, down, "promoter")
+}
+
+// TSS returns the region of n bases either side of the first base of f,
+// 2n+1 bases in all.
+func (fl *Flanker) TSS(f feat.Feature, n int) (*Region, error) {
+	return fl.region(f, -n, n+1, "TSS")
+}
+
+// Promoters returns the Promoter of each transcript of g. Transcripts that
+// share a transcription start site have their own regions.
+func (fl *Flanker) Promoters(g gene.Interface, up, down int) ([]*Region, error) {
+	var rs []*Region
+	for _, t := range geneio.Transcripts(g) {
+		r, err := fl.Promoter(t, up, down)
+		if err != nil {
+			return nil, err
+		}
+		rs = append(rs, r)
+	}
+	return rs, nil
+}
+
+// WriteBED writes the non empty regions of rs to w as BED6 lines with a
+// score of zero.
+func WriteBED(w io.Writer, rs []*Region) error {
+	for _, r := range rs {
+		if r.Length == 0 {
+			continue
+		}
+		strand := "."
+		switch r.Orient {
+		case feat.Forward:
+			strand = "+"
+		case feat.Reverse:
+			strand = "-"
+		}
+		if _, err := fmt.Fprintf(w, "%s\t%d\t%d\t%s\t0\t%s\n", r.Chrom.Name(), r.Start(), r.End(), r.ID, strand); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package flank
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/biogo/biogo/feat"
+	"github.com/go-bio/geneio"
+	"github.com/go-bio/geneio/internal/testgff"
+)
+
+var (
+	_ feat.Feature  = (*Region)(nil)
+	_ feat.Orienter = (*Region)(nil)
+)
+
+var flankInput = "" +
+	"X\t.\texon\t11\t20\t0\t+\t.\tgene_id A; transcript_id A1;\n" +
+	"X\t.\texon\t31\t40\t0\t+\t.\tgene_id A; transcript_id A1;\n" +
+	"X\t.\texon\t16\t40\t0\t+\t.\tgene_id A; transcript_id A2;\n" +
+	"X\t.\texon\t3\t10\t0\t-\t.\tgene_id B; transcript_id B1;\n" +
+	"Y\t.\texon\t3\t10\t0\t-\t.\tgene_id C; transcript_id C1;\n"
+
+// Test Flanker
+func TestFlanker(t *testing.T) {
+	genes := testgff.Genes(t, flankInput)
+	a, b, c := genes[0], genes[1], genes[2]
+	d := geneio.NewSeqDict()
+	d.Add("X", 45)
+	fl := New(d)
+
+	for _, test := range []struct {
+		name string
+		fn   func() (*Region, error)
+		want [2]int
+		err  string
+	}{
+		{name: "A upstream", fn: func() (*Region, error) { return fl.Upstream(a, 5) }, want: [2]int{5, 10}},
+		{name: "A downstream", fn: func() (*Region, error) { return fl.Downstream(a, 10) }, want: [2]int{40, 45}},
+		{name: "A promoter", fn: func() (*Region, error) { return fl.Promoter(a, 20, 5) }, want: [2]int{0, 15}},
+		{name: "A TSS", fn: func() (*Region, error) { return fl.TSS(a, 2) }, want: [2]int{8, 13}},
+		{name: "B upstream", fn: func() (*Region, error) { return fl.Upstream(b, 5) }, want: [2]int{10, 15}},
+		{name: "B downstream", fn: func() (*Region, error) { return fl.Downstream(b, 5) }, want: [2]int{0, 2}},
+		{name: "B downstream clipped", fn: func() (*Region, error) { return New(nil).Downstream(b, 5) }, want: [2]int{0, 2}},
+		{name: "B promoter", fn: func() (*Region, error) { return fl.Promoter(b, 3, 2) }, want: [2]int{8, 13}},
+		{name: "B TSS", fn: func() (*Region, error) { return fl.TSS(b, 1) }, want: [2]int{8, 11}},
+		{name: "A2 upstream", fn: func() (*Region, error) { return fl.Upstream(geneio.Transcripts(a)[1], 3) }, want: [2]int{12, 15}},
+		{name: "C upstream", fn: func() (*Region, error) { return New(nil).Upstream(c, 100) }, want: [2]int{10, 110}},
+		{name: "C dict", fn: func() (*Region, error) { return fl.Upstream(c, 5) }, err: `flank: sequence "Y" of C not in dictionary`},
+	} {
+		r, err := test.fn()
+		if test.err != "" {
+			if err == nil || err.Error() != test.err {
+				t.Errorf("%s: error %q, want error %q", test.name, err, test.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", test.name, err)
+			continue
+		}
+		if got := [2]int{r.Start(), r.End()}; got != test.want {
+			t.Errorf("%s: out region=%v want %v", test.name, got, test.want)
+		}
+	}
+
+	r, err := fl.Upstream(b, 5)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if r.Name() != "B" || r.Location().Name() != "X" || r.Orientation() != feat.Reverse || r.Description() != "upstream" {
+		t.Errorf("out region=%+v", r)
+	}
+}
+
+// Test Promoters and WriteBED
+func TestWriteBED(t *testing.T) {
+	genes := testgff.Genes(t, flankInput)
+	fl := New(nil)
+	rs, err := fl.Promoters(genes[0], 2, 1)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	r, err := fl.Promoters(genes[1], 2, 1)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	rs = append(rs, r...)
+	empty, err := fl.Downstream(genes[1], 0)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	rs = append(rs, empty)
+
+	var buf bytes.Buffer
+	if err := WriteBED(&buf, rs); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	want := "X\t8\t11\tA1\t0\t+\n" +
+		"X\t13\t16\tA2\t0\t+\n" +
+		"X\t9\t12\tB1\t0\t-\n"
+	if buf.String() != want {
+		t.Errorf("out BED=%q want %q", buf.String(), want)
+	}
+}
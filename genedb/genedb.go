@@ -0,0 +1,748 @@
+// Package genedb provides a compact binary gene database.
+//
+// A database is written once from a geneio.Reader and opened by memory
+// mapping the file where the platform allows it, so that genes can be looked
+// up by gene ID, transcript ID and region without parsing the annotation. A
+// DB decodes genes on demand; opening a database only reads its small list
+// of reference sequences.
+//
+// All integers are little endian. A file starts with the magic string
+// "GENEDB\x00\x01" followed by the header fields and then the sections:
+//
+//	chroms    uvarint count, then per sequence its name and the first
+//	          region entry and number of entries on it as uvarints
+//	records   gene records encoded with uvarints, varints and strings,
+//	          including the attributes of genes and transcripts and the
+//	          tags, translational exceptions and coding phase of
+//	          coding transcripts
+//	offsets   uint64 offset of each gene record relative to records
+//	regions   32 byte entries of uint32 sequence, uint32 gene, int64
+//	          start, end and running maximum end, sorted by sequence
+//	          and start
+//	gene IDs  16 byte entries of uint64 name offset and uint32 gene,
+//	          sorted by name
+//	tx IDs    like gene IDs for transcript IDs
+//	names     uvarint length prefixed strings
+package genedb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/biogo/biogo/feat"
+	"github.com/biogo/biogo/feat/gene"
+	"github.com/go-bio/geneio"
+)
+
+const magic = "GENEDB\x00\x01"
+
+// Header fields in order after the magic string.
+const (
+	hGenes = iota
+	hTranscripts
+	hChroms
+	hRecords
+	hOffsets
+	hRegions
+	hGeneIDs
+	hTxIDs
+	hNames
+	hEnd
+	headerFields
+)
+
+const (
+	headerSize  = len(magic) + 8*headerFields
+	regionSize  = 32
+	idEntrySize = 16
+)
+
+// ErrNotFound is returned by lookups that find no gene.
+var ErrNotFound = errors.New("genedb: not found")
+
+// ErrClosed is returned by lookups on a closed DB.
+var ErrClosed = errors.New("genedb: database closed")
+
+// transcript kinds in records.
+const (
+	nonCoding = iota
+	coding
+)
+
+// Write reads all genes from r and writes a database of them to w. Genes
+// must have transcripts of type *gene.CodingTranscript or
+// *gene.NonCodingTranscript, geneio.Annotated transcripts or
+// *geneio.AttributedTranscript. The attributes of genes and transcripts and
+// the tags, translational exceptions and coding phase of Annotated
+// transcripts are stored. Genes are stored in the order they are read.
+func Write(w io.Writer, r geneio.Reader) error {
+	var (
+		records bytes.Buffer
+		offsets []uint64
+		regions []region
+		geneIDs []idEntry
+		txIDs   []idEntry
+		names   nameTable
+		chroms  = make(map[string]uint32)
+		chromNs []string
+		ntx     int
+	)
+	sc := geneio.NewScanner(r)
+	for sc.Next() {
+		g := sc.Gene()
+		start, ref := geneio.RefPosition(g, 0)
+		ci, ok := chroms[ref.Name()]
+		if !ok {
+			ci = uint32(len(chromNs))
+			chroms[ref.Name()] = ci
+			chromNs = append(chromNs, ref.Name())
+		}
+		gi := uint32(len(offsets))
+		offsets = append(offsets, uint64(records.Len()))
+		if err := encodeGene(&records, g, ci); err != nil {
+			return err
+		}
+		regions = append(regions, region{chrom: ci, gene: gi, start: int64(start), end: int64(start + g.Len())})
+		geneIDs = append(geneIDs, idEntry{name: names.add(g.Name()), gene: gi, s: g.Name()})
+		for _, t := range geneio.Transcripts(g) {
+			txIDs = append(txIDs, idEntry{name: names.add(t.Name()), gene: gi, s: t.Name()})
+			ntx++
+		}
+	}
+	if err := sc.Error(); err != nil {
+		return err
+	}
+
+	sort.SliceStable(regions, func(i, j int) bool {
+		a, b := regions[i], regions[j]
+		if a.chrom != b.chrom {
+			return chromNs[a.chrom] < chromNs[b.chrom]
+		}
+		return a.start < b.start
+	})
+	type span struct{ first, n int }
+	spans := make([]span, len(chromNs))
+	var maxEnd int64
+	for i := range regions {
+		c := regions[i].chrom
+		if i == 0 || regions[i-1].chrom != c {
+			spans[c].first = i
+			maxEnd = regions[i].end
+		}
+		spans[c].n++
+		if regions[i].end > maxEnd {
+			maxEnd = regions[i].end
+		}
+		regions[i].maxEnd = maxEnd
+	}
+	sort.SliceStable(geneIDs, func(i, j int) bool { return geneIDs[i].s < geneIDs[j].s })
+	sort.SliceStable(txIDs, func(i, j int) bool { return txIDs[i].s < txIDs[j].s })
+
+	var chromSec []byte
+	chromSec = binary.AppendUvarint(chromSec, uint64(len(chromNs)))
+	for i, n := range chromNs {
+		chromSec = appendString(chromSec, n)
+		chromSec = binary.AppendUvarint(chromSec, uint64(spans[i].first))
+		chromSec = binary.AppendUvarint(chromSec, uint64(spans[i].n))
+	}
+	offSec := make([]byte, 0, 8*len(offsets))
+	for _, o := range offsets {
+		offSec = binary.LittleEndian.AppendUint64(offSec, o)
+	}
+	regSec := make([]byte, 0, regionSize*len(regions))
+	for _, e := range regions {
+		regSec = binary.LittleEndian.AppendUint32(regSec, e.chrom)
+		regSec = binary.LittleEndian.AppendUint32(regSec, e.gene)
+		regSec = binary.LittleEndian.AppendUint64(regSec, uint64(e.start))
+		regSec = binary.LittleEndian.AppendUint64(regSec, uint64(e.end))
+		regSec = binary.LittleEndian.AppendUint64(regSec, uint64(e.maxEnd))
+	}
+
+	sections := [][]byte{chromSec, records.Bytes(), offSec, regSec, encodeIDs(geneIDs), encodeIDs(txIDs), names.buf}
+	header := make([]byte, 0, headerSize)
+	header = append(header, magic...)
+	fields := [headerFields]uint64{hGenes: uint64(len(offsets)), hTranscripts: uint64(ntx)}
+	off := uint64(headerSize)
+	for i, s := range sections {
+		fields[hChroms+i] = off
+		off += uint64(len(s))
+	}
+	fields[hEnd] = off
+	for _, f := range fields {
+		header = binary.LittleEndian.AppendUint64(header, f)
+	}
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	for _, s := range sections {
+		if _, err := w.Write(s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// region is an entry of the region index.
+type region struct {
+	chrom, gene        uint32
+	start, end, maxEnd int64
+}
+
+// idEntry is an entry of an ID index. s is only used while writing.
+type idEntry struct {
+	name uint64
+	gene uint32
+	s    string
+}
+
+// encodeIDs returns the encoded entries of an ID index.
+func encodeIDs(ids []idEntry) []byte {
+	b := make([]byte, 0, idEntrySize*len(ids))
+	for _, e := range ids {
+		b = binary.LittleEndian.AppendUint64(b, e.name)
+		b = binary.LittleEndian.AppendUint32(b, e.gene)
+		b = binary.LittleEndian.AppendUint32(b, 0)
+	}
+	return b
+}
+
+// nameTable holds the names of the names section, each stored once.
+type nameTable struct {
+	buf []byte
+	off map[string]uint64
+}
+
+// add adds s to t if needed and returns its offset.
+func (t *nameTable) add(s string) uint64 {
+	if t.off == nil {
+		t.off = make(map[string]uint64)
+	}
+	if o, ok := t.off[s]; ok {
+		return o
+	}
+	o := uint64(len(t.buf))
+	t.buf = appendString(t.buf, s)
+	t.off[s] = o
+	return o
+}
+
+func appendString(b []byte, s string) []byte {
+	b = binary.AppendUvarint(b, uint64(len(s)))
+	return append(b, s...)
+}
+
+// appendStrings appends the number of strings in ss followed by ss to b.
+func appendStrings(b []byte, ss []string) []byte {
+	b = binary.AppendUvarint(b, uint64(len(ss)))
+	for _, s := range ss {
+		b = appendString(b, s)
+	}
+	return b
+}
+
+// appendAttributes appends the number of attributes in attrs followed by
+// their keys and values in key order to b.
+func appendAttributes(b []byte, attrs map[string]string) []byte {
+	keys := make([]string, 0, len(attrs))
+	for k := range attrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	b = binary.AppendUvarint(b, uint64(len(keys)))
+	for _, k := range keys {
+		b = appendString(b, k)
+		b = appendString(b, attrs[k])
+	}
+	return b
+}
+
+// encodeGene appends the record of g located on sequence ci to b.
+func encodeGene(b *bytes.Buffer, g gene.Interface, ci uint32) error {
+	var rec []byte
+	start, _ := geneio.RefPosition(g, 0)
+	rec = appendString(rec, g.Name())
+	rec = appendString(rec, g.Description())
+	rec = binary.AppendUvarint(rec, uint64(ci))
+	rec = binary.AppendVarint(rec, int64(start))
+	rec = binary.AppendVarint(rec, int64(geneio.RefOrientation(g)))
+	rec = appendAttributes(rec, geneio.Attributes(g))
+	ts := geneio.Transcripts(g)
+	rec = binary.AppendUvarint(rec, uint64(len(ts)))
+	for _, t := range ts {
+		rec = appendString(rec, t.Name())
+		rec = appendString(rec, t.Description())
+		rec = binary.AppendVarint(rec, int64(t.Start()))
+		rec = binary.AppendVarint(rec, int64(t.Orientation()))
+		if ct, ok := geneio.Coding(t); ok {
+			rec = binary.AppendUvarint(rec, coding)
+			rec = binary.AppendVarint(rec, int64(ct.CDSstart))
+			rec = binary.AppendVarint(rec, int64(ct.CDSend))
+			var (
+				tags  []string
+				exs   []geneio.Exception
+				phase int
+			)
+			if a, ok := t.(geneio.Annotated); ok {
+				tags, exs, phase = a.TranscriptTags(), a.TranslationExceptions(), a.CodingPhase()
+			}
+			rec = appendStrings(rec, tags)
+			rec = binary.AppendUvarint(rec, uint64(len(exs)))
+			for _, x := range exs {
+				rec = binary.AppendVarint(rec, int64(x.Offset))
+				rec = append(rec, x.AminoAcid)
+			}
+			rec = binary.AppendUvarint(rec, uint64(phase))
+		} else if _, ok := geneio.NonCoding(t); ok {
+			rec = binary.AppendUvarint(rec, nonCoding)
+		} else {
+			return fmt.Errorf("genedb: cannot store transcript %s of type %T", t.Name(), t)
+		}
+		exons := t.Exons()
+		rec = binary.AppendUvarint(rec, uint64(len(exons)))
+		for _, e := range exons {
+			rec = binary.AppendUvarint(rec, uint64(e.Start()))
+			rec = binary.AppendUvarint(rec, uint64(e.Len()))
+		}
+		rec = appendAttributes(rec, geneio.Attributes(t))
+	}
+	b.Write(rec)
+	return nil
+}
+
+// decoder decodes record fields, keeping the first error.
+type decoder struct {
+	b   []byte
+	err error
+}
+
+func (d *decoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(d.b)
+	if n <= 0 {
+		d.err = errCorrupt
+		return 0
+	}
+	d.b = d.b[n:]
+	return v
+}
+
+func (d *decoder) varint() int64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Varint(d.b)
+	if n <= 0 {
+		d.err = errCorrupt
+		return 0
+	}
+	d.b = d.b[n:]
+	return v
+}
+
+func (d *decoder) string() string {
+	n := d.uvarint()
+	if d.err != nil {
+		return ""
+	}
+	if n > uint64(len(d.b)) {
+		d.err = errCorrupt
+		return ""
+	}
+	s := string(d.b[:n])
+	d.b = d.b[n:]
+	return s
+}
+
+func (d *decoder) byte() byte {
+	if d.err != nil {
+		return 0
+	}
+	if len(d.b) == 0 {
+		d.err = errCorrupt
+		return 0
+	}
+	c := d.b[0]
+	d.b = d.b[1:]
+	return c
+}
+
+// strings decodes a list of strings written by appendStrings.
+func (d *decoder) strings() []string {
+	n := d.uvarint()
+	if n > uint64(len(d.b)) {
+		d.err = errCorrupt
+	}
+	if d.err != nil || n == 0 {
+		return nil
+	}
+	ss := make([]string, n)
+	for i := range ss {
+		ss[i] = d.string()
+	}
+	return ss
+}
+
+// attributes decodes attributes written by appendAttributes. It returns nil
+// if there are none.
+func (d *decoder) attributes() map[string]string {
+	n := d.uvarint()
+	if n > uint64(len(d.b)) {
+		d.err = errCorrupt
+	}
+	if d.err != nil || n == 0 {
+		return nil
+	}
+	attrs := make(map[string]string, n)
+	for i := uint64(0); i < n; i++ {
+		k := d.string()
+		attrs[k] = d.string()
+	}
+	return attrs
+}
+
+var errCorrupt = errors.New("genedb: corrupt database")
+
+// DB is an opened gene database. A DB is safe for concurrent use, except
+// that Close must not be called concurrently with other methods.
+type DB struct {
+	data   []byte
+	fields [headerFields]uint64
+	chroms []chromInfo
+	byName map[string]int
+	unmap  func() error
+	closed bool
+}
+
+// chromInfo describes the region entries of a reference sequence.
+type chromInfo struct {
+	name     string
+	first, n int
+}
+
+// Load returns a DB reading from the database in data. data must not be
+// modified while the DB is in use.
+func Load(data []byte) (*DB, error) {
+	if len(data) < headerSize || string(data[:len(magic)]) != magic {
+		return nil, errors.New("genedb: not a gene database")
+	}
+	db := &DB{data: data}
+	for i := range db.fields {
+		db.fields[i] = binary.LittleEndian.Uint64(data[len(magic)+8*i:])
+	}
+	f := db.fields
+	if f[hEnd] != uint64(len(data)) {
+		return nil, errCorrupt
+	}
+	for i := hChroms; i < hEnd; i++ {
+		if f[i] > f[i+1] || f[i] < uint64(headerSize) {
+			return nil, errCorrupt
+		}
+	}
+	if f[hOffsets+1]-f[hOffsets] != 8*f[hGenes] ||
+		f[hRegions+1]-f[hRegions] != regionSize*f[hGenes] ||
+		f[hGeneIDs+1]-f[hGeneIDs] != idEntrySize*f[hGenes] ||
+		f[hTxIDs+1]-f[hTxIDs] != idEntrySize*f[hTranscripts] {
+		return nil, errCorrupt
+	}
+
+	d := decoder{b: db.section(hChroms)}
+	n := d.uvarint()
+	if n > uint64(len(d.b)) {
+		return nil, errCorrupt
+	}
+	db.byName = make(map[string]int, n)
+	for i := uint64(0); i < n; i++ {
+		name, first, n := d.string(), d.uvarint(), d.uvarint()
+		if d.err != nil {
+			return nil, d.err
+		}
+		if first > f[hGenes] || n > f[hGenes]-first {
+			return nil, errCorrupt
+		}
+		db.byName[name] = len(db.chroms)
+		db.chroms = append(db.chroms, chromInfo{name: name, first: int(first), n: int(n)})
+	}
+	return db, nil
+}
+
+// Open opens the database file at path. The file is memory mapped where
+// supported and read into memory otherwise.
+func Open(path string) (*DB, error) {
+	data, unmap, err := mapFile(path)
+	if err != nil {
+		return nil, err
+	}
+	db, err := Load(data)
+	if err != nil {
+		unmap()
+		return nil, err
+	}
+	db.unmap = unmap
+	return db, nil
+}
+
+// Close releases the resources of db. Genes returned by db remain valid, and
+// later lookups return ErrClosed. Closing a closed DB returns ErrClosed.
+func (db *DB) Close() error {
+	if db.closed {
+		return ErrClosed
+	}
+	db.closed = true
+	if db.unmap == nil {
+		return nil
+	}
+	return db.unmap()
+}
+
+// section returns the bytes of section i.
+func (db *DB) section(i int) []byte {
+	return db.data[db.fields[i]:db.fields[i+1]]
+}
+
+// Len returns the number of genes in db.
+func (db *DB) Len() int {
+	return int(db.fields[hGenes])
+}
+
+// Chroms returns the names of the reference sequences of db in lexical
+// order.
+func (db *DB) Chroms() []string {
+	names := make([]string, len(db.chroms))
+	for i, c := range db.chroms {
+		names[i] = c.name
+	}
+	sort.Strings(names)
+	return names
+}
+
+// gene decodes gene i. The gene is located on a geneio.Chrom.
+func (db *DB) gene(i int) (gene.Interface, error) {
+	if db.closed {
+		return nil, ErrClosed
+	}
+	if i < 0 || i >= db.Len() {
+		return nil, errCorrupt
+	}
+	recs := db.section(hRecords)
+	off := binary.LittleEndian.Uint64(db.section(hOffsets)[8*i:])
+	if off >= uint64(len(recs)) {
+		return nil, errCorrupt
+	}
+	d := &decoder{b: recs[off:]}
+	g := &gene.Gene{ID: d.string(), Desc: d.string()}
+	ci := d.uvarint()
+	g.Offset = int(d.varint())
+	g.Orient = feat.Orientation(d.varint())
+	attrs := d.attributes()
+	if d.err != nil {
+		return nil, d.err
+	}
+	if ci >= uint64(len(db.chroms)) {
+		return nil, errCorrupt
+	}
+	g.Chrom = geneio.Chrom(db.chroms[ci].name)
+	nt := d.uvarint()
+	if nt > uint64(len(d.b)) {
+		return nil, errCorrupt
+	}
+	feats := make([]feat.Feature, 0, nt)
+	for j := uint64(0); j < nt; j++ {
+		t, err := decodeTranscript(d, g)
+		if err != nil {
+			return nil, err
+		}
+		feats = append(feats, t)
+	}
+	if err := g.SetFeatures(feats...); err != nil {
+		return nil, err
+	}
+	return geneio.WithAttributes(g, attrs), nil
+}
+
+// decodeTranscript decodes a transcript located on g.
+func decodeTranscript(d *decoder, g *gene.Gene) (gene.Transcript, error) {
+	id, desc := d.string(), d.string()
+	offset := int(d.varint())
+	orient := feat.Orientation(d.varint())
+	var (
+		t     gene.Transcript
+		tags  []string
+		exs   []geneio.Exception
+		phase int
+	)
+	switch d.uvarint() {
+	case coding:
+		cdsStart, cdsEnd := int(d.varint()), int(d.varint())
+		t = &gene.CodingTranscript{
+			ID: id, Loc: g, Offset: offset, Orient: orient, Desc: desc,
+			CDSstart: cdsStart, CDSend: cdsEnd,
+		}
+		tags = d.strings()
+		nx := d.uvarint()
+		if nx > uint64(len(d.b)) {
+			return nil, errCorrupt
+		}
+		for i := uint64(0); i < nx; i++ {
+			exs = append(exs, geneio.Exception{Offset: int(d.varint()), AminoAcid: d.byte()})
+		}
+		phase = int(d.uvarint())
+	case nonCoding:
+		t = &gene.NonCodingTranscript{ID: id, Loc: g, Offset: offset, Orient: orient, Desc: desc}
+	default:
+		return nil, errCorrupt
+	}
+	ne := d.uvarint()
+	if d.err != nil {
+		return nil, d.err
+	}
+	if ne > uint64(len(d.b)) {
+		return nil, errCorrupt
+	}
+	exons := make([]gene.Exon, ne)
+	for i := range exons {
+		exons[i] = gene.Exon{Transcript: t, Offset: int(d.uvarint()), Length: int(d.uvarint())}
+	}
+	if d.err != nil {
+		return nil, d.err
+	}
+	if err := t.SetExons(exons...); err != nil {
+		return nil, err
+	}
+	attrs := d.attributes()
+	if d.err != nil {
+		return nil, d.err
+	}
+	if ct, ok := t.(*gene.CodingTranscript); ok && (tags != nil || exs != nil || phase != 0) {
+		return &geneio.AnnotatedTranscript{
+			CodingTranscript: ct, Tags: tags, Exceptions: exs, Phase: phase, Attrs: attrs,
+		}, nil
+	}
+	return geneio.TranscriptWithAttributes(t, attrs), nil
+}
+
+// Gene returns the gene with the given ID. It returns ErrNotFound if there
+// is none.
+func (db *DB) Gene(id string) (gene.Interface, error) {
+	i, err := db.lookup(hGeneIDs, id)
+	if err != nil {
+		return nil, err
+	}
+	return db.gene(i)
+}
+
+// Transcript returns the gene holding the transcript with the given ID. It
+// returns ErrNotFound if there is none.
+func (db *DB) Transcript(id string) (gene.Interface, error) {
+	i, err := db.lookup(hTxIDs, id)
+	if err != nil {
+		return nil, err
+	}
+	return db.gene(i)
+}
+
+// lookup returns the gene index of name in the ID index section sec. If a
+// name occurs more than once, the first gene is returned.
+func (db *DB) lookup(sec int, name string) (int, error) {
+	if db.closed {
+		return 0, ErrClosed
+	}
+	ids := db.section(sec)
+	n := len(ids) / idEntrySize
+	var err error
+	i := sort.Search(n, func(i int) bool {
+		s, e := db.name(ids[i*idEntrySize:])
+		if e != nil {
+			err = e
+		}
+		return s >= name
+	})
+	if err != nil {
+		return 0, err
+	}
+	if i == n {
+		return 0, ErrNotFound
+	}
+	if s, err := db.name(ids[i*idEntrySize:]); err != nil {
+		return 0, err
+	} else if s != name {
+		return 0, ErrNotFound
+	}
+	return int(binary.LittleEndian.Uint32(ids[i*idEntrySize+8:])), nil
+}
+
+// name returns the name of the ID index entry at the start of e.
+func (db *DB) name(e []byte) (string, error) {
+	names := db.section(hNames)
+	off := binary.LittleEndian.Uint64(e)
+	if off >= uint64(len(names)) {
+		return "", errCorrupt
+	}
+	d := decoder{b: names[off:]}
+	s := d.string()
+	return s, d.err
+}
+
+// Overlapping returns the genes on chrom that overlap the half open interval
+// [start, end) ordered by start.
+func (db *DB) Overlapping(chrom string, start, end int) ([]gene.Interface, error) {
+	if db.closed {
+		return nil, ErrClosed
+	}
+	ci, ok := db.byName[chrom]
+	if !ok || start >= end {
+		return nil, nil
+	}
+	c := db.chroms[ci]
+	regs := db.section(hRegions)[c.first*regionSize : (c.first+c.n)*regionSize]
+	field := func(i, off int) int64 {
+		return int64(binary.LittleEndian.Uint64(regs[i*regionSize+off:]))
+	}
+	// Entries from j on start at or after end and cannot overlap.
+	j := sort.Search(c.n, func(i int) bool { return field(i, 8) >= int64(end) })
+	var hits []int
+	for i := j - 1; i >= 0 && field(i, 24) > int64(start); i-- {
+		if field(i, 16) > int64(start) {
+			hits = append(hits, i)
+		}
+	}
+	genes := make([]gene.Interface, len(hits))
+	for k, i := range hits {
+		g, err := db.gene(int(binary.LittleEndian.Uint32(regs[i*regionSize+4:])))
+		if err != nil {
+			return nil, err
+		}
+		genes[len(hits)-1-k] = g
+	}
+	return genes, nil
+}
+
+// NewReader returns a Reader of all genes of db in stored order.
+func (db *DB) NewReader() *Reader {
+	return &Reader{db: db}
+}
+
+// Reader is a geneio.Reader of the genes of a DB.
+type Reader struct {
+	db   *DB
+	next int
+}
+
+// Read returns the next gene. At the end of the database, it returns nil
+// and io.EOF.
+func (r *Reader) Read() (gene.Interface, error) {
+	if r.next >= r.db.Len() {
+		return nil, io.EOF
+	}
+	g, err := r.db.gene(r.next)
+	if err != nil {
+		return nil, err
+	}
+	r.next++
+	return g, nil
+}
@@ -0,0 +1,266 @@
+package genedb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/biogo/biogo/feat/gene"
+	"github.com/biogo/biogo/io/featio/gff"
+	"github.com/go-bio/geneio"
+	ggff "github.com/go-bio/geneio/gff"
+)
+
+// Assert that interfaces are satisfied.
+var (
+	_ geneio.Reader = (*Reader)(nil)
+)
+
+var dbInput = "" +
+	"chr2\t.\texon\t101\t200\t.\t+\t.\tgene_id B; transcript_id B1;\n" +
+	"chr1\t.\texon\t10\t20\t.\t-\t.\tgene_id A; transcript_id A1;\n" +
+	"chr1\t.\texon\t50\t90\t.\t-\t.\tgene_id A; transcript_id A1;\n" +
+	"chr1\t.\tstop_codon\t60\t62\t.\t-\t.\tgene_id A; transcript_id A1;\n" +
+	"chr1\t.\tstart_codon\t71\t73\t.\t-\t.\tgene_id A; transcript_id A1;\n" +
+	"chr1\t.\texon\t15\t100\t.\t-\t.\tgene_id A; transcript_id A2;\n" +
+	"chr1\t.\texon\t31\t40\t.\t+\t.\tgene_id C; transcript_id C1;\n" +
+	"chr1\t.\texon\t201\t300\t.\t+\t.\tgene_id D; transcript_id D1;\n"
+
+func gffReader(input string) geneio.Reader {
+	return ggff.NewReader(gff.NewReader(strings.NewReader(input)))
+}
+
+// writeGFF returns genes as GFF text.
+func writeGFF(t *testing.T, genes ...gene.Interface) string {
+	var buf bytes.Buffer
+	w := ggff.NewWriter(&buf)
+	for _, g := range genes {
+		if _, err := w.Write(g); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+	}
+	return buf.String()
+}
+
+func build(t *testing.T) []byte {
+	var buf bytes.Buffer
+	if err := Write(&buf, gffReader(dbInput)); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	return buf.Bytes()
+}
+
+func names(genes []gene.Interface) []string {
+	var ids []string
+	for _, g := range genes {
+		ids = append(ids, g.Name())
+	}
+	return ids
+}
+
+func TestOpen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "genes.gdb")
+	if err := os.WriteFile(path, build(t), 0o644); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	db, err := Open(path)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	sc := geneio.NewScanner(db.NewReader())
+	var genes []gene.Interface
+	for sc.Next() {
+		genes = append(genes, sc.Gene())
+	}
+	if err := sc.Error(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if err := db.Close(); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	if _, err := db.Gene("A"); err != ErrClosed {
+		t.Errorf("error %q, want error %q", err, ErrClosed)
+	}
+	if _, err := db.Overlapping("chr1", 0, 1000); err != ErrClosed {
+		t.Errorf("error %q, want error %q", err, ErrClosed)
+	}
+	if _, err := db.NewReader().Read(); err != ErrClosed {
+		t.Errorf("error %q, want error %q", err, ErrClosed)
+	}
+	if err := db.Close(); err != ErrClosed {
+		t.Errorf("error %q, want error %q", err, ErrClosed)
+	}
+	var want []gene.Interface
+	sc = geneio.NewScanner(gffReader(dbInput))
+	for sc.Next() {
+		want = append(want, sc.Gene())
+	}
+	if got, want := writeGFF(t, genes...), writeGFF(t, want...); got != want {
+		t.Errorf("out genes=\n%s want\n%s", got, want)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	db, err := Load(build(t))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if db.Len() != 4 {
+		t.Errorf("out len=%d want 4", db.Len())
+	}
+	if got, want := db.Chroms(), []string{"chr1", "chr2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("out chroms=%q want %q", got, want)
+	}
+
+	for _, tt := range []struct {
+		Name   string
+		Lookup func(string) (gene.Interface, error)
+		ID     string
+		Gene   string
+		Error  error
+	}{
+		{"Gene", db.Gene, "A", "A", nil},
+		{"Gene", db.Gene, "D", "D", nil},
+		{"Gene", db.Gene, "A1", "", ErrNotFound},
+		{"Transcript", db.Transcript, "A2", "A", nil},
+		{"Transcript", db.Transcript, "C1", "C", nil},
+		{"Transcript", db.Transcript, "Z1", "", ErrNotFound},
+	} {
+		g, err := tt.Lookup(tt.ID)
+		if err != tt.Error {
+			t.Errorf("%s %s: error %v, want error %v", tt.Name, tt.ID, err, tt.Error)
+			continue
+		}
+		if err == nil && g.Name() != tt.Gene {
+			t.Errorf("%s %s: out gene=%s want %s", tt.Name, tt.ID, g.Name(), tt.Gene)
+		}
+	}
+
+	g, err := db.Gene("A")
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	want := "" +
+		"chr1\t.\texon\t10\t20\t.\t-\t.\tgene_id A; transcript_id A1;\n" +
+		"chr1\t.\texon\t50\t90\t.\t-\t.\tgene_id A; transcript_id A1;\n" +
+		"chr1\t.\tstop_codon\t60\t62\t.\t-\t.\tgene_id A; transcript_id A1;\n" +
+		"chr1\t.\tstart_codon\t71\t73\t.\t-\t.\tgene_id A; transcript_id A1;\n" +
+		"chr1\t.\texon\t15\t100\t.\t-\t.\tgene_id A; transcript_id A2;\n"
+	if got := writeGFF(t, g); got != want {
+		t.Errorf("out gene=\n%s want\n%s", got, want)
+	}
+
+	for _, tt := range []struct {
+		Chrom      string
+		Start, End int
+		IDs        []string
+	}{
+		{"chr1", 0, 9, nil},
+		{"chr1", 0, 10, []string{"A"}},
+		{"chr1", 35, 36, []string{"A", "C"}},
+		{"chr1", 99, 250, []string{"A", "D"}},
+		{"chr1", 100, 200, nil},
+		{"chr2", 0, 1000, []string{"B"}},
+		{"chr3", 0, 1000, nil},
+	} {
+		genes, err := db.Overlapping(tt.Chrom, tt.Start, tt.End)
+		if err != nil {
+			t.Errorf("%s:%d-%d: unexpected error %v", tt.Chrom, tt.Start, tt.End, err)
+			continue
+		}
+		if got := names(genes); !reflect.DeepEqual(got, tt.IDs) {
+			t.Errorf("%s:%d-%d: out ids=%q want %q", tt.Chrom, tt.Start, tt.End, got, tt.IDs)
+		}
+	}
+}
+
+func TestAnnotation(t *testing.T) {
+	input := "" +
+		"X\t.\texon\t1\t9\t.\t+\t.\tgene_id S; transcript_id S1; gene_name \"SEL 1\"; tag basic;\n" +
+		"X\t.\texon\t13\t24\t.\t+\t.\tgene_id S; transcript_id S1; gene_name \"SEL 1\"; tag basic;\n" +
+		"X\t.\tstart_codon\t1\t3\t.\t+\t.\tgene_id S; transcript_id S1; gene_name \"SEL 1\"; tag basic;\n" +
+		"X\t.\tstop_codon\t22\t24\t.\t+\t.\tgene_id S; transcript_id S1; gene_name \"SEL 1\"; tag basic;\n" +
+		"X\t.\tSelenocysteine\t4\t6\t.\t+\t.\tgene_id S; transcript_id S1; gene_name \"SEL 1\"; tag basic;\n" +
+		"X\t.\texon\t5\t20\t.\t+\t.\tgene_id S; transcript_id S2; gene_name \"SEL 1\"; transcript_name S-2;\n" +
+		"Y\t.\texon\t1\t13\t.\t-\t.\tgene_id N; transcript_id N1; tag cds_start_NF;\n" +
+		"Y\t.\tstop_codon\t1\t3\t.\t-\t.\tgene_id N; transcript_id N1; tag cds_start_NF;\n" +
+		"Y\t.\tCDS\t4\t13\t.\t-\t1\tgene_id N; transcript_id N1; tag cds_start_NF;\n"
+	r := ggff.NewReader(gff.NewReader(strings.NewReader(input)))
+	if err := r.SetAttributes(true); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	var buf bytes.Buffer
+	if err := Write(&buf, r); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	db, err := Load(buf.Bytes())
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	sc := geneio.NewScanner(db.NewReader())
+	var got []gene.Interface
+	for sc.Next() {
+		got = append(got, sc.Gene())
+	}
+	if err := sc.Error(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if out := writeGFF(t, got...); out != input {
+		t.Errorf("out genes=\n%s want\n%s", out, input)
+	}
+	if len(got) != 2 {
+		t.Fatalf("out len=%d want 2", len(got))
+	}
+	if attrs, want := geneio.Attributes(got[0]), map[string]string{"gene_name": "SEL 1"}; !reflect.DeepEqual(attrs, want) {
+		t.Errorf("out gene attributes=%v want %v", attrs, want)
+	}
+	a, ok := geneio.Transcripts(got[1])[0].(geneio.Annotated)
+	if !ok {
+		t.Fatalf("out N1 annotated=false want true")
+	}
+	if a.CodingPhase() != 1 {
+		t.Errorf("out phase=%d want 1", a.CodingPhase())
+	}
+}
+
+func TestLoadCorrupt(t *testing.T) {
+	data := build(t)
+	for _, tt := range []struct {
+		Name  string
+		Data  []byte
+		Error string
+	}{
+		{"Empty", nil, "genedb: not a gene database"},
+		{"Bad magic", append([]byte("GFF"), data[3:]...), "genedb: not a gene database"},
+		{"Truncated", data[:len(data)-1], "genedb: corrupt database"},
+		{"Wrapping span", withChroms(data, "chr1", 1, math.MaxUint64), "genedb: corrupt database"},
+		{"Long span", withChroms(data, "chr1", 2, 3), "genedb: corrupt database"},
+	} {
+		_, err := Load(tt.Data)
+		if err == nil || err.Error() != tt.Error {
+			t.Errorf("%s: error %q, want error %q", tt.Name, err, tt.Error)
+		}
+	}
+}
+
+// withChroms returns data with its reference sequence list replaced by the
+// single sequence name with first region entry first and n entries.
+func withChroms(data []byte, name string, first, n uint64) []byte {
+	sec := binary.AppendUvarint(nil, 1)
+	sec = appendString(sec, name)
+	sec = binary.AppendUvarint(sec, first)
+	sec = binary.AppendUvarint(sec, n)
+	field := func(i int) uint64 { return binary.LittleEndian.Uint64(data[len(magic)+8*i:]) }
+	shift := uint64(len(sec)) - (field(hRecords) - field(hChroms))
+	out := append([]byte(nil), data[:headerSize]...)
+	for i := hRecords; i <= hEnd; i++ {
+		binary.LittleEndian.PutUint64(out[len(magic)+8*i:], field(i)+shift)
+	}
+	out = append(out, sec...)
+	return append(out, data[field(hRecords):]...)
+}
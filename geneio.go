@@ -4,6 +4,7 @@ package geneio
 import (
 	"fmt"
 	"io"
+	"strings"
 
 	"github.com/biogo/biogo/feat"
 	"github.com/biogo/biogo/feat/gene"
@@ -120,9 +121,34 @@ type FeatureReader interface {
 // "codon_start" and "codon_stop". Features with the same GID that are not
 // read consecutively will result in different genes with the same GID being
 // created.
+//
+// Features that implement Tagger extend this for GENCODE style annotations.
+// A transcript tagged cds_start_NF or start_codon_NF that lacks a start codon,
+// or tagged cds_end_NF or stop_codon_NF that lacks a stop codon, takes the
+// missing end of its coding sequence from its "CDS" features, and the frame
+// of its 5' CDS feature, if it implements Framer, as its coding phase.
+// "Selenocysteine" features become translational exceptions. Coding
+// transcripts with tags or exceptions are read as *AnnotatedTranscript.
+//
+// Genes are read as *gene.Gene, and transcripts without tags, exceptions or a
+// coding phase as *gene.CodingTranscript or *gene.NonCodingTranscript, unless
+// attributes are read. If attribute reading is enabled with SetAttributes,
+// features that implement Attributer give attributes to genes and
+// transcripts. Attributes with the same value in every feature of a gene,
+// other than those starting with "transcript_" or "exon_", are attributes of
+// the gene, which is then read as *AttributedGene. The other attributes with
+// the same value in every feature of a transcript, other than those starting
+// with "exon_", are attributes of the transcript, which is then read as
+// *AnnotatedTranscript if it is coding and as *AttributedTranscript if not.
+//
+// If a sequence dictionary is set with SetSeqDict, every gene is checked to
+// be located on a sequence of the dictionary and to have no exon extending
+// past the end of that sequence.
 type GeneReader struct {
-	r   FeatureReader
-	blk *geneBlock
+	r     FeatureReader
+	blk   *geneBlock
+	dict  *SeqDict
+	attrs bool
 }
 
 // NewGeneReader returns a new GeneReader that reads from r.
@@ -130,6 +156,18 @@ func NewGeneReader(r FeatureReader) *GeneReader {
 	return &GeneReader{r: r}
 }
 
+// SetSeqDict sets the sequence dictionary that genes are validated against.
+// A nil dictionary disables validation.
+func (r *GeneReader) SetSeqDict(d *SeqDict) {
+	r.dict = d
+}
+
+// SetAttributes sets whether the attributes of features are read as the
+// attributes of genes and transcripts. Attributes are not read by default.
+func (r *GeneReader) SetAttributes(read bool) {
+	r.attrs = read
+}
+
 // Read reads one gene from r. At EOF, it returns nil and io.EOF. When Read
 // returns, r is past the last Feature incorporated in the returned gene.
 func (r *GeneReader) Read() (gene.Interface, error) {
@@ -137,20 +175,20 @@ func (r *GeneReader) Read() (gene.Interface, error) {
 		f, err := r.r.Read()
 		if err != nil {
 			if err == io.EOF && r.blk != nil {
-				g, err := r.blk.ToGene()
+				g, err := r.blk.readGene()
 				r.blk = nil
 				return g, err
 			}
 			return nil, err
 		}
 		if r.blk == nil {
-			r.blk = &geneBlock{ID: f.GID(), loc: f.Location(), ori: f.Orientation()}
+			r.blk = &geneBlock{ID: f.GID(), loc: f.Location(), ori: f.Orientation(), dict: r.dict, readAttrs: r.attrs}
 			r.blk.feats = append(r.blk.feats, f)
 			continue
 		}
 		if r.blk.ID != f.GID() {
-			g, err := r.blk.ToGene()
-			r.blk = &geneBlock{ID: f.GID(), loc: f.Location(), ori: f.Orientation()}
+			g, err := r.blk.readGene()
+			r.blk = &geneBlock{ID: f.GID(), loc: f.Location(), ori: f.Orientation(), dict: r.dict, readAttrs: r.attrs}
 			r.blk.feats = append(r.blk.feats, f)
 			return g, err
 		}
@@ -182,6 +220,21 @@ type geneBlock struct {
 	loc   feat.Feature
 	ori   feat.Orientation
 	feats []Feature
+	dict  *SeqDict
+
+	// readAttrs is whether attributes are read, and attrs holds the gene
+	// attributes of feats, set by ToGene.
+	readAttrs bool
+	attrs     map[string]string
+}
+
+// readGene returns the gene of geneBlk with its attributes.
+func (geneBlk *geneBlock) readGene() (gene.Interface, error) {
+	g, err := geneBlk.ToGene()
+	if err != nil {
+		return nil, err
+	}
+	return WithAttributes(g, geneBlk.attrs), nil
 }
 
 // ToGene creates and returns a gene. It also creates the transcripts
@@ -216,7 +269,19 @@ func (geneBlk *geneBlock) ToGene() (*gene.Gene, error) {
 		}
 	}
 
+	// Check the features against the sequence dictionary.
+	if geneBlk.dict != nil {
+		if err := geneBlk.checkDict(g); err != nil {
+			return nil, err
+		}
+	}
+
 	// Build the transcripts.
+	if geneBlk.readAttrs {
+		geneBlk.attrs = sharedAttributes(geneBlk.feats, func(key string) bool {
+			return !strings.HasPrefix(key, "transcript_") && !strings.HasPrefix(key, "exon_")
+		})
+	}
 	var features []feat.Feature
 	var i, j int
 	for i < len(geneBlk.feats) {
@@ -226,7 +291,7 @@ func (geneBlk *geneBlock) ToGene() (*gene.Gene, error) {
 				break
 			}
 		}
-		tr, err := newTrancript(g, tid, geneBlk.feats[i:j])
+		tr, err := newTrancript(g, tid, geneBlk.feats[i:j], geneBlk.readAttrs, geneBlk.attrs)
 		if err != nil {
 			return nil, err
 		}
@@ -242,21 +307,67 @@ func (geneBlk *geneBlock) ToGene() (*gene.Gene, error) {
 	return g, nil
 }
 
-// newTrancript creates and returns a new gene.Transcript from s. Returns nil
-// and the error if it encounters one.
-func newTrancript(g *gene.Gene, tid string, s []Feature) (gene.Transcript, error) {
+// checkDict checks that g is located on a sequence of the sequence dictionary
+// of geneBlk and that no exon extends past the end of the sequence. It returns
+// a FeaturesError with the offending features if it finds a violation.
+func (geneBlk *geneBlock) checkDict(g *gene.Gene) error {
+	length, ok := geneBlk.dict.Length(g.Chrom.Name())
+	if !ok {
+		return &FeaturesError{
+			Gene:  g,
+			Msg:   "geneio: unknown sequence " + g.Chrom.Name(),
+			Feats: geneBlk.feats,
+		}
+	}
+	var past []Feature
+	for _, f := range geneBlk.feats {
+		if f.Type() == "exon" && f.End() > length {
+			past = append(past, f)
+		}
+	}
+	if len(past) > 0 {
+		return &FeaturesError{
+			Gene:  g,
+			Msg:   "geneio: exons past the end of sequence " + g.Chrom.Name(),
+			Feats: past,
+		}
+	}
+	return nil
+}
+
+// newTrancript creates and returns a new gene.Transcript from s, with the
+// attributes of s that are not among the gene attributes gattrs if readAttrs
+// is true. Returns nil and the error if it encounters one.
+func newTrancript(g *gene.Gene, tid string, s []Feature, readAttrs bool, gattrs map[string]string) (gene.Transcript, error) {
 	startCodon := false
 	stopCodon := false
+	cds := false
 	for _, f := range s {
-		if f.Type() == "start_codon" {
+		switch f.Type() {
+		case "start_codon":
 			startCodon = true
-		}
-		if f.Type() == "stop_codon" {
+		case "stop_codon":
 			stopCodon = true
+		case "CDS":
+			cds = true
 		}
 	}
-	if startCodon && stopCodon {
-		return newCodingTranscript(g, tid, s)
+	tags := featureTags(s)
+	var attrs map[string]string
+	if readAttrs {
+		attrs = sharedAttributes(s, func(key string) bool {
+			_, ok := gattrs[key]
+			return !ok && !strings.HasPrefix(key, "exon_")
+		})
+	}
+	startFound := startCodon || cds && (hasTag(tags, TagCDSStartNF) || hasTag(tags, TagStartCodonNF))
+	stopFound := stopCodon || cds && (hasTag(tags, TagCDSEndNF) || hasTag(tags, TagStopCodonNF))
+	if startFound && stopFound {
+		t, err := newCodingTranscript(g, tid, s)
+		if err != nil {
+			return nil, err
+		}
+		return annotateTranscript(t, s, tags, attrs, startCodon), nil
 	} else if startCodon || stopCodon {
 		return nil, &FeaturesError{
 			Gene:  g,
@@ -264,11 +375,102 @@ func newTrancript(g *gene.Gene, tid string, s []Feature) (gene.Transcript, error
 			Feats: s,
 		}
 	}
-	return newNonCodingTranscript(g, tid, s)
+	t, err := newNonCodingTranscript(g, tid, s)
+	if err != nil {
+		return nil, err
+	}
+	return TranscriptWithAttributes(t, attrs), nil
+}
+
+// featureTags returns the distinct tags of the features of s that implement
+// Tagger in the order they are first seen.
+func featureTags(s []Feature) []string {
+	var tags []string
+	for _, f := range s {
+		tf, ok := f.(Tagger)
+		if !ok {
+			continue
+		}
+		for _, tag := range tf.Tags() {
+			if !hasTag(tags, tag) {
+				tags = append(tags, tag)
+			}
+		}
+	}
+	return tags
+}
+
+// sharedAttributes returns the attributes for which keep returns true that
+// have the same value in every feature of s, or nil if there are none or a
+// feature of s does not implement Attributer.
+func sharedAttributes(s []Feature, keep func(key string) bool) map[string]string {
+	var attrs map[string]string
+	for i, f := range s {
+		af, ok := f.(Attributer)
+		if !ok {
+			return nil
+		}
+		fa := af.Attributes()
+		if i == 0 {
+			for k, v := range fa {
+				if !keep(k) {
+					continue
+				}
+				if attrs == nil {
+					attrs = make(map[string]string)
+				}
+				attrs[k] = v
+			}
+			continue
+		}
+		for k, v := range attrs {
+			if fv, ok := fa[k]; !ok || fv != v {
+				delete(attrs, k)
+			}
+		}
+	}
+	if len(attrs) == 0 {
+		return nil
+	}
+	return attrs
+}
+
+// annotateTranscript returns t with the tags, translational exceptions and coding
+// phase found in s and the attributes attrs as an *AnnotatedTranscript, or t
+// itself if there are none. The phase is only taken from s if the start codon
+// was not found.
+func annotateTranscript(t *gene.CodingTranscript, s []Feature, tags []string, attrs map[string]string, startCodon bool) gene.Transcript {
+	off := t.Offset + t.Loc.Start()
+	reverse := RefOrientation(t) == feat.Reverse
+	at := &AnnotatedTranscript{CodingTranscript: t, Tags: tags, Attrs: attrs}
+	var first Feature
+	for _, f := range s {
+		switch f.Type() {
+		case "Selenocysteine":
+			x := Exception{Offset: f.Start() - off, AminoAcid: 'U'}
+			if reverse {
+				x.Offset = f.End() - 1 - off
+			}
+			at.Exceptions = append(at.Exceptions, x)
+		case "CDS":
+			if first == nil || !reverse && f.Start() < first.Start() || reverse && f.End() > first.End() {
+				first = f
+			}
+		}
+	}
+	if fr, ok := first.(Framer); ok && !startCodon && fr.Frame() > 0 {
+		at.Phase = fr.Frame()
+	}
+	if len(at.Tags) == 0 && len(at.Exceptions) == 0 && at.Phase == 0 && len(at.Attrs) == 0 {
+		return t
+	}
+	return at
 }
 
 // newCodingTranscript creates and returns a new gene.CodingTranscript from s.
-// Returns nil and an error if it encounters one.
+// An end of the coding sequence without a start or stop codon is taken from
+// the extent of the CDS features of s. Returns nil and an error if it
+// encounters one.
 func newCodingTranscript(
 	g *gene.Gene, tid string, s []Feature) (*gene.CodingTranscript, error) {
 
@@ -285,6 +487,8 @@ func newCodingTranscript(
 
 	// Parse Features.
 	var exons []gene.Exon
+	startCodon, stopCodon := false, false
+	cdsStart, cdsEnd := maxInt, 0
 	for _, f := range s {
 		switch f.Type() {
 		case "exon":
@@ -295,17 +499,37 @@ func newCodingTranscript(
 			}
 			exons = append(exons, e)
 		case "start_codon":
+			startCodon = true
 			if f.Orientation() == feat.Forward {
 				t.CDSstart = f.Start() - t.Offset - g.Offset
 			} else if f.Orientation() == feat.Reverse {
 				t.CDSend = f.End() - t.Offset - g.Offset
 			}
 		case "stop_codon":
+			stopCodon = true
 			if f.Orientation() == feat.Forward {
 				t.CDSend = f.End() - t.Offset - g.Offset
 			} else if f.Orientation() == feat.Reverse {
 				t.CDSstart = f.Start() - t.Offset - g.Offset
 			}
+		case "CDS":
+			if f.Start() < cdsStart {
+				cdsStart = f.Start()
+			}
+			if f.End() > cdsEnd {
+				cdsEnd = f.End()
+			}
+		}
+	}
+
+	// Take missing ends from the CDS features.
+	if cdsEnd > 0 {
+		reverse := g.Orient == feat.Reverse
+		if !startCodon && !reverse || !stopCodon && reverse {
+			t.CDSstart = cdsStart - t.Offset - g.Offset
+		}
+		if !stopCodon && !reverse || !startCodon && reverse {
+			t.CDSend = cdsEnd - t.Offset - g.Offset
 		}
 	}
 
@@ -0,0 +1,313 @@
+// Package genesort sorts and merges streams of genes.
+//
+// Genes are ordered by reference sequence, start and ID. Sequences are
+// compared either in natural order, where runs of digits compare
+// numerically so that "chr2" sorts before "chr10", or in the order of a
+// sequence dictionary. A Sorter holds a bounded number of genes in memory and
+// spills sorted runs to temporary newline-delimited JSON files, which hold
+// one gene per record and are merged on output.
+package genesort
+
+import (
+	"bufio"
+	"container/heap"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/biogo/biogo/feat/gene"
+	"github.com/go-bio/geneio"
+	"github.com/go-bio/geneio/json"
+)
+
+// Less reports whether gene a is ordered before gene b.
+type Less func(a, b gene.Interface) bool
+
+// Natural returns a Less that orders genes by reference sequence in natural
+// order, then by start and then by ID.
+func Natural() Less {
+	return byChrom(NaturalLess)
+}
+
+// Dict returns a Less that orders genes by reference sequence in the order
+// of d, then by start and then by ID. Sequences missing from d are ordered
+// last.
+func Dict(d *geneio.SeqDict) Less {
+	return byChrom(d.Less)
+}
+
+// byChrom returns a Less that orders genes by reference sequence with
+// chromLess, then by start and then by ID.
+func byChrom(chromLess func(a, b string) bool) Less {
+	return func(a, b gene.Interface) bool {
+		as, ar := geneio.RefPosition(a, 0)
+		bs, br := geneio.RefPosition(b, 0)
+		if ar.Name() != br.Name() {
+			return chromLess(ar.Name(), br.Name())
+		}
+		if as != bs {
+			return as < bs
+		}
+		return a.Name() < b.Name()
+	}
+}
+
+// NaturalLess reports whether a is ordered before b in natural order. Runs of
+// digits are compared by numeric value and other characters byte-wise.
+func NaturalLess(a, b string) bool {
+	for a != "" && b != "" {
+		if isDigit(a[0]) && isDigit(b[0]) {
+			da, db := digits(a), digits(b)
+			na, nb := trimZeros(a[:da]), trimZeros(b[:db])
+			if len(na) != len(nb) {
+				return len(na) < len(nb)
+			}
+			if na != nb {
+				return na < nb
+			}
+			a, b = a[da:], b[db:]
+			continue
+		}
+		if a[0] != b[0] {
+			return a[0] < b[0]
+		}
+		a, b = a[1:], b[1:]
+	}
+	return len(a) < len(b)
+}
+
+func isDigit(c byte) bool { return '0' <= c && c <= '9' }
+
+// digits returns the length of the run of digits at the start of s.
+func digits(s string) int {
+	i := 0
+	for i < len(s) && isDigit(s[i]) {
+		i++
+	}
+	return i
+}
+
+// trimZeros removes leading zeros from the digit run s.
+func trimZeros(s string) string {
+	for len(s) > 1 && s[0] == '0' {
+		s = s[1:]
+	}
+	return s
+}
+
+// Sorter sorts genes using bounded memory.
+type Sorter struct {
+	less   Less
+	max    int
+	dir    string
+	buf    []gene.Interface
+	spills []string
+	files  []*os.File
+}
+
+// NewSorter returns a new Sorter that orders genes by less and keeps at most
+// max genes in memory before spilling them to a temporary file in dir. If dir
+// is empty, the default directory for temporary files is used. A max of zero
+// or less keeps all genes in memory.
+func NewSorter(less Less, max int, dir string) *Sorter {
+	return &Sorter{less: less, max: max, dir: dir}
+}
+
+// Add adds g to s. It returns any error that occurs while spilling.
+func (s *Sorter) Add(g gene.Interface) error {
+	s.buf = append(s.buf, g)
+	if s.max > 0 && len(s.buf) >= s.max {
+		return s.spill()
+	}
+	return nil
+}
+
+// spill writes the sorted buffer to a temporary file.
+func (s *Sorter) spill() error {
+	s.sortBuf()
+	f, err := os.CreateTemp(s.dir, "genesort-*.jsonl")
+	if err != nil {
+		return err
+	}
+	s.spills = append(s.spills, f.Name())
+	bw := bufio.NewWriter(f)
+	w := json.NewWriter(bw)
+	for _, g := range s.buf {
+		if _, err := w.Write(g); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	s.buf = s.buf[:0]
+	return f.Close()
+}
+
+// sortBuf sorts the in-memory genes.
+func (s *Sorter) sortBuf() {
+	sort.SliceStable(s.buf, func(i, j int) bool { return s.less(s.buf[i], s.buf[j]) })
+}
+
+// Sort returns a Reader of all genes added to s in sorted order. No genes
+// may be added after calling Sort. Genes that were spilled are read back from
+// JSON records, so that each keeps its own boundary even next to a gene with
+// the same ID, along with its description, attributes and annotation. Close
+// must be called to remove the temporary files.
+func (s *Sorter) Sort() (geneio.Reader, error) {
+	s.sortBuf()
+	if len(s.spills) == 0 {
+		return &sliceReader{genes: s.buf}, nil
+	}
+	rs := []geneio.Reader{&sliceReader{genes: s.buf}}
+	for _, name := range s.spills {
+		f, err := os.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		s.files = append(s.files, f)
+		rs = append(rs, json.NewReader(f))
+	}
+	return Merge(s.less, rs...), nil
+}
+
+// Close closes and removes the temporary files of s.
+func (s *Sorter) Close() error {
+	var err error
+	for _, f := range s.files {
+		if e := f.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	for _, name := range s.spills {
+		if e := os.Remove(name); e != nil && err == nil {
+			err = e
+		}
+	}
+	s.files, s.spills = nil, nil
+	return err
+}
+
+// Sort reads all genes from r into a Sorter with the given parameters and
+// returns it ready for its Sort method to be called. On error, the Sorter is
+// closed.
+func Sort(r geneio.Reader, less Less, max int, dir string) (*Sorter, error) {
+	s := NewSorter(less, max, dir)
+	sc := geneio.NewScanner(r)
+	for sc.Next() {
+		if err := s.Add(sc.Gene()); err != nil {
+			s.Close()
+			return nil, err
+		}
+	}
+	if err := sc.Error(); err != nil {
+		s.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// sliceReader is a Reader that reads from a slice of genes.
+type sliceReader struct {
+	genes []gene.Interface
+}
+
+// Read implements geneio.Reader.
+func (r *sliceReader) Read() (gene.Interface, error) {
+	if len(r.genes) == 0 {
+		return nil, io.EOF
+	}
+	g := r.genes[0]
+	r.genes = r.genes[1:]
+	return g, nil
+}
+
+// Merge returns a Reader that merges the genes of the sorted readers rs into
+// a single sorted stream. Ties are broken by the order of rs. Reading fails
+// if one of rs is not sorted by less.
+func Merge(less Less, rs ...geneio.Reader) geneio.Reader {
+	return &mergeReader{less: less, rs: rs}
+}
+
+// mergeReader implements Merge with a heap of the head gene of each reader.
+type mergeReader struct {
+	less Less
+	rs   []geneio.Reader
+	h    *mergeHeap
+}
+
+// head is the next gene of reader i.
+type head struct {
+	g gene.Interface
+	i int
+}
+
+// mergeHeap is a min heap of heads.
+type mergeHeap struct {
+	less  Less
+	heads []head
+}
+
+func (h *mergeHeap) Len() int { return len(h.heads) }
+func (h *mergeHeap) Less(i, j int) bool {
+	a, b := h.heads[i], h.heads[j]
+	if h.less(a.g, b.g) {
+		return true
+	}
+	if h.less(b.g, a.g) {
+		return false
+	}
+	return a.i < b.i
+}
+func (h *mergeHeap) Swap(i, j int)      { h.heads[i], h.heads[j] = h.heads[j], h.heads[i] }
+func (h *mergeHeap) Push(x interface{}) { h.heads = append(h.heads, x.(head)) }
+func (h *mergeHeap) Pop() interface{} {
+	x := h.heads[len(h.heads)-1]
+	h.heads = h.heads[:len(h.heads)-1]
+	return x
+}
+
+// Read implements geneio.Reader.
+func (m *mergeReader) Read() (gene.Interface, error) {
+	if m.h == nil {
+		m.h = &mergeHeap{less: m.less}
+		for i := range m.rs {
+			if err := m.advance(i, nil); err != nil {
+				return nil, err
+			}
+		}
+		heap.Init(m.h)
+	}
+	if m.h.Len() == 0 {
+		return nil, io.EOF
+	}
+	top := heap.Pop(m.h).(head)
+	if err := m.advance(top.i, top.g); err != nil {
+		return nil, err
+	}
+	return top.g, nil
+}
+
+// advance pushes the next gene of reader i onto the heap, checking that it
+// is not ordered before prev.
+func (m *mergeReader) advance(i int, prev gene.Interface) error {
+	g, err := m.rs[i].Read()
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if prev != nil && m.less(g, prev) {
+		return fmt.Errorf("genesort: input %d is not sorted at gene %s", i, g.Name())
+	}
+	if prev == nil {
+		m.h.heads = append(m.h.heads, head{g: g, i: i})
+	} else {
+		heap.Push(m.h, head{g: g, i: i})
+	}
+	return nil
+}
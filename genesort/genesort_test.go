@@ -0,0 +1,154 @@
+package genesort
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/biogo/biogo/io/featio/gff"
+	"github.com/go-bio/geneio"
+	ggff "github.com/go-bio/geneio/gff"
+)
+
+var unsortedInput = "" +
+	"chr10\t.\texon\t11\t20\t0\t+\t.\tgene_id A; transcript_id A1;\n" +
+	"chr2\t.\texon\t51\t60\t0\t+\t.\tgene_id B; transcript_id B1;\n" +
+	"chr2\t.\texon\t11\t40\t0\t-\t.\tgene_id C; transcript_id C1;\n" +
+	"chr2\t.\tstop_codon\t11\t13\t0\t-\t.\tgene_id C; transcript_id C1;\n" +
+	"chr2\t.\tstart_codon\t38\t40\t0\t-\t.\tgene_id C; transcript_id C1;\n" +
+	"chrM\t.\texon\t1\t20\t0\t+\t.\tgene_id D; transcript_id D1;\n" +
+	"chr1\t.\texon\t11\t20\t0\t+\t.\tgene_id F; transcript_id F1;\n" +
+	"chr1\t.\texon\t11\t20\t0\t+\t.\tgene_id E; transcript_id E1;\n"
+
+func reader(input string) geneio.Reader {
+	return ggff.NewReader(gff.NewReader(strings.NewReader(input)))
+}
+
+// ids returns the IDs of the genes of r.
+func ids(r geneio.Reader) ([]string, error) {
+	var ids []string
+	sc := geneio.NewScanner(r)
+	for sc.Next() {
+		ids = append(ids, sc.Gene().Name())
+	}
+	return ids, sc.Error()
+}
+
+func TestNaturalLess(t *testing.T) {
+	names := []string{"chrX", "chr10", "chr2", "chr1", "chr02a", "chr2b", "chr"}
+	want := []string{"chr", "chr1", "chr2", "chr02a", "chr2b", "chr10", "chrX"}
+	for i := range names {
+		for j := i + 1; j < len(names); j++ {
+			if NaturalLess(names[j], names[i]) {
+				names[i], names[j] = names[j], names[i]
+			}
+		}
+	}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("out order=%q want %q", names, want)
+	}
+}
+
+// Test Sort
+func TestSort(t *testing.T) {
+	d := geneio.NewSeqDict()
+	for _, n := range []string{"chrM", "chr2", "chr1"} {
+		d.Add(n, 1000)
+	}
+	for _, tt := range []struct {
+		Name string
+		Less Less
+		Max  int
+		IDs  []string
+	}{
+		{"natural in memory", Natural(), 0, []string{"E", "F", "C", "B", "A", "D"}},
+		{"natural spilled", Natural(), 2, []string{"E", "F", "C", "B", "A", "D"}},
+		{"dictionary spilled", Dict(d), 3, []string{"D", "C", "B", "E", "F", "A"}},
+	} {
+		dir := t.TempDir()
+		s, err := Sort(reader(unsortedInput), tt.Less, tt.Max, dir)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tt.Name, err)
+			continue
+		}
+		r, err := s.Sort()
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tt.Name, err)
+			continue
+		}
+		got, err := ids(r)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tt.Name, err)
+		}
+		if !reflect.DeepEqual(got, tt.IDs) {
+			t.Errorf("%s: out ids=%q want %q", tt.Name, got, tt.IDs)
+		}
+		if err := s.Close(); err != nil {
+			t.Errorf("%s: unexpected error %v", tt.Name, err)
+		}
+		if left, _ := filepath.Glob(filepath.Join(dir, "*")); len(left) != 0 {
+			t.Errorf("%s: temporary files left: %q", tt.Name, left)
+		}
+	}
+}
+
+// Test that spilled genes keep their attributes and that genes with the same
+// ID on different sequences stay apart
+func TestSortSpilledPAR(t *testing.T) {
+	input := "" +
+		"chrY\t.\texon\t101\t200\t0\t+\t.\tgene_id P; transcript_id P1; gene_name PAR;\n" +
+		"chrM\t.\texon\t1\t20\t0\t+\t.\tgene_id Q; transcript_id Q1;\n" +
+		"chrX\t.\texon\t101\t200\t0\t+\t.\tgene_id P; transcript_id P1; gene_name PAR;\n"
+	d := geneio.NewSeqDict()
+	for _, n := range []string{"chrX", "chrY", "chrM"} {
+		d.Add(n, 1000)
+	}
+	r := ggff.NewReader(gff.NewReader(strings.NewReader(input)))
+	if err := r.SetAttributes(true); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	s, err := Sort(r, Dict(d), 3, t.TempDir())
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	defer s.Close()
+	sr, err := s.Sort()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	var got []string
+	sc := geneio.NewScanner(sr)
+	for sc.Next() {
+		g := sc.Gene()
+		got = append(got, g.Name()+" "+g.Location().Name()+" "+geneio.Attributes(g)["gene_name"])
+	}
+	if err := sc.Error(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if want := []string{"P chrX PAR", "P chrY PAR", "Q chrM "}; !reflect.DeepEqual(got, want) {
+		t.Errorf("out genes=%q want %q", got, want)
+	}
+}
+
+// Test Merge
+func TestMerge(t *testing.T) {
+	a := "" +
+		"chr1\t.\texon\t11\t20\t0\t+\t.\tgene_id A; transcript_id A1;\n" +
+		"chr2\t.\texon\t11\t20\t0\t+\t.\tgene_id C; transcript_id C1;\n"
+	b := "" +
+		"chr1\t.\texon\t15\t20\t0\t+\t.\tgene_id B; transcript_id B1;\n" +
+		"chr10\t.\texon\t11\t20\t0\t+\t.\tgene_id D; transcript_id D1;\n"
+	got, err := ids(Merge(Natural(), reader(a), reader(b), reader("")))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if want := []string{"A", "B", "C", "D"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("out ids=%q want %q", got, want)
+	}
+
+	_, err = ids(Merge(Natural(), reader(b), reader(unsortedInput)))
+	if want := "genesort: input 1 is not sorted at gene B"; err == nil || err.Error() != want {
+		t.Errorf("error %q, want error %q", err, want)
+	}
+}
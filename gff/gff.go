@@ -1,4 +1,4 @@
-// Package gff reads genes from a GFF v2 file.
+// Package gff reads and writes genes in GFF v2 format.
 //
 // It requires that GFF entries, particularly entries of type exon,
 // start_codon and stop_codon are grouped into genes and transcripts based on
@@ -17,12 +17,18 @@ package gff
 
 import (
 	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/biogo/biogo/feat"
 	"github.com/biogo/biogo/feat/gene"
 	"github.com/biogo/biogo/io/featio"
 	"github.com/biogo/biogo/io/featio/gff"
 	"github.com/go-bio/geneio"
+	"github.com/go-bio/geneio/phase"
 )
 
 // A Reader reads genes from a GFF v2 file.
@@ -31,10 +37,21 @@ import (
 // transcripts and genes respectively. Group tags can be changed by SetGeneTag
 // and SetTranscriptTag to customize the details before the first call to Read
 // or ReadAll.
+//
+// The frame column is ignored unless frame checking is enabled with
+// SetCheckFrames. The frames of CDS, start_codon and stop_codon entries are
+// then checked against the phases computed from the transcripts that are
+// read, and disagreements are available from FrameErrors.
+//
+// If attribute reading is enabled with SetAttributes, attributes other than
+// the group tags and tag are read as the attributes of genes and transcripts
+// as described for geneio.GeneReader, so that, for example, the gene_name
+// attribute of GENCODE records names the gene.
 type Reader struct {
 	r         *geneio.GeneReader
 	fr        *featureReader
 	afterRead bool
+	frameErrs []*phase.Error
 }
 
 // NewReader returns a new Reader that reads from r. It sets transcript and
@@ -52,7 +69,26 @@ func NewReader(r featio.Reader) *Reader {
 // returns, r is past the last feature incorporated in the returned gene.
 func (r *Reader) Read() (gene.Interface, error) {
 	r.afterRead = true
-	return r.r.Read()
+	g, err := r.r.Read()
+	if r.fr.frames == nil {
+		return g, err
+	}
+	if err != nil {
+		// Drop the records of a gene that failed to build. Only the gene of
+		// the last feature read can still be pending.
+		for gid := range r.fr.frames {
+			if r.fr.eof || gid != r.fr.gid {
+				delete(r.fr.frames, gid)
+			}
+		}
+		return nil, err
+	}
+	recs := r.fr.frames[g.Name()]
+	delete(r.fr.frames, g.Name())
+	for _, t := range geneio.Transcripts(g) {
+		r.frameErrs = append(r.frameErrs, phase.Check(g.Name(), t, recs[t.Name()])...)
+	}
+	return g, nil
 }
 
 // ReadAll reads all the remaining genes from r. A successful call returns err
@@ -60,8 +96,17 @@ func (r *Reader) Read() (gene.Interface, error) {
 // does not treat end of file as an error to be reported. It returns a nil
 // slice and an error if it encounters one.
 func (r *Reader) ReadAll() ([]gene.Interface, error) {
-	r.afterRead = true
-	return r.r.ReadAll()
+	var genes []gene.Interface
+	for {
+		g, err := r.Read()
+		if err == io.EOF {
+			return genes, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		genes = append(genes, g)
+	}
 }
 
 // SetGeneTag sets the gene group tag. It is set to ('gene_id') by NewReader.
@@ -74,6 +119,48 @@ func (r *Reader) SetGeneTag(tag string) error {
 	return nil
 }
 
+// SetSeqDict sets the sequence dictionary that genes are validated against.
+// See geneio.GeneReader for details. The dictionary can only be changed before
+// the first call to Read or ReadAll.
+func (r *Reader) SetSeqDict(d *geneio.SeqDict) error {
+	if r.afterRead {
+		return errors.New("gff: cannot set SeqDict after first call to Read")
+	}
+	r.r.SetSeqDict(d)
+	return nil
+}
+
+// SetAttributes sets whether attributes are read as the attributes of genes
+// and transcripts. See geneio.GeneReader for details. Attribute reading can
+// only be changed before the first call to Read or ReadAll.
+func (r *Reader) SetAttributes(read bool) error {
+	if r.afterRead {
+		return errors.New("gff: cannot set Attributes after first call to Read")
+	}
+	r.r.SetAttributes(read)
+	return nil
+}
+
+// SetCheckFrames sets whether the frames of CDS, start_codon and stop_codon
+// entries are checked against the computed phases. Frame checking can only be
+// changed before the first call to Read or ReadAll.
+func (r *Reader) SetCheckFrames(check bool) error {
+	if r.afterRead {
+		return errors.New("gff: cannot set CheckFrames after first call to Read")
+	}
+	r.fr.frames = nil
+	if check {
+		r.fr.frames = make(map[string]map[string][]phase.Record)
+	}
+	return nil
+}
+
+// FrameErrors returns the frame disagreements found in the genes read so
+// far when frame checking is enabled.
+func (r *Reader) FrameErrors() []*phase.Error {
+	return r.frameErrs
+}
+
 // SetTranscriptTag sets the transcript group tag. It is set to
 // ('transcript_id') by NewReader. Tag can only be changed before the first
 // call to Read or ReadAll.
@@ -89,15 +176,52 @@ func (r *Reader) SetTranscriptTag(tag string) error {
 type featureReader struct {
 	r                      featio.Reader
 	GeneTag, TranscriptTag string
+
+	// frames holds the framed records of the features read, keyed by gene
+	// and transcript ID, if frame checking is enabled.
+	frames map[string]map[string][]phase.Record
+	// gid is the gene ID of the last feature read and eof is whether the
+	// end of the input was reached.
+	gid string
+	eof bool
 }
 
 // Read implements geneio.FeatureReader.
 func (r *featureReader) Read() (geneio.Feature, error) {
 	f, err := r.r.Read()
+	if err != nil {
+		r.eof = err == io.EOF
+		return nil, err
+	}
+	nf, err := r.NewFeature(f)
 	if err != nil {
 		return nil, err
 	}
-	return r.NewFeature(f)
+	r.gid = nf.fgid
+	if r.frames != nil && nf.frame >= 0 {
+		switch nf.ftype {
+		case "CDS", "start_codon", "stop_codon":
+			ts, ok := r.frames[nf.fgid]
+			if !ok {
+				ts = make(map[string][]phase.Record)
+				r.frames[nf.fgid] = ts
+			}
+			ts[nf.ftid] = append(ts[nf.ftid], phase.Record{Type: nf.ftype, Start: nf.Start(), End: nf.End(), Frame: nf.frame})
+		}
+	}
+	return nf, nil
+}
+
+// unquote returns s without the double quotes of a quoted GTF attribute
+// value, or s itself if it is not quoted.
+func unquote(s string) string {
+	if len(s) < 2 || s[0] != '"' || s[len(s)-1] != '"' {
+		return s
+	}
+	if u, err := strconv.Unquote(s); err == nil {
+		return u
+	}
+	return s[1 : len(s)-1]
 }
 
 // NewFeature converts f to *feature and returns it.
@@ -115,12 +239,33 @@ func (r *featureReader) NewFeature(f feat.Feature) (*feature, error) {
 		return nil, errors.New("gff: empty grouping " + r.TranscriptTag + " field")
 	}
 
+	var tags []string
+	var attrs map[string]string
+	for _, a := range gf.FeatAttributes {
+		switch a.Tag {
+		case "tag":
+			tags = append(tags, unquote(a.Value))
+		case r.GeneTag, r.TranscriptTag:
+		default:
+			if _, ok := attrs[a.Tag]; ok {
+				continue
+			}
+			if attrs == nil {
+				attrs = make(map[string]string)
+			}
+			attrs[a.Tag] = unquote(a.Value)
+		}
+	}
+
 	return &feature{
 		Feature: f,
 		fgid:    gid,
 		ftid:    tid,
 		ftype:   gf.Feature,
 		ori:     feat.Orientation(gf.FeatStrand),
+		frame:   int(gf.FeatFrame),
+		tags:    tags,
+		attrs:   attrs,
 	}, nil
 }
 
@@ -129,9 +274,187 @@ type feature struct {
 	feat.Feature
 	ori               feat.Orientation
 	fgid, ftid, ftype string
+	frame             int
+	tags              []string
+	attrs             map[string]string
 }
 
 func (f *feature) GID() string                   { return f.fgid }
 func (f *feature) TID() string                   { return f.ftid }
 func (f *feature) Type() string                  { return f.ftype }
 func (f *feature) Orientation() feat.Orientation { return f.ori }
+
+// Frame returns the frame of f, or -1 if it has none.
+func (f *feature) Frame() int { return f.frame }
+
+// Tags returns the values of the tag attributes of f.
+func (f *feature) Tags() []string { return f.tags }
+
+// Attributes returns the attributes of f other than the group tags and the
+// tag attributes. Only the first value of a repeated attribute is kept.
+func (f *feature) Attributes() map[string]string { return f.attrs }
+
+// A Writer writes genes as GFF v2 lines.
+//
+// Each transcript is written as its exon lines followed, for coding
+// transcripts, by a start_codon and a stop_codon line, which is the structure
+// that Reader expects. Group tags can be changed with the GeneTag and
+// TranscriptTag fields and the source column with the Source field. Tag values
+// are written unquoted.
+//
+// If CDS is set, coding transcripts also have CDS lines for the coding
+// sequence without the stop codon, as in GTF, with their phases in the frame
+// column.
+//
+// The attributes of genes and transcripts that implement geneio.Attributer
+// are written on each of their lines after the group tags, in key order and
+// quoted if they hold spaces, semicolons or quotes or are empty. The tags of
+// geneio.Annotated transcripts are written as tag attributes on each of their
+// lines and their translational exceptions as Selenocysteine
+// lines. A start or stop codon line is not written for a coding sequence end
+// that is tagged as not found, and such transcripts always have CDS lines.
+type Writer struct {
+	w                      io.Writer
+	GeneTag, TranscriptTag string
+	Source                 string
+	CDS                    bool
+}
+
+// NewWriter returns a new Writer that writes to w. It sets transcript and
+// gene group tag to "transcript_id" and "gene_id" respectively and the source
+// to ".".
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{
+		w:             w,
+		GeneTag:       "gene_id",
+		TranscriptTag: "transcript_id",
+		Source:        ".",
+	}
+}
+
+// Write writes g to the underlying writer, returning the number of bytes
+// written and any error that occurs during the write.
+func (w *Writer) Write(g gene.Interface) (n int, err error) {
+	for _, t := range geneio.Transcripts(g) {
+		start, ref := geneio.RefPosition(t, 0)
+		strand := strandSymbol(geneio.RefOrientation(t))
+		tags := formatAttributes(geneio.Attributes(g)) + formatAttributes(geneio.Attributes(t))
+		if a, ok := t.(geneio.Annotated); ok {
+			for _, tag := range a.TranscriptTags() {
+				tags += " tag " + tag + ";"
+			}
+		}
+		framed := func(typ string, s, e int, frame byte) error {
+			c, err := fmt.Fprintf(w.w, "%s\t%s\t%s\t%d\t%d\t.\t%c\t%c\t%s %s; %s %s;%s\n",
+				ref.Name(), w.Source, typ, start+s+1, start+e, strand, frame,
+				w.GeneTag, g.Name(), w.TranscriptTag, t.Name(), tags)
+			n += c
+			return err
+		}
+		line := func(typ string, s, e int) error { return framed(typ, s, e, '.') }
+		for _, e := range t.Exons() {
+			if err := line("exon", e.Start(), e.End()); err != nil {
+				return n, err
+			}
+		}
+		ct, ok := geneio.Coding(t)
+		if !ok {
+			continue
+		}
+		startNF, endNF := geneio.StartNotFound(t), geneio.EndNotFound(t)
+		first, last := "start_codon", "stop_codon"
+		firstNF, lastNF := startNF, endNF
+		if strand == '-' {
+			first, last = last, first
+			firstNF, lastNF = lastNF, firstNF
+		}
+		if !firstNF {
+			if err := line(first, ct.CDSstart, ct.CDSstart+3); err != nil {
+				return n, err
+			}
+		}
+		if !lastNF {
+			if err := line(last, ct.CDSend-3, ct.CDSend); err != nil {
+				return n, err
+			}
+		}
+		if a, ok := t.(geneio.Annotated); ok {
+			for _, x := range a.TranslationExceptions() {
+				s := x.Offset
+				if strand == '-' {
+					s -= 2
+				}
+				if err := line("Selenocysteine", s, s+3); err != nil {
+					return n, err
+				}
+			}
+		}
+		if !w.CDS && !startNF && !endNF {
+			continue
+		}
+		for _, seg := range cdsSegments(t, !endNF) {
+			if err := framed("CDS", seg.Start-start, seg.End-start, byte('0'+seg.Phase)); err != nil {
+				return n, err
+			}
+		}
+	}
+	return n, nil
+}
+
+// formatAttributes returns attrs formatted as GFF v2 attributes in key order,
+// each preceded by a space.
+func formatAttributes(attrs map[string]string) string {
+	keys := make([]string, 0, len(attrs))
+	for k := range attrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	for _, k := range keys {
+		v := attrs[k]
+		if v == "" || strings.ContainsAny(v, " \t;\"") {
+			v = strconv.Quote(v)
+		}
+		fmt.Fprintf(&b, " %s %s;", k, v)
+	}
+	return b.String()
+}
+
+// cdsSegments returns the coding sequence segments of t in increasing
+// reference order, without the stop codon if stop is true.
+func cdsSegments(t gene.Transcript, stop bool) []phase.Segment {
+	segs := phase.Segments(t)
+	reverse := geneio.RefOrientation(t) == feat.Reverse
+	trim := 0
+	if stop {
+		trim = 3
+	}
+	for trim > 0 && len(segs) > 0 {
+		last := &segs[len(segs)-1]
+		l := last.End - last.Start
+		if l <= trim {
+			segs = segs[:len(segs)-1]
+			trim -= l
+			continue
+		}
+		if reverse {
+			last.Start += trim
+		} else {
+			last.End -= trim
+		}
+		trim = 0
+	}
+	sort.Slice(segs, func(i, j int) bool { return segs[i].Start < segs[j].Start })
+	return segs
+}
+
+// strandSymbol returns the GFF strand symbol for o.
+func strandSymbol(o feat.Orientation) byte {
+	switch o {
+	case feat.Forward:
+		return '+'
+	case feat.Reverse:
+		return '-'
+	}
+	return '.'
+}
@@ -8,13 +8,16 @@ import (
 	"testing"
 
 	"github.com/biogo/biogo/feat"
+	"github.com/biogo/biogo/feat/gene"
 	"github.com/biogo/biogo/io/featio/gff"
 	"github.com/go-bio/geneio"
 )
 
 // Assert that interfaces are satisfied.
 var (
-	_ geneio.Reader = (*Reader)(nil)
+	_ geneio.Reader     = (*Reader)(nil)
+	_ geneio.Writer     = (*Writer)(nil)
+	_ geneio.Attributer = (*feature)(nil)
 )
 
 // Test Read
@@ -196,6 +199,10 @@ func TestRead(t *testing.T) {
 			t.Errorf("%s: expected error %q", tt.Name,
 				"gff: cannot set TranscriptTag after first call to Read")
 		}
+		if err := r.SetSeqDict(nil); err == nil {
+			t.Errorf("%s: expected error %q", tt.Name,
+				"gff: cannot set SeqDict after first call to Read")
+		}
 
 		var feats []feat.Feature
 		var ids, chrs []string
@@ -233,6 +240,78 @@ func TestRead(t *testing.T) {
 	}
 }
 
+// Test that attributes shared by the features of a gene or transcript are
+// read as gene and transcript attributes
+func TestReadAttributes(t *testing.T) {
+	input := "" +
+		"X\t.\texon\t2\t70\t.\t+\t.\tgene_id C; transcript_id C1; gene_name ABC; transcript_name \"ABC-1\"; exon_number 1;\n" +
+		"X\t.\texon\t80\t90\t.\t+\t.\tgene_id C; transcript_id C1; gene_name ABC; transcript_name \"ABC-1\"; exon_number 2;\n" +
+		"X\t.\tstart_codon\t60\t62\t.\t+\t.\tgene_id C; transcript_id C1; gene_name ABC; transcript_name \"ABC-1\";\n" +
+		"X\t.\tstop_codon\t81\t83\t.\t+\t.\tgene_id C; transcript_id C1; gene_name ABC; transcript_name \"ABC-1\";\n" +
+		"X\t.\texon\t5\t95\t.\t+\t.\tgene_id C; transcript_id C2; gene_name ABC; transcript_name ABC-2; exon_number 1;\n" +
+		"Y\t.\texon\t10\t20\t.\t-\t.\tgene_id E; transcript_id E1;\n"
+	genes, err := NewReader(gff.NewReader(strings.NewReader(input))).ReadAll()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if _, ok := genes[0].(*gene.Gene); !ok {
+		t.Errorf("C: out type=%T want *gene.Gene", genes[0])
+	}
+	if _, ok := geneio.Transcripts(genes[0])[0].(*gene.CodingTranscript); !ok {
+		t.Errorf("C1: out type=%T want *gene.CodingTranscript", geneio.Transcripts(genes[0])[0])
+	}
+
+	r := NewReader(gff.NewReader(strings.NewReader(input)))
+	if err := r.SetAttributes(true); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	genes, err = r.ReadAll()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	for _, test := range []struct {
+		name  string
+		f     interface{}
+		attrs map[string]string
+	}{
+		{name: "C", f: genes[0], attrs: map[string]string{"gene_name": "ABC"}},
+		{name: "C1", f: geneio.Transcripts(genes[0])[0], attrs: map[string]string{"transcript_name": "ABC-1"}},
+		{name: "C2", f: geneio.Transcripts(genes[0])[1], attrs: map[string]string{"transcript_name": "ABC-2"}},
+		{name: "E", f: genes[1]},
+		{name: "E1", f: geneio.Transcripts(genes[1])[0]},
+	} {
+		if got := geneio.Attributes(test.f); !reflect.DeepEqual(got, test.attrs) {
+			t.Errorf("%s: out attributes=%v want %v", test.name, got, test.attrs)
+		}
+	}
+	if _, ok := geneio.Coding(geneio.Transcripts(genes[0])[0]); !ok {
+		t.Errorf("C1: out coding=false want true")
+	}
+	if _, ok := geneio.NonCoding(geneio.Transcripts(genes[0])[1]); !ok {
+		t.Errorf("C2: out non coding=false want true")
+	}
+}
+
+// Test that quoted GENCODE tags mark a coding sequence whose start was not
+// found
+func TestReadQuotedTags(t *testing.T) {
+	input := "" +
+		"Y\t.\texon\t1\t13\t.\t-\t.\tgene_id N; transcript_id N1; tag \"basic\"; tag \"cds_start_NF\";\n" +
+		"Y\t.\tstop_codon\t1\t3\t.\t-\t.\tgene_id N; transcript_id N1; tag \"basic\"; tag \"cds_start_NF\";\n" +
+		"Y\t.\tCDS\t4\t13\t.\t-\t1\tgene_id N; transcript_id N1; tag \"basic\"; tag \"cds_start_NF\";\n"
+	genes, err := NewReader(gff.NewReader(strings.NewReader(input))).ReadAll()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	n1 := geneio.Transcripts(genes[0])[0]
+	if !geneio.StartNotFound(n1) {
+		t.Errorf("out start not found=false want true")
+	}
+	if !geneio.HasTag(n1, "basic") {
+		t.Errorf("out tagged=false want true")
+	}
+}
+
 func BenchmarkReadSorted(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		tt := readTests[0]
@@ -263,3 +342,195 @@ Y	.	start_codon	71	73	0	-	.	gene_id A; transcript_id A1`
 		// gene: A, transcript: A1
 	}
 }
+
+// Test Write
+var writeTests = []struct {
+	Name  string
+	Input string
+}{
+	{
+		Name: "Forward coding and non coding transcripts",
+		Input: "" +
+			"X\t.\texon\t2\t70\t.\t+\t.\tgene_id C; transcript_id C1;\n" +
+			"X\t.\texon\t80\t90\t.\t+\t.\tgene_id C; transcript_id C1;\n" +
+			"X\t.\tstart_codon\t60\t62\t.\t+\t.\tgene_id C; transcript_id C1;\n" +
+			"X\t.\tstop_codon\t81\t83\t.\t+\t.\tgene_id C; transcript_id C1;\n" +
+			"X\t.\texon\t5\t95\t.\t+\t.\tgene_id C; transcript_id C2;\n",
+	},
+	{
+		Name: "Reverse coding transcript",
+		Input: "" +
+			"X\t.\texon\t30\t50\t.\t-\t.\tgene_id D; transcript_id D1;\n" +
+			"X\t.\texon\t80\t99\t.\t-\t.\tgene_id D; transcript_id D1;\n" +
+			"X\t.\tstop_codon\t40\t42\t.\t-\t.\tgene_id D; transcript_id D1;\n" +
+			"X\t.\tstart_codon\t91\t93\t.\t-\t.\tgene_id D; transcript_id D1;\n" +
+			"Y\t.\texon\t10\t20\t.\t-\t.\tgene_id E; transcript_id E1;\n",
+	},
+	{
+		Name: "Tagged transcripts with a selenocysteine and a start not found",
+		Input: "" +
+			"X\t.\texon\t1\t9\t.\t+\t.\tgene_id S; transcript_id S1; tag basic;\n" +
+			"X\t.\texon\t13\t24\t.\t+\t.\tgene_id S; transcript_id S1; tag basic;\n" +
+			"X\t.\tstart_codon\t1\t3\t.\t+\t.\tgene_id S; transcript_id S1; tag basic;\n" +
+			"X\t.\tstop_codon\t22\t24\t.\t+\t.\tgene_id S; transcript_id S1; tag basic;\n" +
+			"X\t.\tSelenocysteine\t4\t6\t.\t+\t.\tgene_id S; transcript_id S1; tag basic;\n" +
+			"Y\t.\texon\t1\t13\t.\t-\t.\tgene_id N; transcript_id N1; tag cds_start_NF;\n" +
+			"Y\t.\tstop_codon\t1\t3\t.\t-\t.\tgene_id N; transcript_id N1; tag cds_start_NF;\n" +
+			"Y\t.\tCDS\t4\t13\t.\t-\t1\tgene_id N; transcript_id N1; tag cds_start_NF;\n",
+	},
+	{
+		Name: "Gene and transcript attributes",
+		Input: "" +
+			"X\t.\texon\t11\t20\t.\t+\t.\tgene_id G; transcript_id G1; gene_name \"ABC 1\"; gene_type protein_coding; transcript_name G-1;\n" +
+			"X\t.\texon\t31\t40\t.\t+\t.\tgene_id G; transcript_id G1; gene_name \"ABC 1\"; gene_type protein_coding; transcript_name G-1;\n" +
+			"X\t.\tstart_codon\t11\t13\t.\t+\t.\tgene_id G; transcript_id G1; gene_name \"ABC 1\"; gene_type protein_coding; transcript_name G-1;\n" +
+			"X\t.\tstop_codon\t38\t40\t.\t+\t.\tgene_id G; transcript_id G1; gene_name \"ABC 1\"; gene_type protein_coding; transcript_name G-1;\n" +
+			"X\t.\texon\t15\t40\t.\t+\t.\tgene_id G; transcript_id G2; gene_name \"ABC 1\"; gene_type protein_coding; transcript_name G-2;\n",
+	},
+}
+
+func TestWrite(t *testing.T) {
+	for _, tt := range writeTests {
+		r := NewReader(gff.NewReader(strings.NewReader(tt.Input)))
+		if err := r.SetAttributes(true); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		genes, err := r.ReadAll()
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tt.Name, err)
+			continue
+		}
+		var buf strings.Builder
+		w := NewWriter(&buf)
+		total := 0
+		for _, g := range genes {
+			n, err := w.Write(g)
+			if err != nil {
+				t.Errorf("%s: unexpected error %v", tt.Name, err)
+			}
+			total += n
+		}
+		if buf.String() != tt.Input {
+			t.Errorf("%s: out=\n%s\nwant\n%s", tt.Name, buf.String(), tt.Input)
+		}
+		if total != buf.Len() {
+			t.Errorf("%s: out byte count=%d want %d", tt.Name, total, buf.Len())
+		}
+	}
+}
+
+var frameInput = "" +
+	"X\t.\texon\t11\t20\t.\t+\t.\tgene_id A; transcript_id A1;\n" +
+	"X\t.\texon\t31\t40\t.\t+\t.\tgene_id A; transcript_id A1;\n" +
+	"X\t.\tstart_codon\t16\t18\t.\t+\t0\tgene_id A; transcript_id A1;\n" +
+	"X\t.\tstop_codon\t32\t34\t.\t+\t0\tgene_id A; transcript_id A1;\n" +
+	"X\t.\tCDS\t16\t20\t.\t+\t0\tgene_id A; transcript_id A1;\n" +
+	"X\t.\tCDS\t31\t31\t.\t+\t2\tgene_id A; transcript_id A1;\n" +
+	"X\t.\texon\t11\t40\t.\t+\t.\tgene_id A; transcript_id A2;\n" +
+	"X\t.\tCDS\t16\t20\t.\t+\t0\tgene_id A; transcript_id A2;\n" +
+	"Y\t.\texon\t11\t20\t.\t-\t.\tgene_id B; transcript_id B1;\n" +
+	"Y\t.\texon\t31\t40\t.\t-\t.\tgene_id B; transcript_id B1;\n" +
+	"Y\t.\tstop_codon\t14\t16\t.\t-\t0\tgene_id B; transcript_id B1;\n" +
+	"Y\t.\tstart_codon\t36\t38\t.\t-\t0\tgene_id B; transcript_id B1;\n" +
+	"Y\t.\tCDS\t17\t20\t.\t-\t1\tgene_id B; transcript_id B1;\n" +
+	"Y\t.\tCDS\t31\t38\t.\t-\t0\tgene_id B; transcript_id B1;\n"
+
+// Test SetCheckFrames and FrameErrors
+func TestCheckFrames(t *testing.T) {
+	r := NewReader(gff.NewReader(strings.NewReader(frameInput)))
+	if err := r.SetCheckFrames(true); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if _, err := r.ReadAll(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	want := []string{
+		"phase: CDS at 31-31 of transcript A1 of gene A has frame 2, want 1",
+		"phase: CDS at 16-20 of transcript A2 of gene A is outside the coding sequence",
+	}
+	var got []string
+	for _, err := range r.FrameErrors() {
+		got = append(got, err.Error())
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("out errors=%q want %q", got, want)
+	}
+	err := r.SetCheckFrames(false)
+	if err == nil || err.Error() != "gff: cannot set CheckFrames after first call to Read" {
+		t.Errorf("error %v, want error after first call to Read", err)
+	}
+
+	r = NewReader(gff.NewReader(strings.NewReader(frameInput)))
+	if _, err := r.ReadAll(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if errs := r.FrameErrors(); len(errs) != 0 {
+		t.Errorf("out errors=%v want none without frame checking", errs)
+	}
+}
+
+// Test that the frames of a gene that fails to build are not checked against
+// a later gene with the same ID
+func TestCheckFramesAfterError(t *testing.T) {
+	input := "" +
+		"X\t.\texon\t11\t40\t.\t+\t.\tgene_id C; transcript_id C1;\n" +
+		"X\t.\tstart_codon\t16\t18\t.\t+\t0\tgene_id C; transcript_id C1;\n" +
+		"X\t.\tCDS\t16\t40\t.\t+\t1\tgene_id C; transcript_id C1;\n" +
+		"Y\t.\texon\t11\t20\t.\t+\t.\tgene_id D; transcript_id D1;\n" +
+		"X\t.\texon\t11\t40\t.\t+\t.\tgene_id C; transcript_id C1;\n" +
+		"X\t.\tstart_codon\t16\t18\t.\t+\t0\tgene_id C; transcript_id C1;\n" +
+		"X\t.\tstop_codon\t34\t36\t.\t+\t0\tgene_id C; transcript_id C1;\n" +
+		"X\t.\tCDS\t16\t33\t.\t+\t0\tgene_id C; transcript_id C1;\n"
+	r := NewReader(gff.NewReader(strings.NewReader(input)))
+	if err := r.SetCheckFrames(true); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	var ids []string
+	for {
+		g, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			continue
+		}
+		ids = append(ids, g.Name())
+	}
+	if want := []string{"D", "C"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("out ids=%q want %q", ids, want)
+	}
+	if errs := r.FrameErrors(); len(errs) != 0 {
+		t.Errorf("out errors=%v want none", errs)
+	}
+}
+
+// Test Write with CDS lines
+func TestWriteCDS(t *testing.T) {
+	genes, err := NewReader(gff.NewReader(strings.NewReader(frameInput))).ReadAll()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	var buf strings.Builder
+	w := NewWriter(&buf)
+	w.CDS = true
+	for _, g := range genes {
+		if _, err := w.Write(g); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+	}
+	var got []string
+	for _, l := range strings.Split(buf.String(), "\n") {
+		if f := strings.Split(l, "\t"); len(f) > 7 && f[2] == "CDS" {
+			got = append(got, strings.Join([]string{f[0], f[3], f[4], f[6], f[7]}, " "))
+		}
+	}
+	want := []string{
+		"X 16 20 + 0",
+		"X 31 31 + 1",
+		"Y 17 20 - 1",
+		"Y 31 38 - 0",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("out CDS lines=%q want %q", got, want)
+	}
+}
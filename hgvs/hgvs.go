@@ -0,0 +1,368 @@
+// Package hgvs parses and generates HGVS variant descriptions for gene
+// models.
+//
+// Descriptions in coding (c.), non coding (n.) and protein (p.) coordinates
+// are resolved against the transcripts of a catalog.Catalog to genomic
+// coordinates, and genomic variants are described in c. coordinates for
+// coding transcripts and n. coordinates for non coding ones. Generation does
+// not apply the HGVS 3' shifting rule or detect duplications, since both
+// need the reference sequence; variants should be normalized beforehand.
+package hgvs
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/go-bio/geneio"
+	"github.com/go-bio/geneio/catalog"
+	"github.com/go-bio/geneio/coordmap"
+)
+
+// Position is a position of a description. For c. descriptions it is a
+// coding sequence position as described by coordmap.CDSPos. For n.
+// descriptions Pos is the one based transcript position, negative before
+// the transcript and, with UTR3 set, counted after its end. For p.
+// descriptions Pos is the codon number and AA the amino acid as written.
+type Position struct {
+	Pos    int
+	UTR3   bool
+	Offset int
+	AA     string
+}
+
+// String returns p as written in a description.
+func (p Position) String() string {
+	if p.AA != "" {
+		return p.AA + strconv.Itoa(p.Pos)
+	}
+	return coordmap.CDSPos{Pos: p.Pos, UTR3: p.UTR3, Offset: p.Offset}.String()
+}
+
+// Description is a parsed HGVS description of a variant on a transcript.
+type Description struct {
+	// Accession is the transcript ID.
+	Accession string
+	// Type is 'c', 'n' or 'p'.
+	Type byte
+	// Start and End are the first and last positions of the variant. End
+	// equals Start for single positions.
+	Start, End Position
+	// Edit is the edit as written, e.g. "A>G", "del", "insAT" or "Trp".
+	Edit string
+}
+
+// String returns d in HGVS notation.
+func (d *Description) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s:%c.%s", d.Accession, d.Type, d.Start)
+	if d.End != d.Start {
+		fmt.Fprintf(&b, "_%s", d.End)
+	}
+	b.WriteString(d.Edit)
+	return b.String()
+}
+
+const (
+	posPattern = `([-*]?)(\d+)(?:([+-])(\d+))?`
+	aaPattern  = `(Ter|[A-Z][a-z]{2}|[A-Z*])`
+)
+
+var (
+	nucRe     = regexp.MustCompile(`^([^:]+):([cn])\.` + posPattern + `(?:_` + posPattern + `)?(.*)$`)
+	nucEditRe = regexp.MustCompile(`^(?:[ACGTN]>[ACGTN]|delins[ACGTN]+|del[ACGTN]*|dup[ACGTN]*|ins[ACGTN]+|inv|=)$`)
+	protRe    = regexp.MustCompile(`^([^:]+):p\.(\()?` + aaPattern + `(\d+)(?:_` + aaPattern + `(\d+))?(.+?)(\))?$`)
+)
+
+// Parse parses an HGVS description of a variant on a transcript, e.g.
+// "ENST00000380152.8:c.123+4A>G" or "NM_000059.4:p.(Arg12Trp)".
+// Uncertain positions and alleles are not supported.
+func Parse(s string) (*Description, error) {
+	if m := nucRe.FindStringSubmatch(s); m != nil {
+		d := &Description{Accession: m[1], Type: m[2][0], Edit: m[11]}
+		d.Start = parsePos(m[3:7])
+		d.End = d.Start
+		if m[8] != "" {
+			d.End = parsePos(m[7:11])
+		}
+		if !nucEditRe.MatchString(d.Edit) {
+			return nil, fmt.Errorf("hgvs: bad edit %q in %q", d.Edit, s)
+		}
+		if d.Start.Pos == 0 || d.End.Pos == 0 {
+			return nil, fmt.Errorf("hgvs: position 0 in %q", s)
+		}
+		if strings.HasPrefix(d.Edit, "ins") && d.End == d.Start {
+			return nil, fmt.Errorf("hgvs: insertion without flanking positions in %q", s)
+		}
+		return d, nil
+	}
+	if m := protRe.FindStringSubmatch(s); m != nil {
+		if (m[2] == "") != (m[8] == "") {
+			return nil, fmt.Errorf("hgvs: unbalanced parentheses in %q", s)
+		}
+		d := &Description{Accession: m[1], Type: 'p', Edit: m[7]}
+		d.Start.AA = m[3]
+		d.Start.Pos, _ = strconv.Atoi(m[4])
+		d.End = d.Start
+		if m[5] != "" {
+			d.End.AA = m[5]
+			d.End.Pos, _ = strconv.Atoi(m[6])
+		}
+		if d.Start.Pos == 0 || d.End.Pos == 0 {
+			return nil, fmt.Errorf("hgvs: codon 0 in %q", s)
+		}
+		return d, nil
+	}
+	return nil, fmt.Errorf("hgvs: cannot parse %q", s)
+}
+
+// parsePos returns the position of the prefix, number, offset sign and
+// offset submatches m.
+func parsePos(m []string) Position {
+	var p Position
+	p.Pos, _ = strconv.Atoi(m[1])
+	switch m[0] {
+	case "-":
+		p.Pos = -p.Pos
+	case "*":
+		p.UTR3 = true
+	}
+	if m[2] != "" {
+		p.Offset, _ = strconv.Atoi(m[3])
+		if m[2] == "-" {
+			p.Offset = -p.Offset
+		}
+	}
+	return p
+}
+
+// Variant is a variant in genomic coordinates. It replaces the bases in the
+// zero based half open interval [Start, End) of the forward strand of Chrom
+// with Alt. An insertion has Start equal to End. Ref and Alt are empty when
+// not known.
+type Variant struct {
+	Chrom      string
+	Start, End int
+	Ref, Alt   string
+}
+
+// Resolver resolves descriptions against the transcripts of a catalog and
+// describes variants on them.
+type Resolver struct {
+	cat     *catalog.Catalog
+	mappers map[string]*coordmap.Mapper
+}
+
+// NewResolver returns a new Resolver for the transcripts of c.
+func NewResolver(c *catalog.Catalog) *Resolver {
+	return &Resolver{cat: c, mappers: make(map[string]*coordmap.Mapper)}
+}
+
+// mapper returns the mapper of transcript tid.
+func (r *Resolver) mapper(tid string) (*coordmap.Mapper, error) {
+	t, ok := r.cat.Transcript(tid)
+	if !ok {
+		return nil, fmt.Errorf("hgvs: unknown transcript %s", tid)
+	}
+	if m, ok := r.mappers[t.Name()]; ok {
+		return m, nil
+	}
+	m, err := coordmap.New(t)
+	if err != nil {
+		return nil, err
+	}
+	r.mappers[t.Name()] = m
+	return m, nil
+}
+
+// Resolve returns the genomic variant of d. For c. and n. descriptions the
+// Ref and Alt bases given by substitutions, deletions, insertions and
+// deletion-insertions are returned on the forward strand. A duplication must
+// give its bases, which are returned as Ref and twice as Alt. Inversions and
+// duplications without bases are rejected since they need the reference
+// sequence. For p. descriptions the interval spans the codons of d and Ref
+// and Alt are empty.
+func (r *Resolver) Resolve(d *Description) (*Variant, error) {
+	m, err := r.mapper(d.Accession)
+	if err != nil {
+		return nil, err
+	}
+	if d.Type != 'p' && (d.Type == 'c') != m.Coding() {
+		return nil, fmt.Errorf("hgvs: %c. description for %s transcript %s", d.Type, kind(m), d.Accession)
+	}
+	var first, last int
+	switch d.Type {
+	case 'c':
+		if first, err = m.CDSToGenomic(cdsPos(d.Start)); err != nil {
+			return nil, err
+		}
+		if last, err = m.CDSToGenomic(cdsPos(d.End)); err != nil {
+			return nil, err
+		}
+	case 'n':
+		if first, err = m.TranscriptToGenomic(txPos(m, d.Start)); err != nil {
+			return nil, err
+		}
+		if last, err = m.TranscriptToGenomic(txPos(m, d.End)); err != nil {
+			return nil, err
+		}
+	case 'p':
+		s, err := m.ProteinToCDS(d.Start.Pos)
+		if err != nil {
+			return nil, err
+		}
+		e, err := m.ProteinToCDS(d.End.Pos)
+		if err != nil {
+			return nil, err
+		}
+		e.Pos += 2
+		if first, err = m.CDSToGenomic(s); err != nil {
+			return nil, err
+		}
+		if last, err = m.CDSToGenomic(e); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("hgvs: unknown description type %q", d.Type)
+	}
+	if first > last {
+		first, last = last, first
+	}
+	_, ref := geneio.RefPosition(m.Transcript(), 0)
+	v := &Variant{Chrom: ref.Name(), Start: first, End: last + 1}
+	if d.Type == 'p' {
+		return v, nil
+	}
+
+	orient := func(s string) string {
+		if isReverse(m) {
+			return string(geneio.ReverseComplement([]byte(s)))
+		}
+		return s
+	}
+	switch e := d.Edit; {
+	case strings.Contains(e, ">"):
+		v.Ref, v.Alt = orient(e[:1]), orient(e[2:])
+	case strings.HasPrefix(e, "delins"):
+		v.Alt = orient(e[len("delins"):])
+	case strings.HasPrefix(e, "del"):
+		v.Ref = orient(e[len("del"):])
+	case strings.HasPrefix(e, "ins"):
+		if last-first != 1 {
+			return nil, fmt.Errorf("hgvs: insertion between non adjacent positions in %s", d)
+		}
+		v.Start, v.End = last, last
+		v.Alt = orient(e[len("ins"):])
+	case e == "dup", e == "inv":
+		return nil, fmt.Errorf("hgvs: %s needs the reference sequence in %s", e, d)
+	case strings.HasPrefix(e, "dup"):
+		bases := e[len("dup"):]
+		if len(bases) != v.End-v.Start {
+			return nil, fmt.Errorf("hgvs: %d duplicated bases for %d positions in %s", len(bases), v.End-v.Start, d)
+		}
+		v.Ref, v.Alt = orient(bases), orient(bases+bases)
+	}
+	return v, nil
+}
+
+// isReverse returns whether the transcript of m is on the reverse strand.
+func isReverse(m *coordmap.Mapper) bool {
+	return geneio.RefOrientation(m.Transcript()) < 0
+}
+
+// kind returns the kind of transcript of m.
+func kind(m *coordmap.Mapper) string {
+	if m.Coding() {
+		return "coding"
+	}
+	return "non coding"
+}
+
+func cdsPos(p Position) coordmap.CDSPos {
+	return coordmap.CDSPos{Pos: p.Pos, UTR3: p.UTR3, Offset: p.Offset}
+}
+
+// txPos returns the transcript position of n. position p.
+func txPos(m *coordmap.Mapper, p Position) coordmap.Pos {
+	switch {
+	case p.UTR3:
+		return coordmap.Pos{Base: m.Len() - 1 + p.Pos, Offset: p.Offset}
+	case p.Pos < 0:
+		return coordmap.Pos{Base: p.Pos, Offset: p.Offset}
+	}
+	return coordmap.Pos{Base: p.Pos - 1, Offset: p.Offset}
+}
+
+// nPos returns the n. position of transcript position p.
+func nPos(m *coordmap.Mapper, p coordmap.Pos) Position {
+	switch {
+	case p.Base < 0:
+		return Position{Pos: p.Base, Offset: p.Offset}
+	case p.Base >= m.Len():
+		return Position{Pos: p.Base - m.Len() + 1, UTR3: true, Offset: p.Offset}
+	}
+	return Position{Pos: p.Base + 1, Offset: p.Offset}
+}
+
+// position returns the c. or n. position of reference position pos.
+func position(m *coordmap.Mapper, pos int) (Position, error) {
+	p, err := m.GenomicToTranscript(pos)
+	if err != nil {
+		return Position{}, err
+	}
+	if !m.Coding() {
+		return nPos(m, p), nil
+	}
+	c, err := m.TranscriptToCDS(p)
+	if err != nil {
+		return Position{}, err
+	}
+	return Position{Pos: c.Pos, UTR3: c.UTR3, Offset: c.Offset}, nil
+}
+
+// Describe returns the description of v on transcript tid, in c.
+// coordinates for coding transcripts and n. coordinates otherwise. v must
+// give its Alt bases and, for substitutions, its Ref base.
+func (r *Resolver) Describe(tid string, v *Variant) (*Description, error) {
+	m, err := r.mapper(tid)
+	if err != nil {
+		return nil, err
+	}
+	if _, ref := geneio.RefPosition(m.Transcript(), 0); ref.Name() != v.Chrom {
+		return nil, fmt.Errorf("hgvs: variant on %s is not on the sequence of transcript %s", v.Chrom, tid)
+	}
+	if v.End < v.Start || (v.Start == v.End && v.Alt == "") {
+		return nil, fmt.Errorf("hgvs: empty variant at %s:%d", v.Chrom, v.Start)
+	}
+	first, last := v.Start, v.End-1
+	if v.Start == v.End {
+		first, last = v.Start-1, v.Start
+	}
+	d := &Description{Accession: m.Transcript().Name(), Type: 'n'}
+	if m.Coding() {
+		d.Type = 'c'
+	}
+	if d.Start, err = position(m, first); err != nil {
+		return nil, err
+	}
+	if d.End, err = position(m, last); err != nil {
+		return nil, err
+	}
+	orient := func(s string) string { return s }
+	if isReverse(m) {
+		d.Start, d.End = d.End, d.Start
+		orient = func(s string) string { return string(geneio.ReverseComplement([]byte(s))) }
+	}
+	switch {
+	case v.Start == v.End:
+		d.Edit = "ins" + orient(v.Alt)
+	case v.End-v.Start == 1 && len(v.Ref) == 1 && len(v.Alt) == 1:
+		d.Edit = orient(v.Ref) + ">" + orient(v.Alt)
+	case v.Alt == "":
+		d.Edit = "del"
+	default:
+		d.Edit = "delins" + orient(v.Alt)
+	}
+	return d, nil
+}
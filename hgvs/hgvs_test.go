@@ -0,0 +1,196 @@
+package hgvs
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/biogo/biogo/feat"
+	"github.com/biogo/biogo/io/featio/gff"
+	"github.com/go-bio/geneio"
+	"github.com/go-bio/geneio/catalog"
+	ggff "github.com/go-bio/geneio/gff"
+)
+
+var hgvsInput = "" +
+	"X\t.\texon\t11\t20\t0\t+\t.\tgene_id F; transcript_id F1.2;\n" +
+	"X\t.\texon\t31\t40\t0\t+\t.\tgene_id F; transcript_id F1.2;\n" +
+	"X\t.\tstart_codon\t13\t15\t0\t+\t.\tgene_id F; transcript_id F1.2;\n" +
+	"X\t.\tstop_codon\t35\t37\t0\t+\t.\tgene_id F; transcript_id F1.2;\n" +
+	"X\t.\texon\t11\t20\t0\t-\t.\tgene_id R; transcript_id R1;\n" +
+	"X\t.\texon\t31\t40\t0\t-\t.\tgene_id R; transcript_id R1;\n" +
+	"X\t.\tstart_codon\t35\t37\t0\t-\t.\tgene_id R; transcript_id R1;\n" +
+	"X\t.\tstop_codon\t13\t15\t0\t-\t.\tgene_id R; transcript_id R1;\n" +
+	"X\t.\texon\t51\t60\t0\t+\t.\tgene_id N; transcript_id N1;\n"
+
+func resolver(t *testing.T) *Resolver {
+	c, err := catalog.Read(ggff.NewReader(gff.NewReader(strings.NewReader(hgvsInput))))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	return NewResolver(c)
+}
+
+// Test Parse
+var parseTests = []struct {
+	In    string
+	Desc  Description
+	Error string
+}{
+	{In: "F1.2:c.123+4A>G", Desc: Description{"F1.2", 'c', Position{Pos: 123, Offset: 4}, Position{Pos: 123, Offset: 4}, "A>G"}},
+	{In: "F1:c.-15_*7-2del", Desc: Description{"F1", 'c', Position{Pos: -15}, Position{Pos: 7, UTR3: true, Offset: -2}, "del"}},
+	{In: "N1:n.4_5insAT", Desc: Description{"N1", 'n', Position{Pos: 4}, Position{Pos: 5}, "insAT"}},
+	{In: "F1:p.Arg12Trp", Desc: Description{"F1", 'p', Position{Pos: 12, AA: "Arg"}, Position{Pos: 12, AA: "Arg"}, "Trp"}},
+	{In: "F1:p.(G12_V14del)", Desc: Description{"F1", 'p', Position{Pos: 12, AA: "G"}, Position{Pos: 14, AA: "V"}, "del"}},
+	{In: "F1:c.12A>GT", Error: `hgvs: bad edit "A>GT" in "F1:c.12A>GT"`},
+	{In: "F1:c.0del", Error: `hgvs: position 0 in "F1:c.0del"`},
+	{In: "F1:c.4insA", Error: `hgvs: insertion without flanking positions in "F1:c.4insA"`},
+	{In: "F1:p.(Arg12Trp", Error: `hgvs: unbalanced parentheses in "F1:p.(Arg12Trp"`},
+	{In: "F1:g.12A>G", Error: `hgvs: cannot parse "F1:g.12A>G"`},
+}
+
+func TestParse(t *testing.T) {
+	for _, tt := range parseTests {
+		d, err := Parse(tt.In)
+		if tt.Error != "" {
+			if err == nil || err.Error() != tt.Error {
+				t.Errorf("%s: error %q, want error %q", tt.In, err, tt.Error)
+			}
+			continue
+		} else if err != nil {
+			t.Errorf("%s: unexpected error %v", tt.In, err)
+			continue
+		}
+		if *d != tt.Desc {
+			t.Errorf("%s: out description=%+v want %+v", tt.In, *d, tt.Desc)
+		}
+	}
+}
+
+// Test Resolve and Describe
+var resolveTests = []struct {
+	In      string
+	Variant Variant
+	Out     string
+	Error   string
+}{
+	{In: "F1.2:c.1A>G", Variant: Variant{"X", 12, 13, "A", "G"}},
+	{In: "F1:c.8+1G>T", Variant: Variant{"X", 20, 21, "G", "T"}, Out: "F1.2:c.8+1G>T"},
+	{In: "F1.2:c.9-1_9del", Variant: Variant{"X", 29, 31, "", ""}},
+	{In: "F1.2:c.*1_*2insTT", Variant: Variant{"X", 38, 38, "", "TT"}},
+	{In: "F1.2:c.-3delinsCA", Variant: Variant{"X", 9, 10, "", "CA"}},
+	{In: "F1.2:p.Met1del", Variant: Variant{"X", 12, 15, "", ""}},
+	{In: "F1.2:p.Ser3_Ter5del", Variant: Variant{"X", 18, 37, "", ""}},
+	{In: "R1:c.1A>G", Variant: Variant{"X", 36, 37, "T", "C"}},
+	{In: "R1:c.7+1_8-1delGA", Variant: Variant{"X", 20, 30, "TC", ""}, Out: "R1:c.7+1_8-1del"},
+	{In: "R1:c.1_2insAC", Variant: Variant{"X", 36, 36, "", "GT"}},
+	{In: "N1:n.1C>T", Variant: Variant{"X", 50, 51, "C", "T"}},
+	{In: "N1:n.-2_*1delinsA", Variant: Variant{"X", 48, 61, "", "A"}},
+	{In: "F1.2:c.1_2dupAT", Variant: Variant{"X", 12, 14, "AT", "ATAT"}, Out: "F1.2:c.1_2delinsATAT"},
+	{In: "R1:c.1_2dupAT", Variant: Variant{"X", 35, 37, "AT", "ATAT"}, Out: "R1:c.1_2delinsATAT"},
+	{In: "F1.2:c.1_2dup", Error: "hgvs: dup needs the reference sequence in F1.2:c.1_2dup"},
+	{In: "F1.2:c.1_3inv", Error: "hgvs: inv needs the reference sequence in F1.2:c.1_3inv"},
+	{In: "F1.2:c.1_3dupAT", Error: "hgvs: 2 duplicated bases for 3 positions in F1.2:c.1_3dupAT"},
+	{In: "Z1:c.1A>G", Error: "hgvs: unknown transcript Z1"},
+	{In: "N1:c.1A>G", Error: "hgvs: c. description for non coding transcript N1"},
+	{In: "F1.2:c.2_4insA", Error: "hgvs: insertion between non adjacent positions in F1.2:c.2_4insA"},
+	{In: "F1.2:p.Arg6Trp", Error: "coordmap: codon 6 out of range for transcript F1.2"},
+}
+
+func TestResolve(t *testing.T) {
+	r := resolver(t)
+	for _, tt := range resolveTests {
+		d, err := Parse(tt.In)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tt.In, err)
+			continue
+		}
+		v, err := r.Resolve(d)
+		if tt.Error != "" {
+			if err == nil || err.Error() != tt.Error {
+				t.Errorf("%s: error %q, want error %q", tt.In, err, tt.Error)
+			}
+			continue
+		} else if err != nil {
+			t.Errorf("%s: unexpected error %v", tt.In, err)
+			continue
+		}
+		if *v != tt.Variant {
+			t.Errorf("%s: out variant=%+v want %+v", tt.In, *v, tt.Variant)
+		}
+		if d.Type == 'p' {
+			continue
+		}
+		out, err := r.Describe(d.Accession, v)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tt.In, err)
+			continue
+		}
+		want := tt.Out
+		if want == "" {
+			want = strings.Replace(tt.In, "delGA", "del", 1)
+		}
+		if out.String() != want {
+			t.Errorf("%s: out description=%s want %s", tt.In, out, want)
+		}
+	}
+}
+
+func TestResolvePhase(t *testing.T) {
+	genes, err := geneio.NewBuilder().
+		Gene("P", geneio.Chrom("X"), feat.Forward).
+		Transcript("P1").Exon(10, 30).CDS(10, 24).Tag(geneio.TagCDSStartNF).Phase(2).
+		Build()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	c := catalog.New()
+	if err := c.Add(genes[0]); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	r := NewResolver(c)
+	for _, tt := range []struct {
+		In      string
+		Variant Variant
+		Error   string
+	}{
+		{In: "P1:p.Leu1del", Variant: Variant{"X", 12, 15, "", ""}},
+		{In: "P1:p.Ser2_Ter4del", Variant: Variant{"X", 15, 24, "", ""}},
+		{In: "P1:p.Arg5Trp", Error: "coordmap: codon 5 out of range for transcript P1"},
+	} {
+		d, err := Parse(tt.In)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tt.In, err)
+			continue
+		}
+		v, err := r.Resolve(d)
+		if tt.Error != "" {
+			if err == nil || err.Error() != tt.Error {
+				t.Errorf("%s: error %q, want error %q", tt.In, err, tt.Error)
+			}
+			continue
+		} else if err != nil {
+			t.Errorf("%s: unexpected error %v", tt.In, err)
+			continue
+		}
+		if *v != tt.Variant {
+			t.Errorf("%s: out variant=%+v want %+v", tt.In, *v, tt.Variant)
+		}
+	}
+}
+
+func TestDescribeErrors(t *testing.T) {
+	r := resolver(t)
+	for _, tt := range []struct {
+		TID     string
+		Variant Variant
+		Error   string
+	}{
+		{"F1.2", Variant{"Y", 12, 13, "A", "G"}, "hgvs: variant on Y is not on the sequence of transcript F1.2"},
+		{"F1.2", Variant{"X", 12, 12, "", ""}, "hgvs: empty variant at X:12"},
+	} {
+		_, err := r.Describe(tt.TID, &tt.Variant)
+		if err == nil || err.Error() != tt.Error {
+			t.Errorf("%+v: error %q, want error %q", tt.Variant, err, tt.Error)
+		}
+	}
+}
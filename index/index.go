@@ -0,0 +1,122 @@
+// Package index provides a region index of genes for overlap queries.
+//
+// Genes are indexed by the name of their reference sequence and their extent
+// on it. Queries use zero based, half open reference coordinates.
+package index
+
+import (
+	"sort"
+
+	"github.com/biogo/biogo/feat/gene"
+	"github.com/go-bio/geneio"
+)
+
+// Index is a region index of genes. An Index is immutable once built and safe
+// for concurrent queries.
+type Index struct {
+	chroms map[string]*chromIndex
+	names  []string
+}
+
+// chromIndex holds the genes of one reference sequence sorted by start.
+// maxEnd[i] is the largest end of genes[0:i+1].
+type chromIndex struct {
+	genes  []entry
+	maxEnd []int
+}
+
+// entry is an indexed gene with its reference coordinates.
+type entry struct {
+	start, end int
+	g          gene.Interface
+}
+
+// New returns a new Index of genes.
+func New(genes ...gene.Interface) *Index {
+	idx := &Index{chroms: make(map[string]*chromIndex)}
+	for _, g := range genes {
+		start, ref := geneio.RefPosition(g, 0)
+		c, ok := idx.chroms[ref.Name()]
+		if !ok {
+			c = &chromIndex{}
+			idx.chroms[ref.Name()] = c
+			idx.names = append(idx.names, ref.Name())
+		}
+		c.genes = append(c.genes, entry{start: start, end: start + g.Len(), g: g})
+	}
+	sort.Strings(idx.names)
+	for _, c := range idx.chroms {
+		sort.SliceStable(c.genes, func(i, j int) bool { return c.genes[i].start < c.genes[j].start })
+		c.maxEnd = make([]int, len(c.genes))
+		max := 0
+		for i, e := range c.genes {
+			if e.end > max {
+				max = e.end
+			}
+			c.maxEnd[i] = max
+		}
+	}
+	return idx
+}
+
+// Read reads all genes from r and returns an Index of them. It returns nil
+// and an error if reading fails.
+func Read(r geneio.Reader) (*Index, error) {
+	var genes []gene.Interface
+	sc := geneio.NewScanner(r)
+	for sc.Next() {
+		genes = append(genes, sc.Gene())
+	}
+	if err := sc.Error(); err != nil {
+		return nil, err
+	}
+	return New(genes...), nil
+}
+
+// Overlapping returns the genes on chrom that overlap the half open interval
+// [start, end), ordered by start.
+func (idx *Index) Overlapping(chrom string, start, end int) []gene.Interface {
+	c, ok := idx.chroms[chrom]
+	if !ok {
+		return nil
+	}
+	// Genes at or after hi start at or after end and cannot overlap.
+	hi := sort.Search(len(c.genes), func(i int) bool { return c.genes[i].start >= end })
+	// Genes before lo all end at or before start.
+	lo := sort.Search(hi, func(i int) bool { return c.maxEnd[i] > start })
+	var genes []gene.Interface
+	for _, e := range c.genes[lo:hi] {
+		if e.end > start {
+			genes = append(genes, e.g)
+		}
+	}
+	return genes
+}
+
+// Chroms returns the names of the indexed reference sequences in lexical
+// order.
+func (idx *Index) Chroms() []string {
+	return append([]string(nil), idx.names...)
+}
+
+// Genes returns the indexed genes on chrom ordered by start.
+func (idx *Index) Genes(chrom string) []gene.Interface {
+	c, ok := idx.chroms[chrom]
+	if !ok {
+		return nil
+	}
+	genes := make([]gene.Interface, len(c.genes))
+	for i, e := range c.genes {
+		genes[i] = e.g
+	}
+	return genes
+}
+
+// Len returns the number of indexed genes.
+func (idx *Index) Len() int {
+	n := 0
+	for _, c := range idx.chroms {
+		n += len(c.genes)
+	}
+	return n
+}
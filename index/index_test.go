@@ -0,0 +1,53 @@
+package index
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/biogo/biogo/io/featio/gff"
+	ggff "github.com/go-bio/geneio/gff"
+)
+
+var indexInput = "" +
+	"X\t.\texon\t11\t100\t0\t+\t.\tgene_id A; transcript_id A1;\n" +
+	"X\t.\texon\t21\t30\t0\t+\t.\tgene_id B; transcript_id B1;\n" +
+	"X\t.\texon\t201\t300\t0\t-\t.\tgene_id C; transcript_id C1;\n" +
+	"Y\t.\texon\t11\t20\t0\t+\t.\tgene_id D; transcript_id D1;\n"
+
+// Test Overlapping
+var overlappingTests = []struct {
+	Name       string
+	Chrom      string
+	Start, End int
+	IDs        []string
+}{
+	{"inside long gene", "X", 50, 60, []string{"A"}},
+	{"nested genes", "X", 25, 26, []string{"A", "B"}},
+	{"touching end", "X", 100, 200, nil},
+	{"spanning", "X", 0, 1000, []string{"A", "B", "C"}},
+	{"other chromosome", "Y", 0, 11, []string{"D"}},
+	{"unknown chromosome", "Z", 0, 1000, nil},
+}
+
+func TestOverlapping(t *testing.T) {
+	idx, err := Read(ggff.NewReader(gff.NewReader(strings.NewReader(indexInput))))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if idx.Len() != 4 {
+		t.Errorf("out len=%d want 4", idx.Len())
+	}
+	if got := idx.Chroms(); !reflect.DeepEqual(got, []string{"X", "Y"}) {
+		t.Errorf("out chroms=%q want %q", got, []string{"X", "Y"})
+	}
+	for _, tt := range overlappingTests {
+		var ids []string
+		for _, g := range idx.Overlapping(tt.Chrom, tt.Start, tt.End) {
+			ids = append(ids, g.Name())
+		}
+		if !reflect.DeepEqual(ids, tt.IDs) {
+			t.Errorf("%s: out ids=%q want %q", tt.Name, ids, tt.IDs)
+		}
+	}
+}
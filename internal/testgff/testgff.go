@@ -0,0 +1,26 @@
+// Package testgff provides a helper for tests that read genes from GFF.
+package testgff
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/biogo/biogo/feat/gene"
+	"github.com/biogo/biogo/io/featio/gff"
+	"github.com/go-bio/geneio"
+	ggff "github.com/go-bio/geneio/gff"
+)
+
+// Genes returns the genes of the GFF records in input, failing t if they
+// cannot be read.
+func Genes(t *testing.T, input string) []gene.Interface {
+	var genes []gene.Interface
+	sc := geneio.NewScanner(ggff.NewReader(gff.NewReader(strings.NewReader(input))))
+	for sc.Next() {
+		genes = append(genes, sc.Gene())
+	}
+	if err := sc.Error(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	return genes
+}
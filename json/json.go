@@ -0,0 +1,309 @@
+// Package json reads and writes genes as newline-delimited JSON.
+//
+// Each line holds one gene record. Coordinates are on the reference sequence,
+// zero based and half open, and strands are "+", "-" or ".". The record
+// below is a gene with one coding transcript on the reverse strand.
+//
+//	{"id":"A","chrom":"Y","strand":"-","start":9,"end":90,"transcripts":[
+//	 {"id":"A1","strand":"-","start":9,"end":90,"exons":[[9,20],[49,90]],"cds":[59,73]}]}
+//
+// Records are shown wrapped for readability. The tags, translational
+// exceptions and coding phase of geneio.Annotated transcripts are kept in the
+// tags, exceptions and phase fields of their records. The same records are
+// described for protobuf users by gene.proto in this directory.
+package json
+
+import (
+	"bufio"
+	ejson "encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/biogo/biogo/feat"
+	"github.com/biogo/biogo/feat/gene"
+	"github.com/go-bio/geneio"
+)
+
+// Gene is the JSON record of a gene.
+type Gene struct {
+	ID          string            `json:"id"`
+	Chrom       string            `json:"chrom"`
+	Strand      string            `json:"strand"`
+	Start       int               `json:"start"`
+	End         int               `json:"end"`
+	Description string            `json:"description,omitempty"`
+	Attributes  map[string]string `json:"attributes,omitempty"`
+	Transcripts []Transcript      `json:"transcripts"`
+}
+
+// Transcript is the JSON record of a transcript. CDS is nil for non coding
+// transcripts.
+type Transcript struct {
+	ID          string            `json:"id"`
+	Strand      string            `json:"strand"`
+	Start       int               `json:"start"`
+	End         int               `json:"end"`
+	Description string            `json:"description,omitempty"`
+	Attributes  map[string]string `json:"attributes,omitempty"`
+	Exons       [][2]int          `json:"exons"`
+	CDS         *[2]int           `json:"cds,omitempty"`
+	Tags        []string          `json:"tags,omitempty"`
+	Exceptions  []Exception       `json:"exceptions,omitempty"`
+	Phase       int               `json:"phase,omitempty"`
+}
+
+// Exception is the JSON record of a translational exception. Position is the
+// reference position of the first base of the codon in the direction of
+// transcription and AminoAcid is the one letter code of the amino acid it
+// encodes.
+type Exception struct {
+	Position  int    `json:"position"`
+	AminoAcid string `json:"amino_acid"`
+}
+
+// NewGene returns the record of g.
+func NewGene(g gene.Interface) *Gene {
+	start, ref := geneio.RefPosition(g, 0)
+	rec := &Gene{
+		ID:          g.Name(),
+		Chrom:       ref.Name(),
+		Strand:      strandString(geneio.RefOrientation(g)),
+		Start:       start,
+		End:         start + g.Len(),
+		Description: g.Description(),
+		Attributes:  geneio.Attributes(g),
+		Transcripts: []Transcript{},
+	}
+	for _, t := range geneio.Transcripts(g) {
+		ts, _ := geneio.RefPosition(t, 0)
+		tr := Transcript{
+			ID:          t.Name(),
+			Strand:      strandString(geneio.RefOrientation(t)),
+			Start:       ts,
+			End:         ts + t.Len(),
+			Description: t.Description(),
+			Attributes:  geneio.Attributes(t),
+			Exons:       [][2]int{},
+		}
+		for _, e := range t.Exons() {
+			tr.Exons = append(tr.Exons, [2]int{ts + e.Start(), ts + e.End()})
+		}
+		if ct, ok := geneio.Coding(t); ok {
+			tr.CDS = &[2]int{ts + ct.CDSstart, ts + ct.CDSend}
+		}
+		if a, ok := t.(geneio.Annotated); ok {
+			tr.Tags = a.TranscriptTags()
+			for _, x := range a.TranslationExceptions() {
+				tr.Exceptions = append(tr.Exceptions, Exception{Position: ts + x.Offset, AminoAcid: string(x.AminoAcid)})
+			}
+			tr.Phase = a.CodingPhase()
+		}
+		rec.Transcripts = append(rec.Transcripts, tr)
+	}
+	return rec
+}
+
+// Gene returns the gene described by rec. The gene is located on a
+// geneio.Chrom named after rec.Chrom and its transcripts are located on the
+// gene. A gene or transcript with attributes is returned as a
+// *geneio.AttributedGene, *geneio.AnnotatedTranscript or
+// *geneio.AttributedTranscript that carries them, and a coding transcript
+// with tags, exceptions or a phase as a *geneio.AnnotatedTranscript.
+func (rec *Gene) Gene() (gene.Interface, error) {
+	g, err := rec.gene()
+	if err != nil {
+		return nil, errors.New("json: " + err.Error())
+	}
+	return g, nil
+}
+
+// gene implements Gene, returning errors without package prefix.
+func (rec *Gene) gene() (gene.Interface, error) {
+	if rec.ID == "" {
+		return nil, errors.New("empty gene id")
+	}
+	if len(rec.Transcripts) == 0 {
+		return nil, fmt.Errorf("no transcripts for gene %s", rec.ID)
+	}
+	orient, err := parseStrand(rec.Strand)
+	if err != nil {
+		return nil, fmt.Errorf("%v for gene %s", err, rec.ID)
+	}
+	g := &gene.Gene{
+		ID:     rec.ID,
+		Chrom:  geneio.Chrom(rec.Chrom),
+		Offset: rec.Start,
+		Orient: orient,
+		Desc:   rec.Description,
+	}
+	feats := make([]feat.Feature, len(rec.Transcripts))
+	for i, tr := range rec.Transcripts {
+		t, err := tr.transcript(g)
+		if err != nil {
+			return nil, fmt.Errorf("%v for transcript %s of gene %s", err, tr.ID, rec.ID)
+		}
+		feats[i] = t
+	}
+	if err := g.SetFeatures(feats...); err != nil {
+		return nil, err
+	}
+	if g.End() != rec.End {
+		return nil, fmt.Errorf("end %d does not match transcripts for gene %s", rec.End, rec.ID)
+	}
+	return geneio.WithAttributes(g, rec.Attributes), nil
+}
+
+// transcript returns the transcript described by tr located on g.
+func (tr *Transcript) transcript(g *gene.Gene) (gene.Transcript, error) {
+	if tr.ID == "" {
+		return nil, errors.New("empty transcript id")
+	}
+	if tr.Start < g.Offset {
+		return nil, errors.New("start before gene start")
+	}
+	orient, err := parseStrand(tr.Strand)
+	if err != nil {
+		return nil, err
+	}
+	if g.Orient != feat.NotOriented {
+		orient *= g.Orient
+	}
+	offset := tr.Start - g.Offset
+
+	var t gene.Transcript
+	if tr.CDS != nil {
+		if tr.CDS[0] < tr.Start || tr.CDS[1] > tr.End || tr.CDS[0] > tr.CDS[1] {
+			return nil, errors.New("cds out of transcript bounds")
+		}
+		t = &gene.CodingTranscript{
+			ID: tr.ID, Loc: g, Offset: offset, Orient: orient, Desc: tr.Description,
+			CDSstart: tr.CDS[0] - tr.Start, CDSend: tr.CDS[1] - tr.Start,
+		}
+	} else {
+		t = &gene.NonCodingTranscript{ID: tr.ID, Loc: g, Offset: offset, Orient: orient, Desc: tr.Description}
+	}
+	if len(tr.Exons) == 0 {
+		return nil, errors.New("no exons")
+	}
+	exons := make([]gene.Exon, len(tr.Exons))
+	for i, e := range tr.Exons {
+		if e[0] < tr.Start || e[1] > tr.End || e[0] >= e[1] {
+			return nil, fmt.Errorf("bad exon [%d,%d)", e[0], e[1])
+		}
+		exons[i] = gene.Exon{Transcript: t, Offset: e[0] - tr.Start, Length: e[1] - e[0]}
+	}
+	if err := t.SetExons(exons...); err != nil {
+		return nil, err
+	}
+	if t.Len() != tr.End-tr.Start {
+		return nil, errors.New("bounds do not match exons")
+	}
+	if len(tr.Tags) != 0 || len(tr.Exceptions) != 0 || tr.Phase != 0 {
+		ct, ok := t.(*gene.CodingTranscript)
+		if !ok {
+			return nil, errors.New("annotation for non coding transcript")
+		}
+		if tr.Phase < 0 || tr.Phase > 2 {
+			return nil, fmt.Errorf("bad phase %d", tr.Phase)
+		}
+		at := &geneio.AnnotatedTranscript{CodingTranscript: ct, Tags: tr.Tags, Phase: tr.Phase}
+		for _, x := range tr.Exceptions {
+			if len(x.AminoAcid) != 1 || x.Position < tr.Start || x.Position >= tr.End {
+				return nil, fmt.Errorf("bad exception %d %q", x.Position, x.AminoAcid)
+			}
+			at.Exceptions = append(at.Exceptions, geneio.Exception{Offset: x.Position - tr.Start, AminoAcid: x.AminoAcid[0]})
+		}
+		t = at
+	}
+	return geneio.TranscriptWithAttributes(t, tr.Attributes), nil
+}
+
+// strandString returns the symbol of o.
+func strandString(o feat.Orientation) string {
+	switch o {
+	case feat.Forward:
+		return "+"
+	case feat.Reverse:
+		return "-"
+	}
+	return "."
+}
+
+// parseStrand returns the orientation of strand symbol s.
+func parseStrand(s string) (feat.Orientation, error) {
+	switch s {
+	case "+":
+		return feat.Forward, nil
+	case "-":
+		return feat.Reverse, nil
+	case ".", "":
+		return feat.NotOriented, nil
+	}
+	return 0, fmt.Errorf("bad strand %q", s)
+}
+
+// Reader reads genes from newline-delimited JSON.
+type Reader struct {
+	sc   *bufio.Scanner
+	line int
+	rec  *Gene
+}
+
+// NewReader returns a new Reader that reads from r.
+func NewReader(r io.Reader) *Reader {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, 1<<26)
+	return &Reader{sc: sc}
+}
+
+// Read returns the next gene. Empty lines are skipped. At EOF, it returns nil
+// and io.EOF.
+func (r *Reader) Read() (gene.Interface, error) {
+	for r.sc.Scan() {
+		r.line++
+		b := r.sc.Bytes()
+		if len(b) == 0 {
+			continue
+		}
+		var rec Gene
+		if err := ejson.Unmarshal(b, &rec); err != nil {
+			return nil, fmt.Errorf("json: line %d: %v", r.line, err)
+		}
+		g, err := rec.gene()
+		if err != nil {
+			return nil, fmt.Errorf("json: line %d: %v", r.line, err)
+		}
+		r.rec = &rec
+		return g, nil
+	}
+	if err := r.sc.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+// Record returns the record of the last gene read.
+func (r *Reader) Record() *Gene {
+	return r.rec
+}
+
+// Writer writes genes as newline-delimited JSON.
+type Writer struct {
+	w io.Writer
+}
+
+// NewWriter returns a new Writer that writes to w.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+// Write writes the record of g as one line, returning the number of bytes
+// written and any error that occurs during the write.
+func (w *Writer) Write(g gene.Interface) (n int, err error) {
+	b, err := ejson.Marshal(NewGene(g))
+	if err != nil {
+		return 0, err
+	}
+	return w.w.Write(append(b, '\n'))
+}
@@ -0,0 +1,215 @@
+package json
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/biogo/biogo/io/featio/gff"
+	"github.com/go-bio/geneio"
+	ggff "github.com/go-bio/geneio/gff"
+)
+
+// Assert that interfaces are satisfied.
+var (
+	_ geneio.Reader = (*Reader)(nil)
+	_ geneio.Writer = (*Writer)(nil)
+)
+
+var gffInput = "" +
+	"Y\t.\texon\t10\t20\t.\t-\t.\tgene_id A; transcript_id A1;\n" +
+	"Y\t.\texon\t50\t90\t.\t-\t.\tgene_id A; transcript_id A1;\n" +
+	"Y\t.\tstop_codon\t60\t62\t.\t-\t.\tgene_id A; transcript_id A1;\n" +
+	"Y\t.\tstart_codon\t71\t73\t.\t-\t.\tgene_id A; transcript_id A1;\n" +
+	"Y\t.\texon\t15\t100\t.\t-\t.\tgene_id A; transcript_id A2;\n" +
+	"X\t.\texon\t11\t20\t.\t+\t.\tgene_id B; transcript_id B1;\n"
+
+// Test Writer and Reader
+func TestRoundTrip(t *testing.T) {
+	var js bytes.Buffer
+	w := NewWriter(&js)
+	sc := geneio.NewScanner(ggff.NewReader(gff.NewReader(strings.NewReader(gffInput))))
+	for sc.Next() {
+		if _, err := w.Write(sc.Gene()); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+	}
+	if err := sc.Error(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	want := `{"id":"A","chrom":"Y","strand":"-","start":9,"end":100,"transcripts":[` +
+		`{"id":"A1","strand":"-","start":9,"end":90,"exons":[[9,20],[49,90]],"cds":[59,73]},` +
+		`{"id":"A2","strand":"-","start":14,"end":100,"exons":[[14,100]]}]}` + "\n" +
+		`{"id":"B","chrom":"X","strand":"+","start":10,"end":20,"transcripts":[` +
+		`{"id":"B1","strand":"+","start":10,"end":20,"exons":[[10,20]]}]}` + "\n"
+	if js.String() != want {
+		t.Fatalf("out json=\n%s want\n%s", js.String(), want)
+	}
+
+	var out bytes.Buffer
+	gw := ggff.NewWriter(&out)
+	sc = geneio.NewScanner(NewReader(strings.NewReader(js.String())))
+	for sc.Next() {
+		if _, err := gw.Write(sc.Gene()); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+	}
+	if err := sc.Error(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	var direct bytes.Buffer
+	gw = ggff.NewWriter(&direct)
+	sc = geneio.NewScanner(ggff.NewReader(gff.NewReader(strings.NewReader(gffInput))))
+	for sc.Next() {
+		gw.Write(sc.Gene())
+	}
+	if out.String() != direct.String() {
+		t.Errorf("out gff=\n%s want\n%s", out.String(), direct.String())
+	}
+}
+
+// Test Reader
+var readTests = []struct {
+	Name       string
+	Input      string
+	Attributes map[string]string
+	Error      string
+}{
+	{
+		Name: "Attributes",
+		Input: "\n" + `{"id":"A","chrom":"X","strand":"+","start":0,"end":5,"attributes":{"gene_name":"a"},` +
+			`"transcripts":[{"id":"A1","strand":"+","start":0,"end":5,"exons":[[0,5]]}]}`,
+		Attributes: map[string]string{"gene_name": "a"},
+	},
+	{
+		Name:  "Bad JSON",
+		Input: `{"id":`,
+		Error: "json: line 1: unexpected end of JSON input",
+	},
+	{
+		Name:  "No transcripts",
+		Input: `{"id":"A","chrom":"X","strand":"+","start":0,"end":5,"transcripts":[]}`,
+		Error: "json: line 1: no transcripts for gene A",
+	},
+	{
+		Name: "Bad strand",
+		Input: `{"id":"A","chrom":"X","strand":"+","start":0,"end":5,` +
+			`"transcripts":[{"id":"A1","strand":"x","start":0,"end":5,"exons":[[0,5]]}]}`,
+		Error: `json: line 1: bad strand "x" for transcript A1 of gene A`,
+	},
+	{
+		Name: "Exon out of bounds",
+		Input: `{"id":"A","chrom":"X","strand":"+","start":0,"end":5,` +
+			`"transcripts":[{"id":"A1","strand":"+","start":0,"end":5,"exons":[[0,6]]}]}`,
+		Error: "json: line 1: bad exon [0,6) for transcript A1 of gene A",
+	},
+	{
+		Name: "Annotated non coding transcript",
+		Input: `{"id":"A","chrom":"X","strand":"+","start":0,"end":5,` +
+			`"transcripts":[{"id":"A1","strand":"+","start":0,"end":5,"exons":[[0,5]],"tags":["basic"]}]}`,
+		Error: "json: line 1: annotation for non coding transcript for transcript A1 of gene A",
+	},
+	{
+		Name: "Bad exception",
+		Input: `{"id":"A","chrom":"X","strand":"+","start":0,"end":5,` +
+			`"transcripts":[{"id":"A1","strand":"+","start":0,"end":5,"exons":[[0,5]],"cds":[0,5],` +
+			`"exceptions":[{"position":7,"amino_acid":"U"}]}]}`,
+		Error: `json: line 1: bad exception 7 "U" for transcript A1 of gene A`,
+	},
+	{
+		Name: "Bad gene end",
+		Input: `{"id":"A","chrom":"X","strand":"+","start":0,"end":6,` +
+			`"transcripts":[{"id":"A1","strand":"+","start":0,"end":5,"exons":[[0,5]]}]}`,
+		Error: "json: line 1: end 6 does not match transcripts for gene A",
+	},
+}
+
+func TestRead(t *testing.T) {
+	for _, tt := range readTests {
+		r := NewReader(strings.NewReader(tt.Input))
+		g, err := r.Read()
+		if tt.Error != "" {
+			if err == nil || err.Error() != tt.Error {
+				t.Errorf("%s: error %q, want error %q", tt.Name, err, tt.Error)
+			}
+			continue
+		} else if err != nil {
+			t.Errorf("%s: unexpected error %v", tt.Name, err)
+			continue
+		}
+		if got := geneio.Attributes(g); !reflect.DeepEqual(got, tt.Attributes) {
+			t.Errorf("%s: out attributes=%v want %v", tt.Name, got, tt.Attributes)
+		}
+	}
+}
+
+// Test that the annotation of transcripts survives writing and reading
+func TestAnnotationRoundTrip(t *testing.T) {
+	input := "" +
+		"X\t.\texon\t1\t9\t.\t+\t.\tgene_id S; transcript_id S1; tag basic;\n" +
+		"X\t.\texon\t13\t24\t.\t+\t.\tgene_id S; transcript_id S1; tag basic;\n" +
+		"X\t.\tstart_codon\t1\t3\t.\t+\t.\tgene_id S; transcript_id S1; tag basic;\n" +
+		"X\t.\tstop_codon\t22\t24\t.\t+\t.\tgene_id S; transcript_id S1; tag basic;\n" +
+		"X\t.\tSelenocysteine\t4\t6\t.\t+\t.\tgene_id S; transcript_id S1; tag basic;\n" +
+		"Y\t.\texon\t1\t13\t.\t-\t.\tgene_id N; transcript_id N1; tag cds_start_NF;\n" +
+		"Y\t.\tstop_codon\t1\t3\t.\t-\t.\tgene_id N; transcript_id N1; tag cds_start_NF;\n" +
+		"Y\t.\tCDS\t4\t13\t.\t-\t1\tgene_id N; transcript_id N1; tag cds_start_NF;\n"
+	var js bytes.Buffer
+	w := NewWriter(&js)
+	sc := geneio.NewScanner(ggff.NewReader(gff.NewReader(strings.NewReader(input))))
+	for sc.Next() {
+		if _, err := w.Write(sc.Gene()); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+	}
+	if err := sc.Error(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	want := `{"id":"S","chrom":"X","strand":"+","start":0,"end":24,"transcripts":[` +
+		`{"id":"S1","strand":"+","start":0,"end":24,"exons":[[0,9],[12,24]],"cds":[0,24],` +
+		`"tags":["basic"],"exceptions":[{"position":3,"amino_acid":"U"}]}]}` + "\n" +
+		`{"id":"N","chrom":"Y","strand":"-","start":0,"end":13,"transcripts":[` +
+		`{"id":"N1","strand":"-","start":0,"end":13,"exons":[[0,13]],"cds":[0,13],` +
+		`"tags":["cds_start_NF"],"phase":1}]}` + "\n"
+	if js.String() != want {
+		t.Fatalf("out json=\n%s want\n%s", js.String(), want)
+	}
+
+	var out bytes.Buffer
+	w = NewWriter(&out)
+	sc = geneio.NewScanner(NewReader(strings.NewReader(want)))
+	for sc.Next() {
+		if _, err := w.Write(sc.Gene()); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+	}
+	if err := sc.Error(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if out.String() != want {
+		t.Errorf("out json=\n%s want\n%s", out.String(), want)
+	}
+}
+
+// Test that attributes of genes and transcripts survive reading and writing
+func TestAttributesRoundTrip(t *testing.T) {
+	input := `{"id":"A","chrom":"Y","strand":"-","start":9,"end":100,"attributes":{"Alias":"a1","gene_name":"a"},"transcripts":[` +
+		`{"id":"A1","strand":"-","start":9,"end":90,"attributes":{"transcript_name":"a-201"},"exons":[[9,20],[49,90]],"cds":[59,73]},` +
+		`{"id":"A2","strand":"-","start":14,"end":100,"attributes":{"transcript_name":"a-202"},"exons":[[14,100]]},` +
+		`{"id":"A3","strand":"-","start":14,"end":50,"exons":[[14,50]]}]}` + "\n"
+	var out bytes.Buffer
+	w := NewWriter(&out)
+	sc := geneio.NewScanner(NewReader(strings.NewReader(input)))
+	for sc.Next() {
+		if _, err := w.Write(sc.Gene()); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+	}
+	if err := sc.Error(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if out.String() != input {
+		t.Errorf("out json=\n%s want\n%s", out.String(), input)
+	}
+}
@@ -0,0 +1,523 @@
+// Package liftover converts gene models between reference assemblies using
+// UCSC chain files.
+//
+// A chain file describes the alignment of a source assembly (the chain
+// target, "t" fields) to a destination assembly (the chain query, "q"
+// fields). Genes are lifted exon by exon. Transcripts that cannot be lifted
+// faithfully are reported as Failures and left out of the lifted gene rather
+// than being converted into invalid models. The tags, coding phase and
+// attributes of transcripts are kept, and their translational exceptions are
+// lifted with them.
+package liftover
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/biogo/biogo/feat"
+	"github.com/biogo/biogo/feat/gene"
+	"github.com/go-bio/geneio"
+)
+
+// Reason is the reason a transcript could not be lifted.
+type Reason int
+
+// Reasons for failing to lift a transcript.
+const (
+	// ExonLost means an exon boundary falls outside the aligned blocks.
+	ExonLost Reason = iota
+	// Split means the exons map to different chains, sequences or strands.
+	Split
+	// Reordered means the lifted exons overlap or are out of order.
+	Reordered
+	// CDSBroken means the CDS ends cannot be lifted or the lifted CDS length
+	// is no longer in the original reading frame.
+	CDSBroken
+)
+
+var reasonNames = [...]string{
+	ExonLost:  "exon lost",
+	Split:     "split",
+	Reordered: "reordered",
+	CDSBroken: "CDS broken",
+}
+
+// String returns the name of r.
+func (r Reason) String() string {
+	if r < 0 || int(r) >= len(reasonNames) {
+		return "unknown"
+	}
+	return reasonNames[r]
+}
+
+// Failure describes a transcript that could not be lifted.
+type Failure struct {
+	Gene       string
+	Transcript string
+	Reason     Reason
+	Msg        string
+}
+
+// Error returns the failure message.
+func (f *Failure) Error() string {
+	return fmt.Sprintf("liftover: %s for transcript %s of gene %s: %s",
+		f.Reason, f.Transcript, f.Gene, f.Msg)
+}
+
+// Chain is the header of a chain.
+type Chain struct {
+	Score        float64
+	TName        string
+	TSize        int
+	TStrand      byte
+	TStart, TEnd int
+	QName        string
+	QSize        int
+	QStrand      byte
+	QStart, QEnd int
+	ID           string
+}
+
+// block is an ungapped aligned block of a chain.
+type block struct {
+	tStart, tEnd int
+	qStart       int
+	chain        *Chain
+}
+
+// Lifter lifts genes using a set of chains.
+type Lifter struct {
+	chroms map[string]*blocks
+}
+
+// blocks holds the blocks of a source sequence sorted by start. maxEnd[i] is
+// the largest end of b[0:i+1].
+type blocks struct {
+	b      []block
+	maxEnd []int
+}
+
+// ReadChains reads a UCSC chain file from r and returns a Lifter for its
+// chains. Only chains on the forward strand of the source are supported. It
+// returns nil and an error if r is malformed.
+func ReadChains(r io.Reader) (*Lifter, error) {
+	l := &Lifter{chroms: make(map[string]*blocks)}
+	sc := bufio.NewScanner(r)
+	var c *Chain
+	var tPos, qPos, line int
+	for sc.Scan() {
+		line++
+		fields := strings.Fields(sc.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if fields[0] == "chain" {
+			var err error
+			c, err = parseHeader(fields)
+			if err != nil {
+				return nil, fmt.Errorf("liftover: line %d: %v", line, err)
+			}
+			if c.TStrand != '+' {
+				return nil, fmt.Errorf("liftover: line %d: unsupported source strand %c", line, c.TStrand)
+			}
+			tPos, qPos = c.TStart, c.QStart
+			continue
+		}
+		if c == nil {
+			return nil, fmt.Errorf("liftover: line %d: alignment data before chain header", line)
+		}
+		if len(fields) != 1 && len(fields) != 3 {
+			return nil, fmt.Errorf("liftover: line %d: malformed alignment data", line)
+		}
+		var n [3]int
+		for i, f := range fields {
+			v, err := strconv.Atoi(f)
+			if err != nil || v < 0 {
+				return nil, fmt.Errorf("liftover: line %d: bad number %q", line, f)
+			}
+			n[i] = v
+		}
+		bs, ok := l.chroms[c.TName]
+		if !ok {
+			bs = &blocks{}
+			l.chroms[c.TName] = bs
+		}
+		bs.b = append(bs.b, block{tStart: tPos, tEnd: tPos + n[0], qStart: qPos, chain: c})
+		tPos += n[0] + n[1]
+		qPos += n[0] + n[2]
+		if len(fields) == 1 {
+			c = nil
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	for _, bs := range l.chroms {
+		sort.SliceStable(bs.b, func(i, j int) bool { return bs.b[i].tStart < bs.b[j].tStart })
+		bs.maxEnd = make([]int, len(bs.b))
+		max := 0
+		for i, b := range bs.b {
+			if b.tEnd > max {
+				max = b.tEnd
+			}
+			bs.maxEnd[i] = max
+		}
+	}
+	return l, nil
+}
+
+// parseHeader parses the fields of a chain header line.
+func parseHeader(fields []string) (*Chain, error) {
+	if len(fields) < 12 {
+		return nil, errors.New("short chain header")
+	}
+	c := &Chain{TName: fields[2], QName: fields[7]}
+	if len(fields) > 12 {
+		c.ID = fields[12]
+	}
+	var err error
+	if c.Score, err = strconv.ParseFloat(fields[1], 64); err != nil {
+		return nil, fmt.Errorf("bad score %q", fields[1])
+	}
+	for _, f := range []struct {
+		s string
+		v *int
+	}{
+		{fields[3], &c.TSize}, {fields[5], &c.TStart}, {fields[6], &c.TEnd},
+		{fields[8], &c.QSize}, {fields[10], &c.QStart}, {fields[11], &c.QEnd},
+	} {
+		if *f.v, err = strconv.Atoi(f.s); err != nil {
+			return nil, fmt.Errorf("bad number %q", f.s)
+		}
+	}
+	for _, f := range []struct {
+		s string
+		v *byte
+	}{{fields[4], &c.TStrand}, {fields[9], &c.QStrand}} {
+		if f.s != "+" && f.s != "-" {
+			return nil, fmt.Errorf("bad strand %q", f.s)
+		}
+		*f.v = f.s[0]
+	}
+	return c, nil
+}
+
+// find returns the block with the highest scoring chain that contains
+// position pos of the source sequence chrom.
+func (l *Lifter) find(chrom string, pos int) (block, bool) {
+	bs, ok := l.chroms[chrom]
+	if !ok {
+		return block{}, false
+	}
+	hi := sort.Search(len(bs.b), func(i int) bool { return bs.b[i].tStart > pos })
+	lo := sort.Search(hi, func(i int) bool { return bs.maxEnd[i] > pos })
+	var best block
+	found := false
+	for _, b := range bs.b[lo:hi] {
+		if b.tEnd > pos && (!found || b.chain.Score > best.chain.Score) {
+			best, found = b, true
+		}
+	}
+	return best, found
+}
+
+// liftPos lifts the base at position pos of the source sequence chrom and
+// returns its forward strand position on the destination and the chain used.
+func (l *Lifter) liftPos(chrom string, pos int) (int, *Chain, bool) {
+	b, ok := l.find(chrom, pos)
+	if !ok {
+		return 0, nil, false
+	}
+	q := b.qStart + pos - b.tStart
+	if b.chain.QStrand == '-' {
+		q = b.chain.QSize - q - 1
+	}
+	return q, b.chain, true
+}
+
+// liftInterval lifts the half open interval [start, end) of chrom. Both end
+// bases must be lifted by the same chain.
+func (l *Lifter) liftInterval(chrom string, start, end int) (s, e int, c *Chain, err error) {
+	a, ca, ok := l.liftPos(chrom, start)
+	if !ok {
+		return 0, 0, nil, fmt.Errorf("%s:%d is not aligned", chrom, start+1)
+	}
+	b, cb, ok := l.liftPos(chrom, end-1)
+	if !ok {
+		return 0, 0, nil, fmt.Errorf("%s:%d is not aligned", chrom, end)
+	}
+	if ca != cb {
+		return 0, 0, nil, errSplit
+	}
+	if a > b {
+		a, b = b, a
+	}
+	return a, b + 1, ca, nil
+}
+
+var errSplit = errors.New("interval ends lifted by different chains")
+
+// lifted is a transcript lifted to destination coordinates.
+type lifted struct {
+	t     gene.Transcript
+	exons [][2]int
+	cds   *[2]int
+	chain *Chain
+	// exceptions holds the destination positions of the translational
+	// exceptions of t, in order.
+	exceptions []int
+}
+
+// Lift lifts g to the destination assembly. It returns the lifted gene with
+// the transcripts that could be lifted and a Failure for each transcript that
+// could not. The lifted gene is nil if no transcript could be lifted. The
+// lifted gene is located on a geneio.Chrom named after the destination
+// sequence.
+func (l *Lifter) Lift(g gene.Interface) (*gene.Gene, []*Failure) {
+	var ok []*lifted
+	var failures []*Failure
+	var chain *Chain
+	for _, t := range geneio.Transcripts(g) {
+		lt, f := l.liftTranscript(t)
+		if f == nil && chain != nil &&
+			(lt.chain.QName != chain.QName || lt.chain.QStrand != chain.QStrand) {
+			f = &Failure{Reason: Split, Msg: "transcripts of gene lifted to different sequences or strands"}
+		}
+		if f != nil {
+			f.Gene, f.Transcript = g.Name(), t.Name()
+			failures = append(failures, f)
+			continue
+		}
+		if chain == nil {
+			chain = lt.chain
+		}
+		ok = append(ok, lt)
+	}
+	if len(ok) == 0 {
+		return nil, failures
+	}
+
+	orient := g.Orientation()
+	if chain.QStrand == '-' {
+		orient = -orient
+	}
+	ng := &gene.Gene{
+		ID:     g.Name(),
+		Chrom:  geneio.Chrom(chain.QName),
+		Offset: ok[0].exons[0][0],
+		Orient: orient,
+		Desc:   g.Description(),
+	}
+	for _, lt := range ok {
+		if lt.exons[0][0] < ng.Offset {
+			ng.Offset = lt.exons[0][0]
+		}
+	}
+	var feats []feat.Feature
+	for _, lt := range ok {
+		t, err := lt.build(ng)
+		if err != nil {
+			failures = append(failures, &Failure{
+				Gene: g.Name(), Transcript: lt.t.Name(), Reason: Reordered, Msg: err.Error(),
+			})
+			continue
+		}
+		feats = append(feats, t)
+	}
+	if len(feats) == 0 {
+		return nil, failures
+	}
+	if err := ng.SetFeatures(feats...); err != nil {
+		for _, lt := range ok {
+			failures = append(failures, &Failure{
+				Gene: g.Name(), Transcript: lt.t.Name(), Reason: Reordered, Msg: err.Error(),
+			})
+		}
+		return nil, failures
+	}
+	return ng, failures
+}
+
+// liftTranscript lifts the exons and CDS of t.
+func (l *Lifter) liftTranscript(t gene.Transcript) (*lifted, *Failure) {
+	lt := &lifted{t: t}
+	origLen, liftLen := 0, 0
+	for _, e := range t.Exons() {
+		start, ref := geneio.RefPosition(t, e.Start())
+		s, end, c, err := l.liftInterval(ref.Name(), start, start+e.Len())
+		if err == errSplit {
+			return nil, &Failure{Reason: Split, Msg: err.Error()}
+		}
+		if err != nil {
+			return nil, &Failure{Reason: ExonLost, Msg: err.Error()}
+		}
+		if lt.chain == nil {
+			lt.chain = c
+		} else if c != lt.chain {
+			return nil, &Failure{Reason: Split, Msg: "exons lifted by different chains"}
+		}
+		lt.exons = append(lt.exons, [2]int{s, end})
+		origLen += e.Len()
+		liftLen += end - s
+	}
+	if len(lt.exons) == 0 {
+		return nil, &Failure{Reason: ExonLost, Msg: "transcript has no exons"}
+	}
+	if lt.chain.QStrand == '-' {
+		for i, j := 0, len(lt.exons)-1; i < j; i, j = i+1, j-1 {
+			lt.exons[i], lt.exons[j] = lt.exons[j], lt.exons[i]
+		}
+	}
+	for i := 1; i < len(lt.exons); i++ {
+		if lt.exons[i][0] < lt.exons[i-1][1] {
+			return nil, &Failure{Reason: Reordered, Msg: "lifted exons overlap or are out of order"}
+		}
+	}
+
+	ct, ok := geneio.Coding(t)
+	if !ok {
+		return lt, nil
+	}
+	start, ref := geneio.RefPosition(t, ct.CDSstart)
+	end, _ := geneio.RefPosition(t, ct.CDSend)
+	s, e, c, err := l.liftInterval(ref.Name(), start, end)
+	if err != nil || c != lt.chain {
+		return nil, &Failure{Reason: CDSBroken, Msg: "CDS ends cannot be lifted"}
+	}
+	lt.cds = &[2]int{s, e}
+	origCDS := codingLen(exonsOf(t), start, end)
+	if liftCDS := codingLen(lt.exons, s, e); (liftCDS-origCDS)%3 != 0 {
+		return nil, &Failure{
+			Reason: CDSBroken,
+			Msg:    fmt.Sprintf("CDS length changed from %d to %d", origCDS, liftCDS),
+		}
+	}
+	if a, ok := t.(geneio.Annotated); ok {
+		for _, x := range a.TranslationExceptions() {
+			pos, _ := geneio.RefPosition(t, x.Offset)
+			q, c, ok := l.liftPos(ref.Name(), pos)
+			if !ok || c != lt.chain {
+				return nil, &Failure{Reason: CDSBroken, Msg: "translational exception cannot be lifted"}
+			}
+			lt.exceptions = append(lt.exceptions, q)
+		}
+	}
+	return lt, nil
+}
+
+// exonsOf returns the exons of t in reference coordinates.
+func exonsOf(t gene.Transcript) [][2]int {
+	var exons [][2]int
+	for _, e := range t.Exons() {
+		s, _ := geneio.RefPosition(t, e.Start())
+		exons = append(exons, [2]int{s, s + e.Len()})
+	}
+	return exons
+}
+
+// codingLen returns the number of exonic bases in [start, end).
+func codingLen(exons [][2]int, start, end int) int {
+	n := 0
+	for _, e := range exons {
+		s, en := e[0], e[1]
+		if s < start {
+			s = start
+		}
+		if en > end {
+			en = end
+		}
+		if s < en {
+			n += en - s
+		}
+	}
+	return n
+}
+
+// build creates the lifted transcript located on g.
+func (lt *lifted) build(g *gene.Gene) (gene.Transcript, error) {
+	offset := lt.exons[0][0] - g.Offset
+	var t gene.Transcript
+	if _, ok := geneio.Coding(lt.t); ok {
+		ct := &gene.CodingTranscript{
+			ID:       lt.t.Name(),
+			Loc:      g,
+			Offset:   offset,
+			Orient:   lt.t.Orientation(),
+			Desc:     lt.t.Description(),
+			CDSstart: lt.cds[0] - g.Offset - offset,
+			CDSend:   lt.cds[1] - g.Offset - offset,
+		}
+		t = ct
+	} else {
+		t = &gene.NonCodingTranscript{
+			ID:     lt.t.Name(),
+			Loc:    g,
+			Offset: offset,
+			Orient: lt.t.Orientation(),
+			Desc:   lt.t.Description(),
+		}
+	}
+	exons := make([]gene.Exon, len(lt.exons))
+	for i, e := range lt.exons {
+		exons[i] = gene.Exon{
+			Transcript: t,
+			Offset:     e[0] - g.Offset - offset,
+			Length:     e[1] - e[0],
+		}
+	}
+	if err := t.SetExons(exons...); err != nil {
+		return nil, err
+	}
+	ct, ok := t.(*gene.CodingTranscript)
+	if !ok {
+		return geneio.TranscriptWithAttributes(t, geneio.Attributes(lt.t)), nil
+	}
+	at := geneio.CopyAnnotation(lt.t, ct)
+	if a, ok := at.(*geneio.AnnotatedTranscript); ok {
+		for i, q := range lt.exceptions {
+			a.Exceptions[i].Offset = q - g.Offset - offset
+		}
+	}
+	return at, nil
+}
+
+// Reader is a geneio.Reader that lifts the genes read from an underlying
+// Reader. Genes with no liftable transcript are skipped. All failures are
+// collected and available from Failures.
+type Reader struct {
+	r        geneio.Reader
+	l        *Lifter
+	failures []*Failure
+}
+
+// NewReader returns a new Reader that lifts the genes of r with l.
+func NewReader(r geneio.Reader, l *Lifter) *Reader {
+	return &Reader{r: r, l: l}
+}
+
+// Read reads and lifts the next gene that has at least one liftable
+// transcript, keeping the attributes of the gene. At EOF, it returns nil and
+// io.EOF.
+func (r *Reader) Read() (gene.Interface, error) {
+	for {
+		g, err := r.r.Read()
+		if err != nil {
+			return nil, err
+		}
+		lg, failures := r.l.Lift(g)
+		r.failures = append(r.failures, failures...)
+		if lg != nil {
+			return geneio.WithAttributes(lg, geneio.Attributes(g)), nil
+		}
+	}
+}
+
+// Failures returns the failures of all transcripts read so far.
+func (r *Reader) Failures() []*Failure {
+	return r.failures
+}
@@ -0,0 +1,135 @@
+package liftover
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/biogo/biogo/feat"
+	"github.com/biogo/biogo/feat/gene"
+	"github.com/biogo/biogo/io/featio/gff"
+	"github.com/go-bio/geneio"
+	ggff "github.com/go-bio/geneio/gff"
+)
+
+// Assert that interfaces are satisfied.
+var (
+	_ geneio.Reader = (*Reader)(nil)
+	_ error         = (*Failure)(nil)
+)
+
+var chains = "" +
+	"chain 1000 X 1000 + 0 500 chrX 5000 + 1000 1490 1\n" +
+	"100 10 0\n" +
+	"390\n" +
+	"\n" +
+	"chain 500 X 1000 + 600 900 chrY 2000 - 100 400 2\n" +
+	"300\n"
+
+var liftInput = "" +
+	"X\t.\texon\t11\t50\t0\t+\t.\tgene_id A; transcript_id A1; gene_name ABC; tag basic;\n" +
+	"X\t.\texon\t201\t300\t0\t+\t.\tgene_id A; transcript_id A1; gene_name ABC; tag basic;\n" +
+	"X\t.\tstart_codon\t21\t23\t0\t+\t.\tgene_id A; transcript_id A1; gene_name ABC; tag basic;\n" +
+	"X\t.\tstop_codon\t281\t283\t0\t+\t.\tgene_id A; transcript_id A1; gene_name ABC; tag basic;\n" +
+	"X\t.\tSelenocysteine\t31\t33\t0\t+\t.\tgene_id A; transcript_id A1; gene_name ABC; tag basic;\n" +
+	"X\t.\texon\t91\t120\t0\t+\t.\tgene_id B; transcript_id B1;\n" +
+	"X\t.\texon\t601\t650\t0\t-\t.\tgene_id C; transcript_id C1;\n" +
+	"X\t.\texon\t701\t750\t0\t-\t.\tgene_id C; transcript_id C1;\n" +
+	"X\t.\texon\t521\t540\t0\t+\t.\tgene_id D; transcript_id D1;\n" +
+	"X\t.\texon\t401\t450\t0\t+\t.\tgene_id E; transcript_id E1;\n" +
+	"X\t.\texon\t611\t620\t0\t+\t.\tgene_id E; transcript_id E1;\n" +
+	"X\t.\texon\t81\t130\t0\t+\t.\tgene_id F; transcript_id F1;\n" +
+	"X\t.\tstart_codon\t85\t87\t0\t+\t.\tgene_id F; transcript_id F1;\n" +
+	"X\t.\tstop_codon\t121\t123\t0\t+\t.\tgene_id F; transcript_id F1;\n"
+
+type liftedGene struct {
+	ID         string
+	Chrom      string
+	Orient     feat.Orientation
+	Exons      [][2]int
+	CDS        [2]int
+	Name       string
+	Tags       []string
+	Exceptions []int
+}
+
+// Test Reader
+func TestReader(t *testing.T) {
+	l, err := ReadChains(strings.NewReader(chains))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	gr := ggff.NewReader(gff.NewReader(strings.NewReader(liftInput)))
+	if err := gr.SetAttributes(true); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	r := NewReader(gr, l)
+
+	var got []liftedGene
+	sc := geneio.NewScanner(r)
+	for sc.Next() {
+		g := sc.Gene()
+		tr := g.Features()[0].(gene.Transcript)
+		lg := liftedGene{ID: g.Name(), Chrom: g.Location().Name(), Orient: g.Orientation(), Name: geneio.Attributes(g)["gene_name"]}
+		for _, e := range tr.Exons() {
+			s, _ := geneio.RefPosition(tr, e.Start())
+			lg.Exons = append(lg.Exons, [2]int{s, s + e.Len()})
+		}
+		if ct, ok := geneio.Coding(tr); ok {
+			lg.CDS[0], _ = geneio.RefPosition(ct, ct.CDSstart)
+			lg.CDS[1], _ = geneio.RefPosition(ct, ct.CDSend)
+		}
+		if a, ok := tr.(geneio.Annotated); ok {
+			lg.Tags = a.TranscriptTags()
+			for _, x := range a.TranslationExceptions() {
+				pos, _ := geneio.RefPosition(tr, x.Offset)
+				lg.Exceptions = append(lg.Exceptions, pos)
+			}
+		}
+		got = append(got, lg)
+	}
+	if err := sc.Error(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	want := []liftedGene{
+		{
+			ID: "A", Chrom: "chrX", Orient: feat.Forward, Exons: [][2]int{{1010, 1050}, {1190, 1290}}, CDS: [2]int{1020, 1273},
+			Name: "ABC", Tags: []string{"basic"}, Exceptions: []int{1030},
+		},
+		{ID: "B", Chrom: "chrX", Orient: feat.Forward, Exons: [][2]int{{1090, 1110}}},
+		{ID: "C", Chrom: "chrY", Orient: feat.Forward, Exons: [][2]int{{1750, 1800}, {1850, 1900}}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("out genes=%+v\nwant %+v", got, want)
+	}
+
+	var reasons []string
+	for _, f := range r.Failures() {
+		reasons = append(reasons, f.Gene+":"+f.Reason.String())
+	}
+	wantReasons := []string{"D:exon lost", "E:split", "F:CDS broken"}
+	if !reflect.DeepEqual(reasons, wantReasons) {
+		t.Errorf("out failures=%q want %q", reasons, wantReasons)
+	}
+}
+
+// Test ReadChains errors
+var readChainsErrorTests = []struct {
+	Name  string
+	Input string
+	Error string
+}{
+	{"data before header", "100\n", "alignment data before chain header"},
+	{"short header", "chain 1 X 10 + 0 10\n", "short chain header"},
+	{"bad strand", "chain 1 X 10 + 0 10 Y 10 * 0 10\n", "bad strand"},
+	{"malformed data", "chain 1 X 10 + 0 10 Y 10 + 0 10\n1 2\n", "malformed alignment data"},
+}
+
+func TestReadChainsError(t *testing.T) {
+	for _, tt := range readChainsErrorTests {
+		_, err := ReadChains(strings.NewReader(tt.Input))
+		if err == nil || !strings.Contains(err.Error(), tt.Error) {
+			t.Errorf("%s: error %q, want error %q", tt.Name, err, tt.Error)
+		}
+	}
+}
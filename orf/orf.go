@@ -0,0 +1,234 @@
+// Package orf finds open reading frames in transcripts.
+//
+// A Finder searches the three forward frames of a spliced transcript
+// sequence for open reading frames that begin with a start codon and end
+// with a stop codon of its genetic code. Open reading frames of non coding
+// transcripts can be used to make coding transcripts.
+package orf
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/biogo/biogo/feat"
+	"github.com/biogo/biogo/feat/gene"
+	"github.com/go-bio/geneio"
+)
+
+// ErrNoORF is returned when a transcript has no open reading frame.
+var ErrNoORF = errors.New("orf: no open reading frame")
+
+// ORF is an open reading frame. Start and End are the zero based, half open
+// positions on the spliced transcript from its 5' end, and End includes the
+// stop codon.
+type ORF struct {
+	Start, End int
+}
+
+// Len returns the length of o in bases, including the stop codon.
+func (o ORF) Len() int { return o.End - o.Start }
+
+// Frame returns the frame of o, the position of its start modulo three.
+func (o ORF) Frame() int { return o.Start % 3 }
+
+// Finder finds open reading frames.
+type Finder struct {
+	// Code is the genetic code that defines stop codons.
+	Code geneio.GeneticCode
+	// Starts are the start codons.
+	Starts []string
+	// MinCodons is the smallest number of codons of reported open reading
+	// frames, not counting the stop codon.
+	MinCodons int
+}
+
+// NewFinder returns a new Finder for the standard genetic code with ATG as
+// the only start codon and a MinCodons of 100.
+func NewFinder() *Finder {
+	return &Finder{Code: geneio.StandardCode, Starts: []string{"ATG"}, MinCodons: 100}
+}
+
+// isStart returns whether codon is a start codon of f.
+func (f *Finder) isStart(codon []byte) bool {
+	for _, s := range f.Starts {
+		if len(s) == 3 && equalFold(codon, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// equalFold returns whether the nucleotides a and b are equal ignoring case
+// and the difference between T and U.
+func equalFold(a []byte, b string) bool {
+	for i := range a {
+		x, y := a[i]|0x20, b[i]|0x20
+		if x == 'u' {
+			x = 't'
+		}
+		if y == 'u' {
+			y = 't'
+		}
+		if x != y {
+			return false
+		}
+	}
+	return true
+}
+
+// Find returns the open reading frames of seq with at least MinCodons
+// codons ordered by start. Each stop codon ends at most one open reading
+// frame, which begins at the first start codon in frame after the previous
+// stop codon.
+func (f *Finder) Find(seq []byte) []ORF {
+	var orfs []ORF
+	for frame := 0; frame < 3; frame++ {
+		open := -1
+		for i := frame; i+3 <= len(seq); i += 3 {
+			codon := seq[i : i+3]
+			if open < 0 && f.isStart(codon) {
+				open = i
+			}
+			if f.Code.Codon(codon) != '*' {
+				continue
+			}
+			if open >= 0 && (i-open)/3 >= f.MinCodons {
+				orfs = append(orfs, ORF{Start: open, End: i + 3})
+			}
+			open = -1
+		}
+	}
+	sort.Slice(orfs, func(i, j int) bool { return orfs[i].Start < orfs[j].Start })
+	return orfs
+}
+
+// Longest returns the longest open reading frame of seq with at least
+// MinCodons codons, the first one if several are equally long. It returns
+// false if there is none.
+func (f *Finder) Longest(seq []byte) (ORF, bool) {
+	var (
+		best ORF
+		ok   bool
+	)
+	for _, o := range f.Find(seq) {
+		if !ok || o.Len() > best.Len() {
+			best, ok = o, true
+		}
+	}
+	return best, ok
+}
+
+// exons returns the exons of t relative to t in 5' to 3' order.
+func exons(t gene.Transcript) []gene.Exon {
+	es := append([]gene.Exon(nil), t.Exons()...)
+	sort.Slice(es, func(i, j int) bool { return es[i].Start() < es[j].Start() })
+	if geneio.RefOrientation(t) == feat.Reverse {
+		for i, j := 0, len(es)-1; i < j; i, j = i+1, j-1 {
+			es[i], es[j] = es[j], es[i]
+		}
+	}
+	return es
+}
+
+// Sequence returns the spliced sequence of t from its 5' end, read from s.
+func Sequence(t gene.Transcript, s geneio.Sequencer) ([]byte, error) {
+	start, ref := geneio.RefPosition(t, 0)
+	reverse := geneio.RefOrientation(t) == feat.Reverse
+	var seq []byte
+	for _, e := range exons(t) {
+		b, err := s.Seq(ref.Name(), start+e.Start(), start+e.End())
+		if err != nil {
+			return nil, err
+		}
+		if reverse {
+			b = geneio.ReverseComplement(b)
+		}
+		seq = append(seq, b...)
+	}
+	return seq, nil
+}
+
+// position returns the position relative to t of the spliced transcript
+// position i.
+func position(t gene.Transcript, i int) int {
+	reverse := geneio.RefOrientation(t) == feat.Reverse
+	for _, e := range exons(t) {
+		if i < e.Len() {
+			if reverse {
+				return e.End() - 1 - i
+			}
+			return e.Start() + i
+		}
+		i -= e.Len()
+	}
+	panic("orf: position outside transcript")
+}
+
+// Coding returns a coding transcript with the ID, location, orientation,
+// description and exons of t and the longest open reading frame of t, read
+// from s, as its coding sequence. It returns ErrNoORF if t has no open
+// reading frame with at least MinCodons codons.
+func (f *Finder) Coding(t *gene.NonCodingTranscript, s geneio.Sequencer) (*gene.CodingTranscript, error) {
+	seq, err := Sequence(t, s)
+	if err != nil {
+		return nil, err
+	}
+	o, ok := f.Longest(seq)
+	if !ok {
+		return nil, ErrNoORF
+	}
+	first, last := position(t, o.Start), position(t, o.End-1)
+	if first > last {
+		first, last = last, first
+	}
+	ct := &gene.CodingTranscript{
+		ID:       t.ID,
+		Loc:      t.Loc,
+		Offset:   t.Offset,
+		Orient:   t.Orient,
+		Desc:     t.Desc,
+		CDSstart: first,
+		CDSend:   last + 1,
+	}
+	es := make([]gene.Exon, len(t.Exons()))
+	for i, e := range t.Exons() {
+		es[i] = gene.Exon{Transcript: ct, Offset: e.Offset, Length: e.Length, Desc: e.Desc}
+	}
+	if err := ct.SetExons(es...); err != nil {
+		return nil, err
+	}
+	return ct, nil
+}
+
+// Upgrade replaces the non coding transcripts of g that have an open
+// reading frame with the coding transcripts made by Coding, keeping their
+// attributes, and returns the number of replaced transcripts.
+func (f *Finder) Upgrade(g *gene.Gene, s geneio.Sequencer) (int, error) {
+	feats := g.Features()
+	n := 0
+	up := make([]feat.Feature, len(feats))
+	for i, ft := range feats {
+		up[i] = ft
+		t, ok := ft.(gene.Transcript)
+		if !ok {
+			continue
+		}
+		nc, ok := geneio.NonCoding(t)
+		if !ok {
+			continue
+		}
+		ct, err := f.Coding(nc, s)
+		if err == ErrNoORF {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		up[i] = geneio.TranscriptWithAttributes(ct, geneio.Attributes(t))
+		n++
+	}
+	if n == 0 {
+		return 0, nil
+	}
+	return n, g.SetFeatures(up...)
+}
@@ -0,0 +1,147 @@
+package orf
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/biogo/biogo/feat/gene"
+	"github.com/biogo/biogo/io/featio/gff"
+	"github.com/go-bio/geneio"
+	ggff "github.com/go-bio/geneio/gff"
+)
+
+// Test Find and Longest
+func TestFind(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		code    geneio.GeneticCode
+		starts  []string
+		min     int
+		seq     string
+		want    []ORF
+		longest ORF
+	}{
+		{
+			name: "standard", code: geneio.StandardCode, starts: []string{"ATG"}, min: 1,
+			seq:  "ATGAAATAGCTGCCCTGA",
+			want: []ORF{{0, 9}}, longest: ORF{0, 9},
+		},
+		{
+			name: "alternative start", code: geneio.StandardCode, starts: []string{"ATG", "CTG"}, min: 1,
+			seq:  "ATGAAATAGCTGCCCTGA",
+			want: []ORF{{0, 9}, {9, 18}}, longest: ORF{0, 9},
+		},
+		{
+			name: "frames", code: geneio.StandardCode, starts: []string{"ATG"}, min: 2,
+			seq:  "atgaaatagxatgtttgggtaa",
+			want: []ORF{{0, 9}, {10, 22}}, longest: ORF{10, 22},
+		},
+		{
+			name: "minimum", code: geneio.StandardCode, starts: []string{"ATG"}, min: 3,
+			seq: "ATGAAATAG",
+		},
+		{
+			name: "no stop", code: geneio.StandardCode, starts: []string{"ATG"}, min: 1,
+			seq: "ATGAAAAAA",
+		},
+		{
+			name: "mitochondrial", code: geneio.VertebrateMitochondrialCode, starts: []string{"ATG"}, min: 1,
+			seq:  "AUGUGAAGA",
+			want: []ORF{{0, 9}}, longest: ORF{0, 9},
+		},
+	} {
+		f := &Finder{Code: test.code, Starts: test.starts, MinCodons: test.min}
+		if got := f.Find([]byte(test.seq)); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: out ORFs=%v want %v", test.name, got, test.want)
+		}
+		got, ok := f.Longest([]byte(test.seq))
+		if ok != (test.want != nil) || got != test.longest {
+			t.Errorf("%s: out longest=%v, %t want %v", test.name, got, ok, test.longest)
+		}
+	}
+}
+
+// The transcripts of A and B have the spliced sequence CCATGAAACCCTAGGGGG
+// with an open reading frame at [2,14).
+var orfInput = "" +
+	"X\t.\texon\t3\t11\t0\t+\t.\tgene_id A; transcript_id A1;\n" +
+	"X\t.\texon\t21\t29\t0\t+\t.\tgene_id A; transcript_id A1;\n" +
+	"X\t.\texon\t3\t11\t0\t+\t.\tgene_id A; transcript_id A2;\n" +
+	"Y\t.\texon\t3\t11\t0\t-\t.\tgene_id B; transcript_id B1; transcript_name B-1;\n" +
+	"Y\t.\texon\t21\t29\t0\t-\t.\tgene_id B; transcript_id B1; transcript_name B-1;\n"
+
+var orfSeqs = geneio.SeqMap{
+	"X": []byte("NNCCATGAAACGTAAGTTAGCCTAGGGGGNN"),
+	"Y": []byte("NNCCCCCTAGGGTAAGTTAGGTTTCATGGNN"),
+}
+
+// Test Coding and Upgrade
+func TestUpgrade(t *testing.T) {
+	var genes []gene.Interface
+	r := ggff.NewReader(gff.NewReader(strings.NewReader(orfInput)))
+	if err := r.SetAttributes(true); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	sc := geneio.NewScanner(r)
+	for sc.Next() {
+		genes = append(genes, sc.Gene())
+	}
+	if err := sc.Error(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	f := NewFinder()
+	f.MinCodons = 2
+	for i, test := range []struct {
+		n    int
+		cds  [][2]int
+		seqs []string
+	}{
+		{n: 1, cds: [][2]int{{4, 25}, {-1, -1}}, seqs: []string{"CCATGAAACCCTAGGGGG", "CCATGAAAC"}},
+		{n: 1, cds: [][2]int{{6, 27}}, seqs: []string{"CCATGAAACCCTAGGGGG"}},
+	} {
+		g := genes[i].(*gene.Gene)
+		ts := geneio.Transcripts(g)
+		for j, tr := range ts {
+			seq, err := Sequence(tr, orfSeqs)
+			if err != nil {
+				t.Fatalf("%s: unexpected error %v", tr.Name(), err)
+			}
+			if string(seq) != test.seqs[j] {
+				t.Errorf("%s: out sequence=%s want %s", tr.Name(), seq, test.seqs[j])
+			}
+		}
+		n, err := f.Upgrade(g, orfSeqs)
+		if err != nil {
+			t.Fatalf("%s: unexpected error %v", g.Name(), err)
+		}
+		if n != test.n {
+			t.Errorf("%s: out upgraded=%d want %d", g.Name(), n, test.n)
+		}
+		for j, tr := range geneio.Transcripts(g) {
+			ct, ok := geneio.Coding(tr)
+			if ok != (test.cds[j][0] >= 0) {
+				t.Errorf("%s: out coding=%t want %t", tr.Name(), ok, !ok)
+				continue
+			}
+			if !ok {
+				continue
+			}
+			s, _ := geneio.RefPosition(ct, 0)
+			if got := [2]int{s + ct.CDSstart, s + ct.CDSend}; got != test.cds[j] {
+				t.Errorf("%s: out CDS=%v want %v", tr.Name(), got, test.cds[j])
+			}
+			if len(ct.Exons()) != len(ts[j].Exons()) || ct.Location() != g {
+				t.Errorf("%s: out transcript=%+v", tr.Name(), ct)
+			}
+			if got, want := geneio.Attributes(tr), geneio.Attributes(ts[j]); !reflect.DeepEqual(got, want) {
+				t.Errorf("%s: out attributes=%v want %v", tr.Name(), got, want)
+			}
+		}
+	}
+
+	_, err := f.Coding(geneio.Transcripts(genes[0])[1].(*gene.NonCodingTranscript), orfSeqs)
+	if err != ErrNoORF {
+		t.Errorf("error %v, want error %v", err, ErrNoORF)
+	}
+}
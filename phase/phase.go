@@ -0,0 +1,157 @@
+// Package phase computes and checks the phases of coding sequence segments.
+//
+// The phase of a coding sequence segment is the number of bases that must be
+// removed from its 5' end to reach the first base of a codon, as in the
+// phase column of GFF3 and the frame column of GTF. Phases are computed from
+// the exons and the coding sequence of a coding transcript in the direction
+// of transcription, so that segments of transcripts on the reverse strand are
+// phased from their highest reference position. The coding sequence of a
+// geneio.Annotated transcript starts with its coding phase.
+package phase
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/biogo/biogo/feat"
+	"github.com/biogo/biogo/feat/gene"
+	"github.com/go-bio/geneio"
+)
+
+// Segment is a coding sequence segment of a transcript. Start and End are
+// zero based, half open reference coordinates.
+type Segment struct {
+	Start, End int
+	Phase      int
+}
+
+// cds returns the coding segments of t in reference coordinates in 5' to 3'
+// order.
+func cds(t *gene.CodingTranscript) [][2]int {
+	s, _ := geneio.RefPosition(t, 0)
+	var ivs [][2]int
+	for _, e := range t.Exons() {
+		start, end := e.Start(), e.End()
+		if start < t.CDSstart {
+			start = t.CDSstart
+		}
+		if end > t.CDSend {
+			end = t.CDSend
+		}
+		if start < end {
+			ivs = append(ivs, [2]int{s + start, s + end})
+		}
+	}
+	sort.Slice(ivs, func(i, j int) bool { return ivs[i][0] < ivs[j][0] })
+	if geneio.RefOrientation(t) == feat.Reverse {
+		for i, j := 0, len(ivs)-1; i < j; i, j = i+1, j-1 {
+			ivs[i], ivs[j] = ivs[j], ivs[i]
+		}
+	}
+	return ivs
+}
+
+// phaseAt returns the phase of a position n bases from the 5' end of a
+// coding sequence with 5' phase p.
+func phaseAt(n, p int) int {
+	return ((p-n)%3 + 3) % 3
+}
+
+// codingPhase returns the phase of the 5' end of the coding sequence of t.
+func codingPhase(t gene.Transcript) int {
+	if a, ok := t.(geneio.Annotated); ok {
+		return a.CodingPhase()
+	}
+	return 0
+}
+
+// Segments returns the coding sequence segments of t with their phases in
+// 5' to 3' order. The last segment includes the stop codon. It returns nil if
+// t is not coding.
+func Segments(t gene.Transcript) []Segment {
+	ct, ok := geneio.Coding(t)
+	if !ok {
+		return nil
+	}
+	p := codingPhase(t)
+	var segs []Segment
+	n := 0
+	for _, iv := range cds(ct) {
+		segs = append(segs, Segment{Start: iv[0], End: iv[1], Phase: phaseAt(n, p)})
+		n += iv[1] - iv[0]
+	}
+	return segs
+}
+
+// At returns the phase of a coding sequence segment of t whose 5' base is at
+// the reference position pos. It returns false if pos is not in the coding
+// sequence of t.
+func At(t gene.Transcript, pos int) (int, bool) {
+	ct, ok := geneio.Coding(t)
+	if !ok {
+		return 0, false
+	}
+	reverse := geneio.RefOrientation(t) == feat.Reverse
+	n := 0
+	for _, iv := range cds(ct) {
+		if pos >= iv[0] && pos < iv[1] {
+			d := pos - iv[0]
+			if reverse {
+				d = iv[1] - 1 - pos
+			}
+			return phaseAt(n+d, codingPhase(t)), true
+		}
+		n += iv[1] - iv[0]
+	}
+	return 0, false
+}
+
+// Record is a feature with a frame read from an annotation, such as a GTF
+// CDS line. Start and End are zero based, half open reference coordinates.
+type Record struct {
+	Type       string
+	Start, End int
+	Frame      int
+}
+
+// Error is a disagreement between the frame of a Record and the phase
+// computed for it.
+type Error struct {
+	Gene, Transcript string
+	Record           Record
+	// Want is the computed phase, or -1 if the record is not in the coding
+	// sequence of the transcript.
+	Want int
+}
+
+// Error returns the error message.
+func (e *Error) Error() string {
+	if e.Want < 0 {
+		return fmt.Sprintf("phase: %s at %d-%d of transcript %s of gene %s is outside the coding sequence",
+			e.Record.Type, e.Record.Start+1, e.Record.End, e.Transcript, e.Gene)
+	}
+	return fmt.Sprintf("phase: %s at %d-%d of transcript %s of gene %s has frame %d, want %d",
+		e.Record.Type, e.Record.Start+1, e.Record.End, e.Transcript, e.Gene, e.Record.Frame, e.Want)
+}
+
+// Check returns an Error for each record of recs whose frame disagrees with
+// the phase computed from t, which is a transcript of gene gid. t may be a
+// non coding transcript, in which case every record is outside the coding
+// sequence.
+func Check(gid string, t gene.Transcript, recs []Record) []*Error {
+	var errs []*Error
+	for _, r := range recs {
+		pos := r.Start
+		if geneio.RefOrientation(t) == feat.Reverse {
+			pos = r.End - 1
+		}
+		want := -1
+		if p, ok := At(t, pos); ok {
+			want = p
+		}
+		if want != r.Frame {
+			errs = append(errs, &Error{Gene: gid, Transcript: t.Name(), Record: r, Want: want})
+		}
+	}
+	return errs
+}
@@ -0,0 +1,103 @@
+package phase_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/biogo/biogo/feat/gene"
+	"github.com/go-bio/geneio"
+	"github.com/go-bio/geneio/internal/testgff"
+	"github.com/go-bio/geneio/phase"
+)
+
+// The coding sequences of A1 and B1 are [15,20)+[30,34) and [30,38)+[13,20)
+// in 5' to 3' order.
+var phaseInput = "" +
+	"X\t.\texon\t11\t20\t0\t+\t.\tgene_id A; transcript_id A1;\n" +
+	"X\t.\texon\t31\t40\t0\t+\t.\tgene_id A; transcript_id A1;\n" +
+	"X\t.\tstart_codon\t16\t18\t0\t+\t.\tgene_id A; transcript_id A1;\n" +
+	"X\t.\tstop_codon\t32\t34\t0\t+\t.\tgene_id A; transcript_id A1;\n" +
+	"X\t.\texon\t11\t40\t0\t+\t.\tgene_id A; transcript_id A2;\n" +
+	"Y\t.\texon\t11\t20\t0\t-\t.\tgene_id B; transcript_id B1;\n" +
+	"Y\t.\texon\t31\t40\t0\t-\t.\tgene_id B; transcript_id B1;\n" +
+	"Y\t.\tstop_codon\t14\t16\t0\t-\t.\tgene_id B; transcript_id B1;\n" +
+	"Y\t.\tstart_codon\t36\t38\t0\t-\t.\tgene_id B; transcript_id B1;\n"
+
+func readTranscripts(t *testing.T) []gene.Transcript {
+	var ts []gene.Transcript
+	for _, g := range testgff.Genes(t, phaseInput) {
+		ts = append(ts, geneio.Transcripts(g)...)
+	}
+	return ts
+}
+
+// Test Segments and At
+func TestSegments(t *testing.T) {
+	ts := readTranscripts(t)
+	a, b := ts[0].(*gene.CodingTranscript), ts[2].(*gene.CodingTranscript)
+	for _, test := range []struct {
+		t    *gene.CodingTranscript
+		want []phase.Segment
+	}{
+		{a, []phase.Segment{{15, 20, 0}, {30, 34, 1}}},
+		{b, []phase.Segment{{30, 38, 0}, {13, 20, 1}}},
+	} {
+		if got := phase.Segments(test.t); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: out segments=%v want %v", test.t.Name(), got, test.want)
+		}
+	}
+
+	for _, test := range []struct {
+		t     *gene.CodingTranscript
+		pos   int
+		phase int
+		ok    bool
+	}{
+		{a, 15, 0, true},
+		{a, 16, 2, true},
+		{a, 30, 1, true},
+		{a, 32, 2, true},
+		{a, 25, 0, false},
+		{a, 34, 0, false},
+		{b, 37, 0, true},
+		{b, 36, 2, true},
+		{b, 19, 1, true},
+		{b, 18, 0, true},
+		{b, 12, 0, false},
+	} {
+		p, ok := phase.At(test.t, test.pos)
+		if p != test.phase || ok != test.ok {
+			t.Errorf("%s/%d: out phase=%d, %t want %d, %t", test.t.Name(), test.pos, p, ok, test.phase, test.ok)
+		}
+	}
+}
+
+// Test Check
+func TestCheck(t *testing.T) {
+	ts := readTranscripts(t)
+	recs := []phase.Record{
+		{Type: "CDS", Start: 15, End: 20, Frame: 0},
+		{Type: "CDS", Start: 30, End: 31, Frame: 2},
+		{Type: "start_codon", Start: 15, End: 18, Frame: 0},
+	}
+	errs := phase.Check("A", ts[0], recs)
+	want := "phase: CDS at 31-31 of transcript A1 of gene A has frame 2, want 1"
+	if len(errs) != 1 || errs[0].Error() != want || errs[0].Want != 1 || errs[0].Record != recs[1] {
+		t.Errorf("out errors=%v want %q", errs, want)
+	}
+
+	errs = phase.Check("A", ts[1], recs[:1])
+	want = "phase: CDS at 16-20 of transcript A2 of gene A is outside the coding sequence"
+	if len(errs) != 1 || errs[0].Error() != want || errs[0].Want != -1 {
+		t.Errorf("out errors=%v want %q", errs, want)
+	}
+
+	recs = []phase.Record{
+		{Type: "CDS", Start: 30, End: 38, Frame: 0},
+		{Type: "CDS", Start: 16, End: 20, Frame: 1},
+		{Type: "stop_codon", Start: 13, End: 16, Frame: 0},
+	}
+	if errs := phase.Check("B", ts[2], recs); len(errs) != 0 {
+		t.Errorf("out errors=%v want none", errs)
+	}
+}
@@ -0,0 +1,175 @@
+package geneio
+
+import (
+	"errors"
+
+	"github.com/biogo/biogo/feat"
+	"github.com/biogo/biogo/feat/gene"
+)
+
+// Sequencer is the common interface for access to the reference sequences
+// that genes are located on.
+type Sequencer interface {
+	// Seq returns the forward strand bases of the reference sequence chrom in
+	// the half-open interval [start, end), returning any error that occurs.
+	Seq(chrom string, start, end int) ([]byte, error)
+}
+
+// SeqMap is a Sequencer that holds whole reference sequences in memory,
+// keyed by name.
+type SeqMap map[string][]byte
+
+// Seq implements Sequencer.
+func (m SeqMap) Seq(chrom string, start, end int) ([]byte, error) {
+	s, ok := m[chrom]
+	if !ok {
+		return nil, errors.New("geneio: unknown sequence " + chrom)
+	}
+	if start < 0 || end > len(s) || start > end {
+		return nil, errors.New("geneio: interval out of range for " + chrom)
+	}
+	return s[start:end], nil
+}
+
+// ReverseComplement returns a new slice with the reverse complement of the
+// nucleotide sequence s. Case is preserved and unknown bases become N.
+func ReverseComplement(s []byte) []byte {
+	rc := make([]byte, len(s))
+	for i, b := range s {
+		rc[len(s)-1-i] = complement(b)
+	}
+	return rc
+}
+
+// complement returns the complement of the nucleotide b.
+func complement(b byte) byte {
+	switch b {
+	case 'A':
+		return 'T'
+	case 'C':
+		return 'G'
+	case 'G':
+		return 'C'
+	case 'T', 'U':
+		return 'A'
+	case 'a':
+		return 't'
+	case 'c':
+		return 'g'
+	case 'g':
+		return 'c'
+	case 't', 'u':
+		return 'a'
+	case 'n':
+		return 'n'
+	}
+	return 'N'
+}
+
+// GeneticCode is a genetic code. It holds the amino acids of the 64 codons
+// with bases in TCAG order, the first base most significant, as in the NCBI
+// translation tables.
+type GeneticCode string
+
+// Genetic codes.
+const (
+	// StandardCode is NCBI translation table 1.
+	StandardCode GeneticCode = "FFLLSSSSYY**CC*WLLLLPPPPHHQQRRRRIIIMTTTTNNKKSSRRVVVVAAAADDEEGGGG"
+	// VertebrateMitochondrialCode is NCBI translation table 2.
+	VertebrateMitochondrialCode GeneticCode = "FFLLSSSSYY**CCWWLLLLPPPPHHQQRRRRIIMMTTTTNNKKSS**VVVVAAAADDEEGGGG"
+)
+
+// Translate returns the translation of the nucleotide sequence s with the
+// standard genetic code. Stop codons are translated as '*' and codons with
+// unknown bases as 'X'. A trailing partial codon is ignored.
+func Translate(s []byte) []byte {
+	return StandardCode.Translate(s)
+}
+
+// Translate returns the translation of the nucleotide sequence s with the
+// genetic code c, as for the Translate function.
+func (c GeneticCode) Translate(s []byte) []byte {
+	p := make([]byte, 0, len(s)/3)
+	for i := 0; i+3 <= len(s); i += 3 {
+		p = append(p, c.Codon(s[i:i+3]))
+	}
+	return p
+}
+
+// Codon returns the amino acid of the first three bases of codon, or 'X' if
+// any of them is unknown or codon is shorter than three bases.
+func (c GeneticCode) Codon(codon []byte) byte {
+	if len(codon) < 3 {
+		return 'X'
+	}
+	n := 0
+	for _, b := range codon[:3] {
+		var v int
+		switch b {
+		case 'T', 't', 'U', 'u':
+			v = 0
+		case 'C', 'c':
+			v = 1
+		case 'A', 'a':
+			v = 2
+		case 'G', 'g':
+			v = 3
+		default:
+			return 'X'
+		}
+		n = n*4 + v
+	}
+	return c[n]
+}
+
+// CodingSequence returns the spliced coding sequence of t from its 5' end,
+// including the stop codon, read from s. It returns an error if t is not
+// coding.
+func CodingSequence(t gene.Transcript, s Sequencer) ([]byte, error) {
+	ct, ok := Coding(t)
+	if !ok {
+		return nil, errors.New("geneio: transcript " + t.Name() + " is not coding")
+	}
+	start, ref := RefPosition(ct, 0)
+	reverse := RefOrientation(ct) == feat.Reverse
+	var seq []byte
+	for _, iv := range codingExons(ct) {
+		b, err := s.Seq(ref.Name(), start+iv[0], start+iv[1])
+		if err != nil {
+			return nil, err
+		}
+		if reverse {
+			b = ReverseComplement(b)
+		}
+		seq = append(seq, b...)
+	}
+	return seq, nil
+}
+
+// TranslateTranscript returns the translation of the coding sequence of t,
+// read from s, with the genetic code c. The first codon of a coding sequence
+// whose start was found is translated as methionine, as alternative
+// initiation codons such as CUG are. For Annotated transcripts, the bases
+// before the first complete codon are skipped and translational exceptions
+// replace the amino acids of their codons. It returns an error if t is not
+// coding.
+func (c GeneticCode) TranslateTranscript(t gene.Transcript, s Sequencer) ([]byte, error) {
+	seq, err := CodingSequence(t, s)
+	if err != nil {
+		return nil, err
+	}
+	phase := codingPhase(t)
+	if phase > len(seq) {
+		phase = len(seq)
+	}
+	p := c.Translate(seq[phase:])
+	if len(p) != 0 && !StartNotFound(t) {
+		p[0] = 'M'
+	}
+	for pos, aa := range ExceptionCodons(t) {
+		if i := (pos - phase) / 3; i < len(p) {
+			p[i] = aa
+		}
+	}
+	return p, nil
+}
@@ -0,0 +1,57 @@
+package geneio
+
+import (
+	"testing"
+)
+
+func TestReverseComplement(t *testing.T) {
+	for _, tt := range []struct{ In, Out string }{
+		{"ACGT", "ACGT"},
+		{"AACgtN", "NacGTT"},
+		{"", ""},
+	} {
+		if got := string(ReverseComplement([]byte(tt.In))); got != tt.Out {
+			t.Errorf("%q: out seq=%q want %q", tt.In, got, tt.Out)
+		}
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	for _, tt := range []struct{ In, Out string }{
+		{"ATGGCCTGA", "MA*"},
+		{"atgTTTtaaTAGTGG", "MF**W"},
+		{"AUGNCCGG", "MX"},
+		{"GG", ""},
+	} {
+		if got := string(Translate([]byte(tt.In))); got != tt.Out {
+			t.Errorf("%q: out protein=%q want %q", tt.In, got, tt.Out)
+		}
+	}
+}
+
+func TestGeneticCode(t *testing.T) {
+	for _, tt := range []struct {
+		Code    GeneticCode
+		In, Out string
+	}{
+		{StandardCode, "ATAAGATGA", "IR*"},
+		{VertebrateMitochondrialCode, "ATAAGATGA", "M*W"},
+	} {
+		if got := string(tt.Code.Translate([]byte(tt.In))); got != tt.Out {
+			t.Errorf("%q: out protein=%q want %q", tt.In, got, tt.Out)
+		}
+	}
+	for _, tt := range []struct {
+		In  string
+		Out byte
+	}{
+		{"TGGA", 'W'},
+		{"TNG", 'X'},
+		{"TG", 'X'},
+		{"", 'X'},
+	} {
+		if got := StandardCode.Codon([]byte(tt.In)); got != tt.Out {
+			t.Errorf("%q: out amino acid=%q want %q", tt.In, got, tt.Out)
+		}
+	}
+}
@@ -0,0 +1,380 @@
+// Package setop provides set operations between collections of genes.
+//
+// The operations follow the semantics of bedtools intersect, subtract and
+// closest, applied at the level of genes, transcripts or exons of the gene
+// models built by geneio.GeneReader. At gene and transcript level a feature
+// is its extent on the reference sequence; at exon level a transcript is the
+// set of its exons, so that two transcripts overlap only if their exons do.
+// The first collection is streamed and the second is held in an
+// index.Index. Coordinates are zero based and half open on the reference
+// sequence.
+package setop
+
+import (
+	"sort"
+
+	"github.com/biogo/biogo/feat"
+	"github.com/biogo/biogo/feat/gene"
+	"github.com/go-bio/geneio"
+	"github.com/go-bio/geneio/index"
+)
+
+// Level is the granularity of a set operation.
+type Level int
+
+// Levels.
+const (
+	Gene Level = iota
+	Transcript
+	Exon
+)
+
+// Strand is the strand requirement of a set operation.
+type Strand int
+
+// Strand requirements.
+const (
+	// Either ignores strand.
+	Either Strand = iota
+	// Same requires features on the same strand.
+	Same
+	// Opposite requires features on opposite strands.
+	Opposite
+)
+
+// Options holds the parameters of a set operation.
+type Options struct {
+	Level  Level
+	Strand Strand
+}
+
+// unit is a feature taking part in a set operation with its reference
+// intervals in increasing order.
+type unit struct {
+	f      feat.Feature
+	chrom  string
+	orient feat.Orientation
+	ivs    [][2]int
+}
+
+func (u unit) start() int { return u.ivs[0][0] }
+func (u unit) end() int   { return u.ivs[len(u.ivs)-1][1] }
+
+// units returns the units of g at level l.
+func units(g gene.Interface, l Level) []unit {
+	if l == Gene {
+		s, ref := geneio.RefPosition(g, 0)
+		return []unit{{f: g, chrom: ref.Name(), orient: geneio.RefOrientation(g), ivs: [][2]int{{s, s + g.Len()}}}}
+	}
+	var us []unit
+	for _, t := range geneio.Transcripts(g) {
+		s, ref := geneio.RefPosition(t, 0)
+		u := unit{f: t, chrom: ref.Name(), orient: geneio.RefOrientation(t)}
+		if l == Transcript {
+			u.ivs = [][2]int{{s, s + t.Len()}}
+		} else {
+			for _, e := range t.Exons() {
+				u.ivs = append(u.ivs, [2]int{s + e.Start(), s + e.End()})
+			}
+			if len(u.ivs) == 0 {
+				continue
+			}
+			sort.Slice(u.ivs, func(i, j int) bool { return u.ivs[i][0] < u.ivs[j][0] })
+		}
+		us = append(us, u)
+	}
+	return us
+}
+
+// strandMatch returns whether a and b satisfy the strand requirement s.
+func strandMatch(s Strand, a, b feat.Orientation) bool {
+	switch s {
+	case Same:
+		return a == b
+	case Opposite:
+		return a == -b && a != feat.NotOriented
+	}
+	return true
+}
+
+// candidates returns the units of the genes of b in [start, end) of chrom
+// that satisfy the strand requirement for u.
+func candidates(u unit, b *index.Index, opts Options, start, end int) []unit {
+	var us []unit
+	for _, g := range b.Overlapping(u.chrom, start, end) {
+		for _, c := range units(g, opts.Level) {
+			if strandMatch(opts.Strand, u.orient, c.orient) {
+				us = append(us, c)
+			}
+		}
+	}
+	return us
+}
+
+// overlapping returns the units of b that overlap u.
+func overlapping(u unit, b *index.Index, opts Options) []unit {
+	var us []unit
+	for _, c := range candidates(u, b, opts, u.start(), u.end()) {
+		if len(intersect(u.ivs, c.ivs)) != 0 {
+			us = append(us, c)
+		}
+	}
+	return us
+}
+
+// Reader is a geneio.Reader that filters the genes read from an underlying
+// Reader by their overlap with the genes of an index. At transcript and exon
+// level, genes are returned with only their selected transcripts and genes
+// with no selected transcripts are dropped. The underlying Reader must
+// return genes located on their reference sequence.
+type Reader struct {
+	r    geneio.Reader
+	b    *index.Index
+	opts Options
+	keep bool
+}
+
+// Intersect returns a Reader of the genes, or the transcripts, of r that
+// overlap those of b.
+func Intersect(r geneio.Reader, b *index.Index, opts Options) *Reader {
+	return &Reader{r: r, b: b, opts: opts, keep: true}
+}
+
+// Subtract returns a Reader of the genes, or the transcripts, of r that do
+// not overlap those of b.
+func Subtract(r geneio.Reader, b *index.Index, opts Options) *Reader {
+	return &Reader{r: r, b: b, opts: opts}
+}
+
+// Read reads one selected gene. At EOF, it returns nil and io.EOF.
+func (r *Reader) Read() (gene.Interface, error) {
+	for {
+		g, err := r.r.Read()
+		if err != nil {
+			return nil, err
+		}
+		var ts []gene.Transcript
+		us := units(g, r.opts.Level)
+		for _, u := range us {
+			if (len(overlapping(u, r.b, r.opts)) != 0) != r.keep {
+				continue
+			}
+			if r.opts.Level == Gene {
+				return g, nil
+			}
+			ts = append(ts, u.f.(gene.Transcript))
+		}
+		switch {
+		case len(ts) == 0:
+			continue
+		case len(ts) == len(geneio.Transcripts(g)):
+			return g, nil
+		}
+		return geneio.CopyGene(g, ts)
+	}
+}
+
+// Interval is a part of a gene or transcript resulting from a set
+// operation. It implements feat.Feature and feat.Orienter with the reference
+// sequence as its location.
+type Interval struct {
+	// Source is the gene or transcript the interval is part of.
+	Source feat.Feature
+	Chrom  feat.Feature
+	Offset int
+	Length int
+	Orient feat.Orientation
+}
+
+// Start returns the start position of i on its reference sequence.
+func (i *Interval) Start() int { return i.Offset }
+
+// End returns the end position of i on its reference sequence.
+func (i *Interval) End() int { return i.Offset + i.Length }
+
+// Len returns the length of i.
+func (i *Interval) Len() int { return i.Length }
+
+// Name returns the name of the source of i.
+func (i *Interval) Name() string { return i.Source.Name() }
+
+// Description returns "interval".
+func (i *Interval) Description() string { return "interval" }
+
+// Location returns the reference sequence of i.
+func (i *Interval) Location() feat.Feature { return i.Chrom }
+
+// Orientation returns the orientation of the source of i on the reference
+// sequence.
+func (i *Interval) Orientation() feat.Orientation { return i.Orient }
+
+// intervals returns the Intervals of ivs taken from u.
+func intervals(u unit, ivs [][2]int) []*Interval {
+	_, ref := geneio.RefPosition(u.f, 0)
+	is := make([]*Interval, len(ivs))
+	for i, iv := range ivs {
+		is[i] = &Interval{Source: u.f, Chrom: ref, Offset: iv[0], Length: iv[1] - iv[0], Orient: u.orient}
+	}
+	return is
+}
+
+// Overlaps returns the parts of the genes or transcripts of g that overlap
+// those of b, in the order of the units of g and increasing position.
+func Overlaps(g gene.Interface, b *index.Index, opts Options) []*Interval {
+	var is []*Interval
+	for _, u := range units(g, opts.Level) {
+		var all [][2]int
+		for _, c := range overlapping(u, b, opts) {
+			all = append(all, intersect(u.ivs, c.ivs)...)
+		}
+		is = append(is, intervals(u, merge(all))...)
+	}
+	return is
+}
+
+// Remainders returns the parts of the genes or transcripts of g that do not
+// overlap those of b, in the order of the units of g and increasing
+// position.
+func Remainders(g gene.Interface, b *index.Index, opts Options) []*Interval {
+	var is []*Interval
+	for _, u := range units(g, opts.Level) {
+		var all [][2]int
+		for _, c := range overlapping(u, b, opts) {
+			all = append(all, c.ivs...)
+		}
+		is = append(is, intervals(u, subtract(u.ivs, merge(all)))...)
+	}
+	return is
+}
+
+// Match is the closest feature of b to a feature of a set operation.
+type Match struct {
+	// A is the gene or transcript of the query and B the closest gene or
+	// transcript, or nil if there is none on the reference sequence of A.
+	A, B feat.Feature
+	// Distance is the number of bases between A and B, which is zero for
+	// overlapping and adjacent features.
+	Distance int
+}
+
+// maxWindow is the largest distance searched by Closest.
+const maxWindow = 1 << 30
+
+// Closest returns, for each gene or transcript of g, the closest genes or
+// transcripts of b. All features at the smallest distance are reported in
+// the order of their genes in b, and features of g with no match are
+// reported with a nil B.
+func Closest(g gene.Interface, b *index.Index, opts Options) []Match {
+	var ms []Match
+	for _, u := range units(g, opts.Level) {
+		var found []Match
+		for w := 1024; w <= maxWindow; w *= 2 {
+			best := -1
+			found = found[:0]
+			for _, c := range candidates(u, b, opts, u.start()-w, u.end()+w) {
+				d := distance(u.ivs, c.ivs)
+				switch {
+				case best < 0 || d < best:
+					best = d
+					found = append(found[:0], Match{A: u.f, B: c.f, Distance: d})
+				case d == best:
+					found = append(found, Match{A: u.f, B: c.f, Distance: d})
+				}
+			}
+			if best >= 0 && best <= w {
+				break
+			}
+		}
+		if len(found) == 0 {
+			found = append(found, Match{A: u.f})
+		}
+		ms = append(ms, found...)
+	}
+	return ms
+}
+
+// intersect returns the intersection of the sorted intervals a and b.
+func intersect(a, b [][2]int) [][2]int {
+	var out [][2]int
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		s, e := a[i][0], a[i][1]
+		if b[j][0] > s {
+			s = b[j][0]
+		}
+		if b[j][1] < e {
+			e = b[j][1]
+		}
+		if s < e {
+			out = append(out, [2]int{s, e})
+		}
+		if a[i][1] < b[j][1] {
+			i++
+		} else {
+			j++
+		}
+	}
+	return out
+}
+
+// merge returns the sorted union of ivs. ivs is sorted in place.
+func merge(ivs [][2]int) [][2]int {
+	if len(ivs) == 0 {
+		return nil
+	}
+	sort.Slice(ivs, func(i, j int) bool { return ivs[i][0] < ivs[j][0] })
+	out := [][2]int{ivs[0]}
+	for _, iv := range ivs[1:] {
+		last := &out[len(out)-1]
+		if iv[0] <= last[1] {
+			if iv[1] > last[1] {
+				last[1] = iv[1]
+			}
+			continue
+		}
+		out = append(out, iv)
+	}
+	return out
+}
+
+// subtract returns the parts of the sorted intervals a not covered by the
+// sorted, non overlapping intervals b.
+func subtract(a, b [][2]int) [][2]int {
+	var out [][2]int
+	for _, iv := range a {
+		s := iv[0]
+		for _, x := range b {
+			if x[1] <= s || x[0] >= iv[1] {
+				continue
+			}
+			if x[0] > s {
+				out = append(out, [2]int{s, x[0]})
+			}
+			s = x[1]
+		}
+		if s < iv[1] {
+			out = append(out, [2]int{s, iv[1]})
+		}
+	}
+	return out
+}
+
+// distance returns the number of bases between the closest intervals of a
+// and b, or zero if they overlap.
+func distance(a, b [][2]int) int {
+	best := -1
+	for _, x := range a {
+		for _, y := range b {
+			var d int
+			switch {
+			case y[0] >= x[1]:
+				d = y[0] - x[1]
+			case x[0] >= y[1]:
+				d = x[0] - y[1]
+			}
+			if best < 0 || d < best {
+				best = d
+			}
+		}
+	}
+	return best
+}
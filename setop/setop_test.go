@@ -0,0 +1,141 @@
+package setop
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/biogo/biogo/feat/gene"
+	"github.com/biogo/biogo/io/featio/gff"
+	"github.com/go-bio/geneio"
+	ggff "github.com/go-bio/geneio/gff"
+	"github.com/go-bio/geneio/index"
+	"github.com/go-bio/geneio/internal/testgff"
+)
+
+var _ geneio.Reader = (*Reader)(nil)
+
+// Gene A has exons at [10,20) and [30,40) in A1 and [30,40) in A2.
+var aInput = "" +
+	"X\t.\texon\t11\t20\t0\t+\t.\tgene_id A; transcript_id A1;\n" +
+	"X\t.\texon\t31\t40\t0\t+\t.\tgene_id A; transcript_id A1;\n" +
+	"X\t.\texon\t31\t40\t0\t+\t.\tgene_id A; transcript_id A2;\n" +
+	"Y\t.\texon\t1\t10\t0\t+\t.\tgene_id C; transcript_id C1;\n"
+
+// Gene P lies in the intron of A1, Q overlaps the last exon of A on the
+// other strand, S lies just after A and R far from it.
+var bInput = "" +
+	"X\t.\texon\t23\t28\t0\t+\t.\tgene_id P; transcript_id P1;\n" +
+	"X\t.\texon\t36\t50\t0\t-\t.\tgene_id Q; transcript_id Q1;\n" +
+	"X\t.\texon\t43\t44\t0\t+\t.\tgene_id S; transcript_id S1;\n" +
+	"X\t.\texon\t101\t110\t0\t+\t.\tgene_id R; transcript_id R1;\n"
+
+// transcripts returns the transcript names and reference extents of the
+// genes read from r.
+func transcripts(t *testing.T, r geneio.Reader) []string {
+	var out []string
+	for {
+		g, err := r.Read()
+		if err == io.EOF {
+			return out
+		}
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		for _, tr := range geneio.Transcripts(g) {
+			s, _ := geneio.RefPosition(tr, 0)
+			out = append(out, fmt.Sprintf("%s/%s:%d-%d", g.Name(), tr.Name(), s, s+tr.Len()))
+		}
+	}
+}
+
+// Test Intersect and Subtract
+func TestReader(t *testing.T) {
+	b := index.New(testgff.Genes(t, bInput)...)
+	for _, test := range []struct {
+		name     string
+		subtract bool
+		opts     Options
+		want     []string
+	}{
+		{"intersect gene", false, Options{Level: Gene}, []string{"A/A1:10-40", "A/A2:30-40"}},
+		{"intersect transcript same", false, Options{Level: Transcript, Strand: Same}, []string{"A/A1:10-40"}},
+		{"intersect exon same", false, Options{Level: Exon, Strand: Same}, nil},
+		{"intersect exon opposite", false, Options{Level: Exon, Strand: Opposite}, []string{"A/A1:10-40", "A/A2:30-40"}},
+		{"subtract gene", true, Options{Level: Gene}, []string{"C/C1:0-10"}},
+		{"subtract transcript same", true, Options{Level: Transcript, Strand: Same}, []string{"A/A2:30-40", "C/C1:0-10"}},
+		{"subtract exon", true, Options{Level: Exon}, []string{"C/C1:0-10"}},
+	} {
+		r := ggff.NewReader(gff.NewReader(strings.NewReader(aInput)))
+		var got []string
+		if test.subtract {
+			got = transcripts(t, Subtract(r, b, test.opts))
+		} else {
+			got = transcripts(t, Intersect(r, b, test.opts))
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: out transcripts=%v want %v", test.name, got, test.want)
+		}
+	}
+}
+
+// Test Overlaps and Remainders
+func TestIntervals(t *testing.T) {
+	a := testgff.Genes(t, aInput)[0]
+	b := index.New(testgff.Genes(t, bInput)...)
+	coords := func(is []*Interval) []string {
+		var out []string
+		for _, i := range is {
+			out = append(out, fmt.Sprintf("%s:%d-%d", i.Name(), i.Start(), i.End()))
+		}
+		return out
+	}
+	for _, test := range []struct {
+		name string
+		fn   func(gene.Interface, *index.Index, Options) []*Interval
+		opts Options
+		want []string
+	}{
+		{"overlaps exon", Overlaps, Options{Level: Exon}, []string{"A1:35-40", "A2:35-40"}},
+		{"overlaps gene same", Overlaps, Options{Level: Gene, Strand: Same}, []string{"A:22-28"}},
+		{"remainders exon", Remainders, Options{Level: Exon}, []string{"A1:10-20", "A1:30-35", "A2:30-35"}},
+		{"remainders gene", Remainders, Options{Level: Gene}, []string{"A:10-22", "A:28-35"}},
+		{"remainders transcript opposite", Remainders, Options{Level: Transcript, Strand: Opposite}, []string{"A1:10-35", "A2:30-35"}},
+	} {
+		if got := coords(test.fn(a, b, test.opts)); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: out intervals=%v want %v", test.name, got, test.want)
+		}
+	}
+}
+
+// Test Closest
+func TestClosest(t *testing.T) {
+	as := testgff.Genes(t, aInput)
+	b := index.New(testgff.Genes(t, bInput)...)
+	for _, test := range []struct {
+		name string
+		g    gene.Interface
+		opts Options
+		want []string
+	}{
+		{"gene", as[0], Options{Level: Gene}, []string{"A-P:0", "A-Q:0"}},
+		{"gene same", as[0], Options{Level: Gene, Strand: Same}, []string{"A-P:0"}},
+		{"exon same", as[0], Options{Level: Exon, Strand: Same}, []string{"A1-P1:2", "A1-S1:2", "A2-P1:2", "A2-S1:2"}},
+		{"exon opposite", as[0], Options{Level: Exon, Strand: Opposite}, []string{"A1-Q1:0", "A2-Q1:0"}},
+		{"none", as[1], Options{Level: Gene}, []string{"C-:0"}},
+	} {
+		var got []string
+		for _, m := range Closest(test.g, b, test.opts) {
+			name := ""
+			if m.B != nil {
+				name = m.B.Name()
+			}
+			got = append(got, fmt.Sprintf("%s-%s:%d", m.A.Name(), name, m.Distance))
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: out matches=%v want %v", test.name, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,209 @@
+// Package stats computes summary statistics of gene annotations.
+package stats
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/biogo/biogo/feat/gene"
+	"github.com/go-bio/geneio"
+)
+
+// Distribution summarises a distribution of integer values.
+type Distribution struct {
+	Count  int     `json:"count"`
+	Min    int     `json:"min"`
+	Q1     float64 `json:"q1"`
+	Median float64 `json:"median"`
+	Q3     float64 `json:"q3"`
+	Max    int     `json:"max"`
+	Mean   float64 `json:"mean"`
+}
+
+// newDistribution returns the Distribution of values. values is sorted in
+// place.
+func newDistribution(values []int) Distribution {
+	if len(values) == 0 {
+		return Distribution{}
+	}
+	sort.Ints(values)
+	sum := 0
+	for _, v := range values {
+		sum += v
+	}
+	return Distribution{
+		Count:  len(values),
+		Min:    values[0],
+		Q1:     quantile(values, 0.25),
+		Median: quantile(values, 0.5),
+		Q3:     quantile(values, 0.75),
+		Max:    values[len(values)-1],
+		Mean:   float64(sum) / float64(len(values)),
+	}
+}
+
+// quantile returns the p quantile of the sorted values using linear
+// interpolation between closest ranks.
+func quantile(values []int, p float64) float64 {
+	pos := p * float64(len(values)-1)
+	i := int(pos)
+	if i+1 >= len(values) {
+		return float64(values[i])
+	}
+	frac := pos - float64(i)
+	return float64(values[i]) + frac*float64(values[i+1]-values[i])
+}
+
+// Chrom holds the counts of a reference sequence.
+type Chrom struct {
+	Name        string `json:"name"`
+	Genes       int    `json:"genes"`
+	Transcripts int    `json:"transcripts"`
+}
+
+// Stats holds the summary statistics of an annotation.
+type Stats struct {
+	Genes              int          `json:"genes"`
+	Transcripts        int          `json:"transcripts"`
+	Coding             int          `json:"coding_transcripts"`
+	NonCoding          int          `json:"noncoding_transcripts"`
+	MonoExonic         int          `json:"mono_exonic_transcripts"`
+	MonoExonicFraction float64      `json:"mono_exonic_fraction"`
+	ExonLengths        Distribution `json:"exon_lengths"`
+	IntronLengths      Distribution `json:"intron_lengths"`
+	TranscriptLengths  Distribution `json:"transcript_lengths"`
+	TranscriptsPerGene Distribution `json:"transcripts_per_gene"`
+	Chroms             []Chrom      `json:"chroms"`
+}
+
+// Collector accumulates the statistics of genes added to it.
+type Collector struct {
+	s                Stats
+	exons, introns   []int
+	lengths, perGene []int
+	chroms           map[string]int
+}
+
+// NewCollector returns a new empty Collector.
+func NewCollector() *Collector {
+	return &Collector{chroms: make(map[string]int)}
+}
+
+// Add adds g to the statistics of c.
+func (c *Collector) Add(g gene.Interface) {
+	_, ref := geneio.RefPosition(g, 0)
+	i, ok := c.chroms[ref.Name()]
+	if !ok {
+		i = len(c.s.Chroms)
+		c.chroms[ref.Name()] = i
+		c.s.Chroms = append(c.s.Chroms, Chrom{Name: ref.Name()})
+	}
+	ts := geneio.Transcripts(g)
+	c.s.Chroms[i].Genes++
+	c.s.Chroms[i].Transcripts += len(ts)
+	c.s.Genes++
+	c.perGene = append(c.perGene, len(ts))
+
+	for _, t := range ts {
+		c.s.Transcripts++
+		if _, ok := geneio.Coding(t); ok {
+			c.s.Coding++
+		} else {
+			c.s.NonCoding++
+		}
+		exons := t.Exons()
+		if len(exons) == 1 {
+			c.s.MonoExonic++
+		}
+		spliced := 0
+		for j, e := range exons {
+			c.exons = append(c.exons, e.Len())
+			spliced += e.Len()
+			if j > 0 {
+				c.introns = append(c.introns, e.Start()-exons[j-1].End())
+			}
+		}
+		c.lengths = append(c.lengths, spliced)
+	}
+}
+
+// Stats returns the statistics of all genes added to c so far.
+func (c *Collector) Stats() *Stats {
+	s := c.s
+	s.Chroms = append([]Chrom(nil), c.s.Chroms...)
+	if s.Transcripts > 0 {
+		s.MonoExonicFraction = float64(s.MonoExonic) / float64(s.Transcripts)
+	}
+	s.ExonLengths = newDistribution(c.exons)
+	s.IntronLengths = newDistribution(c.introns)
+	s.TranscriptLengths = newDistribution(c.lengths)
+	s.TranscriptsPerGene = newDistribution(c.perGene)
+	return &s
+}
+
+// Read reads all genes from r and returns their statistics. It returns nil
+// and an error if reading fails.
+func Read(r geneio.Reader) (*Stats, error) {
+	c := NewCollector()
+	sc := geneio.NewScanner(r)
+	for sc.Next() {
+		c.Add(sc.Gene())
+	}
+	if err := sc.Error(); err != nil {
+		return nil, err
+	}
+	return c.Stats(), nil
+}
+
+// WriteText writes s to w in human readable form.
+func WriteText(w io.Writer, s *Stats) error {
+	ew := &errWriter{w: w}
+	ew.printf("Genes:\t%d\n", s.Genes)
+	ew.printf("Transcripts:\t%d\n", s.Transcripts)
+	ew.printf("  coding:\t%d\n", s.Coding)
+	ew.printf("  non coding:\t%d\n", s.NonCoding)
+	ew.printf("  mono exonic:\t%d (%.1f%%)\n", s.MonoExonic, 100*s.MonoExonicFraction)
+	ew.printf("\n%-22s %8s %8s %10s %10s %10s %8s %10s\n",
+		"Distribution", "count", "min", "q1", "median", "q3", "max", "mean")
+	for _, d := range []struct {
+		name string
+		d    Distribution
+	}{
+		{"exon length", s.ExonLengths},
+		{"intron length", s.IntronLengths},
+		{"transcript length", s.TranscriptLengths},
+		{"transcripts per gene", s.TranscriptsPerGene},
+	} {
+		ew.printf("%-22s %8d %8d %10.1f %10.1f %10.1f %8d %10.1f\n",
+			d.name, d.d.Count, d.d.Min, d.d.Q1, d.d.Median, d.d.Q3, d.d.Max, d.d.Mean)
+	}
+	ew.printf("\n%-22s %8s %12s\n", "Chromosome", "genes", "transcripts")
+	for _, c := range s.Chroms {
+		ew.printf("%-22s %8d %12d\n", c.Name, c.Genes, c.Transcripts)
+	}
+	return ew.err
+}
+
+// WriteJSON writes s to w as an indented JSON object.
+func WriteJSON(w io.Writer, s *Stats) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(s)
+}
+
+// errWriter is a writer that stops writing after the first error.
+type errWriter struct {
+	w   io.Writer
+	err error
+}
+
+// printf formats and writes to the underlying writer unless a previous
+// write failed.
+func (ew *errWriter) printf(format string, args ...interface{}) {
+	if ew.err != nil {
+		return
+	}
+	_, ew.err = fmt.Fprintf(ew.w, format, args...)
+}
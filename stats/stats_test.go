@@ -0,0 +1,70 @@
+package stats
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/biogo/biogo/io/featio/gff"
+	ggff "github.com/go-bio/geneio/gff"
+)
+
+var statsInput = "" +
+	"X\t.\texon\t11\t20\t0\t+\t.\tgene_id A; transcript_id A1;\n" +
+	"X\t.\texon\t31\t50\t0\t+\t.\tgene_id A; transcript_id A1;\n" +
+	"X\t.\tstart_codon\t15\t17\t0\t+\t.\tgene_id A; transcript_id A1;\n" +
+	"X\t.\tstop_codon\t41\t43\t0\t+\t.\tgene_id A; transcript_id A1;\n" +
+	"X\t.\texon\t11\t40\t0\t+\t.\tgene_id A; transcript_id A2;\n" +
+	"Y\t.\texon\t1\t30\t0\t-\t.\tgene_id B; transcript_id B1;\n" +
+	"Y\t.\texon\t71\t100\t0\t-\t.\tgene_id B; transcript_id B1;\n"
+
+// Test Read
+func TestRead(t *testing.T) {
+	s, err := Read(ggff.NewReader(gff.NewReader(strings.NewReader(statsInput))))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	want := &Stats{
+		Genes:              2,
+		Transcripts:        3,
+		Coding:             1,
+		NonCoding:          2,
+		MonoExonic:         1,
+		MonoExonicFraction: 1.0 / 3,
+		ExonLengths:        Distribution{Count: 5, Min: 10, Q1: 20, Median: 30, Q3: 30, Max: 30, Mean: 24},
+		IntronLengths:      Distribution{Count: 2, Min: 10, Q1: 17.5, Median: 25, Q3: 32.5, Max: 40, Mean: 25},
+		TranscriptLengths:  Distribution{Count: 3, Min: 30, Q1: 30, Median: 30, Q3: 45, Max: 60, Mean: 40},
+		TranscriptsPerGene: Distribution{Count: 2, Min: 1, Q1: 1.25, Median: 1.5, Q3: 1.75, Max: 2, Mean: 1.5},
+		Chroms: []Chrom{
+			{Name: "X", Genes: 1, Transcripts: 2},
+			{Name: "Y", Genes: 1, Transcripts: 1},
+		},
+	}
+	if !reflect.DeepEqual(s, want) {
+		t.Errorf("out stats=%+v\nwant %+v", s, want)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteJSON(&buf, s); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	var got Stats
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if !reflect.DeepEqual(&got, want) {
+		t.Errorf("out json round trip=%+v\nwant %+v", got, want)
+	}
+
+	buf.Reset()
+	if err := WriteText(&buf, s); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	for _, line := range []string{"Genes:\t2", "mono exonic:\t1 (33.3%)", "Y                             1            1"} {
+		if !strings.Contains(buf.String(), line) {
+			t.Errorf("text output missing %q:\n%s", line, buf.String())
+		}
+	}
+}